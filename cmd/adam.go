@@ -2,20 +2,27 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/lf-edge/adam/pkg/driver"
+	"github.com/lf-edge/adam/pkg/server"
 	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/utils"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 )
 
 var (
 	adamTag            string
 	adamRemoteRedisURL string
 	adamRemoteRedis    bool
+	adamEmbedded       bool
+	adamPidFile        string
+	adamLogFile        string
 )
 
 var adamCmd = &cobra.Command{
@@ -39,6 +46,9 @@ var startAdamCmd = &cobra.Command{
 			adamForce = viper.GetBool("adam.force")
 			adamRemoteRedisURL = viper.GetString("adam.redis.adam")
 			adamRemoteRedis = viper.GetBool("adam.remote.redis")
+			adamEmbedded = viper.GetBool("adam.embedded")
+			adamPidFile = utils.ResolveAbsPath(viper.GetString("adam.pid"))
+			adamLogFile = utils.ResolveAbsPath(viper.GetString("adam.log"))
 		}
 		return nil
 	},
@@ -55,6 +65,14 @@ var startAdamCmd = &cobra.Command{
 			log.Fatalf("cannot obtain executable path: %s", err)
 		}
 		log.Infof("Executable path: %s", command)
+		if adamEmbedded {
+			if err := utils.StartAdamEmbedded(command, adamPort, adamPath, adamLogFile, adamPidFile); err != nil {
+				log.Errorf("cannot start embedded adam: %s", err)
+			} else {
+				log.Infof("Embedded adam is running and accessible on port %d", adamPort)
+			}
+			return
+		}
 		if !adamRemoteRedis {
 			adamRemoteRedisURL = ""
 		}
@@ -78,10 +96,18 @@ var stopAdamCmd = &cobra.Command{
 		}
 		if viperLoaded {
 			adamRm = viper.GetBool("adam-rm")
+			adamEmbedded = viper.GetBool("adam.embedded")
+			adamPidFile = utils.ResolveAbsPath(viper.GetString("adam.pid"))
 		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if adamEmbedded {
+			if err := utils.StopAdamEmbedded(adamPidFile); err != nil {
+				log.Errorf("cannot stop embedded adam: %s", err)
+			}
+			return
+		}
 		if err := utils.StopAdam(adamRm); err != nil {
 			log.Errorf("cannot stop adam: %s", err)
 		}
@@ -99,10 +125,21 @@ var statusAdamCmd = &cobra.Command{
 			return fmt.Errorf("error reading config: %s", err.Error())
 		}
 		if viperLoaded {
+			adamEmbedded = viper.GetBool("adam.embedded")
+			adamPidFile = utils.ResolveAbsPath(viper.GetString("adam.pid"))
 		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if adamEmbedded {
+			statusAdam, err := utils.StatusAdamEmbedded(adamPidFile)
+			if err != nil {
+				log.Errorf("cannot obtain status of embedded adam: %s", err)
+			} else {
+				fmt.Printf("Embedded adam status: %s\n", statusAdam)
+			}
+			return
+		}
 		statusAdam, err := utils.StatusAdam()
 		if err != nil {
 			log.Errorf("cannot obtain status of adam: %s", err)
@@ -112,8 +149,47 @@ var statusAdamCmd = &cobra.Command{
 	},
 }
 
+var serveAdamCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "run adam inside eden process",
+	Long:  `Run adam server inside eden process with file storage. Used by start with --embedded flag, blocks until killed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		adamPath, err := filepath.Abs(adamDist)
+		if err != nil {
+			log.Fatalf("adam-dist problems: %s", err)
+		}
+		runPath := filepath.Join(adamPath, "run", "adam")
+		if _, err = os.Lstat(runPath); os.IsNotExist(err) {
+			log.Fatalf("%s not found. Please run ./eden setup before start to generate certs", runPath)
+		}
+		mgr := &driver.DeviceManagerFile{}
+		if _, err = mgr.Init(runPath); err != nil {
+			log.Fatalf("cannot init file storage in %s: %s", runPath, err)
+		}
+		srv := &server.Server{
+			Port:          strconv.Itoa(adamPort),
+			Address:       "0.0.0.0",
+			CertPath:      filepath.Join(runPath, "server.pem"),
+			KeyPath:       filepath.Join(runPath, "server-key.pem"),
+			DeviceManager: mgr,
+			CertRefresh:   defaults.DefaultAdamCertRefresh,
+		}
+		log.Infof("Serving adam from %s on port %d", runPath, adamPort)
+		srv.Start()
+	},
+}
+
+//adamFlagAliases allow to use short --embedded instead of --adam-embedded
+func adamFlagAliases(f *pflag.FlagSet, name string) pflag.NormalizedName {
+	if name == "embedded" {
+		name = "adam-embedded"
+	}
+	return pflag.NormalizedName(name)
+}
+
 func adamInit() {
 	adamCmd.AddCommand(startAdamCmd)
+	adamCmd.AddCommand(serveAdamCmd)
 	adamCmd.AddCommand(stopAdamCmd)
 	adamCmd.AddCommand(statusAdamCmd)
 	currentPath, err := os.Getwd()
@@ -126,5 +202,17 @@ func adamInit() {
 	startAdamCmd.Flags().BoolVarP(&adamForce, "adam-force", "", false, "adam force rebuild")
 	startAdamCmd.Flags().StringVarP(&adamRemoteRedisURL, "adam-redis-url", "", "", "adam remote redis url")
 	startAdamCmd.Flags().BoolVarP(&adamRemoteRedis, "adam-redis", "", true, "use adam remote redis")
+	startAdamCmd.Flags().BoolVarP(&adamEmbedded, "adam-embedded", "", false, "run adam inside eden process instead of docker")
+	startAdamCmd.Flags().StringVarP(&adamPidFile, "adam-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "adam.pid"), "file for save embedded adam pid")
+	startAdamCmd.Flags().StringVarP(&adamLogFile, "adam-log", "", filepath.Join(currentPath, defaults.DefaultDist, "adam.log"), "file for save embedded adam log")
+	serveAdamCmd.Flags().StringVarP(&adamDist, "adam-dist", "", path.Join(currentPath, defaults.DefaultDist, defaults.DefaultAdamDist), "adam dist with certs and storage")
+	serveAdamCmd.Flags().IntVarP(&adamPort, "adam-port", "", defaults.DefaultAdamPort, "adam port to serve on")
+	startAdamCmd.Flags().SetNormalizeFunc(adamFlagAliases)
 	stopAdamCmd.Flags().BoolVarP(&adamRm, "adam-rm", "", false, "adam rm on stop")
+	stopAdamCmd.Flags().BoolVarP(&adamEmbedded, "adam-embedded", "", false, "stop embedded adam")
+	stopAdamCmd.Flags().StringVarP(&adamPidFile, "adam-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "adam.pid"), "file with embedded adam pid")
+	statusAdamCmd.Flags().BoolVarP(&adamEmbedded, "adam-embedded", "", false, "status of embedded adam")
+	statusAdamCmd.Flags().StringVarP(&adamPidFile, "adam-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "adam.pid"), "file with embedded adam pid")
+	stopAdamCmd.Flags().SetNormalizeFunc(adamFlagAliases)
+	statusAdamCmd.Flags().SetNormalizeFunc(adamFlagAliases)
 }
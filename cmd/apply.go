@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/device"
+	"github.com/lf-edge/eden/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+)
+
+var manifestFile string
+
+//devicePreRun loads config for commands which change config of device
+func devicePreRun(cmd *cobra.Command, args []string) error {
+	assingCobraToViper(cmd)
+	_, err := utils.LoadConfigFile(configFile)
+	if err != nil {
+		return fmt.Errorf("error reading configFile: %s", err.Error())
+	}
+	return nil
+}
+
+//deviceRun runs process against controller and device selected by mode and pushes changed config
+func deviceRun(process func(ctrl controller.Cloud, dev *device.Ctx) error) {
+	changer, err := getControllerChanger()
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctrl, dev, err := changer.getControllerAndDev()
+	if err != nil {
+		log.Fatalf("getControllerAndDev error: %s", err)
+	}
+	if err = process(ctrl, dev); err != nil {
+		log.Fatal(err)
+	}
+	if err = changer.setControllerAndDev(ctrl, dev); err != nil {
+		log.Fatalf("setControllerAndDev error: %s", err)
+	}
+}
+
+//manifestRun loads manifest and runs process against controller and device selected by mode
+func manifestRun(process func(ctrl controller.Cloud, dev *device.Ctx, manifest *controller.Manifest) error) {
+	data, err := ioutil.ReadFile(manifestFile)
+	if err != nil {
+		log.Fatalf("cannot read manifest: %s", err)
+	}
+	manifest, err := controller.LoadManifest(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	deviceRun(func(ctrl controller.Cloud, dev *device.Ctx) error {
+		return process(ctrl, dev, manifest)
+	})
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "create or update objects of manifest on EVE",
+	Long: `Create or update datastores, images, network instances and apps described in yaml manifest (-f) on EVE.
+Objects are identified by names, uuids are generated from names and versions are bumped for changed objects.
+Objects not described in manifest are not touched.`,
+	PreRunE: devicePreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		manifestRun(func(ctrl controller.Cloud, dev *device.Ctx, manifest *controller.Manifest) error {
+			return ctrl.ManifestApply(dev, manifest)
+		})
+	},
+}
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "delete objects of manifest from EVE",
+	Long: `Delete apps and network instances described in yaml manifest (-f) from EVE.
+Images and datastores of manifest are deleted if they are not used by remaining apps and baseOS.`,
+	PreRunE: devicePreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		manifestRun(func(ctrl controller.Cloud, dev *device.Ctx, manifest *controller.Manifest) error {
+			return ctrl.ManifestDelete(dev, manifest)
+		})
+	},
+}
+
+func manifestInit() {
+	for _, cmd := range []*cobra.Command{applyCmd, deleteCmd} {
+		flags := cmd.Flags()
+		flags.StringVarP(&manifestFile, "file", "f", "", "yaml manifest")
+		if err := cobra.MarkFlagRequired(flags, "file"); err != nil {
+			log.Fatal(err)
+		}
+		flags.StringVarP(&controllerMode, "mode", "m", "adam://", "mode to use [file|proto|adam|zedcloud|fake]://<URL>")
+		planFlags(flags)
+	}
+}
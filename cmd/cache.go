@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/cachers"
+	"github.com/lf-edge/eden/pkg/utils"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "manage cache of logs and info",
+	Long: `Manage cache of logs and info of adam (adam.caching of config).
+Retention of cache (adam.caching.max-age, adam.caching.max-entries and adam.caching.max-size) is enforced on caching,
+prune applies it to the objects cached before.`,
+}
+
+//cachePreRun loads config for cache commands
+func cachePreRun(cmd *cobra.Command, args []string) error {
+	assingCobraToViper(cmd)
+	_, err := utils.LoadConfigFile(configFile)
+	if err != nil {
+		return fmt.Errorf("error reading config: %s", err.Error())
+	}
+	return nil
+}
+
+//cacheDevices return controller and UUIDs of devices selected with --device or all devices of controller
+func cacheDevices() (controller.Cloud, []uuid.UUID) {
+	ctrl, err := controller.CloudPrepare()
+	if err != nil {
+		log.Fatalf("CloudPrepare: %s", err)
+	}
+	if deviceSelector != "" {
+		dev, err := ctrl.GetDevice(deviceSelector)
+		if err != nil {
+			log.Fatalf("GetDevice: %s", err)
+		}
+		return ctrl, []uuid.UUID{dev.GetID()}
+	}
+	devices, err := ctrl.DeviceList()
+	if err != nil {
+		log.Fatalf("DeviceList: %s", err)
+	}
+	var result []uuid.UUID
+	for _, devID := range devices {
+		devUUID, err := uuid.FromString(devID)
+		if err != nil {
+			log.Fatalf("uuid.FromString(%s): %s", devID, err)
+		}
+		result = append(result, devUUID)
+	}
+	return ctrl, result
+}
+
+//printCacheStats prints statistics of cache of devices as table
+func printCacheStats(stats map[uuid.UUID][]cachers.Stats, devices []uuid.UUID) {
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+	if _, err := fmt.Fprintln(w, "DEVICE\tTYPE\tENTRIES\tSIZE\tOLDEST\tNEWEST"); err != nil {
+		log.Fatal(err)
+	}
+	for _, devUUID := range devices {
+		for _, s := range stats[devUUID] {
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", devUUID, s.Type, s.Entries,
+				humanize.Bytes(uint64(s.Bytes)), formatTime(s.Oldest), formatTime(s.Newest)); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:     "stats",
+	Short:   "show statistics of cache",
+	Long:    `Show number, size and time range of cached objects for every device and type.`,
+	PreRunE: cachePreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctrl, devices := cacheDevices()
+		stats := make(map[uuid.UUID][]cachers.Stats)
+		for _, devUUID := range devices {
+			s, err := ctrl.CacheStats(devUUID)
+			if err != nil {
+				log.Fatalf("CacheStats: %s", err)
+			}
+			stats[devUUID] = s
+		}
+		printCacheStats(stats, devices)
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "remove objects out of retention from cache",
+	Long: `Remove cached objects older than --max-age and out of the newest --max-entries objects or --max-size
+for every device and type. Retention from adam.caching of config is used if flags are not set.`,
+	PreRunE: cachePreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := cachers.Retention{
+			MaxAge:     viper.GetDuration("adam.caching.max-age"),
+			MaxEntries: viper.GetInt("adam.caching.max-entries"),
+			MaxBytes:   int64(viper.GetSizeInBytes("adam.caching.max-size")),
+		}
+		if r.IsEmpty() {
+			log.Fatal("retention is not set, use --max-age, --max-entries or --max-size")
+		}
+		ctrl, devices := cacheDevices()
+		removed := make(map[uuid.UUID][]cachers.Stats)
+		for _, devUUID := range devices {
+			s, err := ctrl.CachePrune(devUUID, r)
+			if err != nil {
+				log.Fatalf("CachePrune: %s", err)
+			}
+			removed[devUUID] = s
+		}
+		fmt.Println("Removed from cache:")
+		printCacheStats(removed, devices)
+	},
+}
+
+func cacheInit() {
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.PersistentFlags().StringVar(&configFile, "config", "", "path to config file")
+	cachePruneCmd.Flags().String("max-age", "0", "remove objects older than duration (e.g. 72h)")
+	cachePruneCmd.Flags().Int("max-entries", 0, "keep only the newest number of objects")
+	cachePruneCmd.Flags().String("max-size", "0", "keep only the newest objects with total size up to (e.g. 1GB)")
+}
@@ -4,65 +4,174 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"github.com/golang/protobuf/proto"
 	"github.com/lf-edge/eden/pkg/controller"
 	"github.com/lf-edge/eden/pkg/controller/adam"
+	"github.com/lf-edge/eden/pkg/controller/fake"
+	"github.com/lf-edge/eden/pkg/controller/zedcloud"
 	"github.com/lf-edge/eden/pkg/device"
+	"github.com/lf-edge/eden/pkg/utils"
 	"github.com/lf-edge/eve/api/go/config"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"io/ioutil"
 	"os"
 	"strings"
 )
 
+//planFlags registers flags of plan mode for commands which push config
+func planFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&configPlan, "plan", false, "print changes of config and apply them only with --yes")
+	flags.BoolVar(&configDryRun, "dry-run", false, "print changes of config and never apply them")
+	flags.BoolVar(&configApply, "yes", false, "apply changes of config in plan mode")
+}
+
+//planChanges prints semantic diff between old and new configs in plan or dry-run mode
+//and return true if changes should be applied
+func planChanges(oldConfig, newConfig []byte) (bool, error) {
+	if !configPlan && !configDryRun {
+		return true, nil
+	}
+	changes, err := controller.ConfigDiff(oldConfig, newConfig)
+	if err != nil {
+		return false, fmt.Errorf("ConfigDiff error: %s", err)
+	}
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	if len(changes) == 0 {
+		fmt.Println("no semantic changes")
+	}
+	if configDryRun {
+		fmt.Println("changes not applied in dry-run mode")
+		return false, nil
+	}
+	if !configApply {
+		fmt.Println("changes not applied, use --yes to apply them")
+		return false, nil
+	}
+	return true, nil
+}
+
 type configChanger interface {
 	getControllerAndDev() (controller.Cloud, *device.Ctx, error)
 	setControllerAndDev(controller.Cloud, *device.Ctx) error
 }
 
-type fileChanger struct {
-	fileConfig string
-	oldHash    [32]byte
+//configState keeps config of device obtained by changer to detect changes
+type configState struct {
+	oldHash   [32]byte
+	oldConfig []byte
 }
 
-func (ctx *fileChanger) getControllerAndDev() (controller.Cloud, *device.Ctx, error) {
-	if ctx.fileConfig == "" {
-		return nil, nil, fmt.Errorf("cannot use empty url for file")
+//parse parses deviceConfig with ctrl and remembers resulting config
+func (st *configState) parse(ctrl controller.Cloud, deviceConfig *config.EdgeDevConfig) (*device.Ctx, error) {
+	dev, err := ctrl.ConfigParse(deviceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("configParse error: %s", err)
 	}
-	if _, err := os.Lstat(ctx.fileConfig); os.IsNotExist(err) {
+	return dev, st.remember(ctrl, dev)
+}
+
+//remember saves current config of dev
+func (st *configState) remember(ctrl controller.Cloud, dev *device.Ctx) error {
+	res, err := ctrl.GetConfigBytes(dev, false)
+	if err != nil {
+		return fmt.Errorf("GetConfigBytes error: %s", err)
+	}
+	st.oldHash = sha256.Sum256(res)
+	st.oldConfig = res
+	return nil
+}
+
+//changed return current config of dev and true if it differs from remembered one
+//and changes are confirmed by plan mode
+func (st *configState) changed(ctrl controller.Cloud, dev *device.Ctx) ([]byte, bool, error) {
+	res, err := ctrl.GetConfigBytes(dev, false)
+	if err != nil {
+		return nil, false, fmt.Errorf("GetConfigBytes error: %s", err)
+	}
+	if st.oldHash == sha256.Sum256(res) {
+		log.Debug("config not modified")
+		return nil, false, nil
+	}
+	apply, err := planChanges(st.oldConfig, res)
+	return res, apply, err
+}
+
+//loadFile reads config from file with unmarshal and parses it with adam controller
+func (st *configState) loadFile(fileConfig string, unmarshal func(data []byte, deviceConfig *config.EdgeDevConfig) error) (controller.Cloud, *device.Ctx, error) {
+	if _, err := os.Lstat(fileConfig); os.IsNotExist(err) {
 		return nil, nil, err
 	}
 	var ctrl controller.Cloud = &controller.CloudCtx{Controller: &adam.Ctx{}}
-	data, err := ioutil.ReadFile(ctx.fileConfig)
+	data, err := ioutil.ReadFile(fileConfig)
 	if err != nil {
 		return nil, nil, fmt.Errorf("file reading error: %s", err)
 	}
 	var deviceConfig config.EdgeDevConfig
-	err = json.Unmarshal(data, &deviceConfig)
-	if err != nil {
+	if err = unmarshal(data, &deviceConfig); err != nil {
 		return nil, nil, fmt.Errorf("unmarshal error: %s", err)
 	}
-	dev, err := ctrl.ConfigParse(&deviceConfig)
+	dev, err := st.parse(ctrl, &deviceConfig)
 	if err != nil {
-		return nil, nil, fmt.Errorf("configParse error: %s", err)
+		return nil, nil, err
 	}
-	res, err := ctrl.GetConfigBytes(dev, false)
+	return ctrl, dev, nil
+}
+
+//loadController onboards controller and parses config of device selected with deviceSelector from it
+func (st *configState) loadController(ctrl controller.Cloud) (controller.Cloud, *device.Ctx, error) {
+	if err := ctrl.OnBoard(); err != nil {
+		return nil, nil, fmt.Errorf("OnBoard: %s", err)
+	}
+	devFirst, err := ctrl.GetDevice(deviceSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GetDevice error: %s", err)
+	}
+	configString, err := ctrl.ConfigGet(devFirst.GetID())
+	if err != nil {
+		return nil, nil, fmt.Errorf("ConfigGet error: %s", err)
+	}
+	var deviceConfig config.EdgeDevConfig
+	if err = json.Unmarshal([]byte(configString), &deviceConfig); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal error: %s", err)
+	}
+	dev, err := st.parse(ctrl, &deviceConfig)
 	if err != nil {
-		return nil, nil, fmt.Errorf("GetConfigBytes error: %s", err)
+		return nil, nil, err
 	}
-	ctx.oldHash = sha256.Sum256(res)
 	return ctrl, dev, nil
 }
 
-func (ctx *fileChanger) setControllerAndDev(ctrl controller.Cloud, dev *device.Ctx) error {
-	res, err := ctrl.GetConfigBytes(dev, false)
-	if err != nil {
-		return fmt.Errorf("GetConfigBytes error: %s", err)
+//syncController pushes config of dev with incremented version into controller
+func syncController(ctrl controller.Cloud, dev *device.Ctx) error {
+	dev.SetConfigVersion(dev.GetConfigVersion() + 1)
+	if err := ctrl.ConfigSync(dev); err != nil {
+		return fmt.Errorf("configSync error: %s", err)
 	}
-	newHash := sha256.Sum256(res)
-	if ctx.oldHash == newHash {
-		log.Debug("config not modified")
-		return nil
+	return nil
+}
+
+type fileChanger struct {
+	configState
+	fileConfig string
+}
+
+func (ctx *fileChanger) getControllerAndDev() (controller.Cloud, *device.Ctx, error) {
+	if ctx.fileConfig == "" {
+		return nil, nil, fmt.Errorf("cannot use empty url for file")
+	}
+	return ctx.loadFile(ctx.fileConfig, func(data []byte, deviceConfig *config.EdgeDevConfig) error {
+		return json.Unmarshal(data, deviceConfig)
+	})
+}
+
+func (ctx *fileChanger) setControllerAndDev(ctrl controller.Cloud, dev *device.Ctx) error {
+	res, apply, err := ctx.changed(ctrl, dev)
+	if err != nil || !apply {
+		return err
 	}
 	if res, err = controller.VersionIncrement(res); err != nil {
 		return fmt.Errorf("VersionIncrement error: %s", err)
@@ -74,9 +183,42 @@ func (ctx *fileChanger) setControllerAndDev(ctrl controller.Cloud, dev *device.C
 	return nil
 }
 
+//protoChanger works with config.EdgeDevConfig saved as binary protobuf
+type protoChanger struct {
+	configState
+	protoConfig string
+}
+
+func (ctx *protoChanger) getControllerAndDev() (controller.Cloud, *device.Ctx, error) {
+	if ctx.protoConfig == "" {
+		return nil, nil, fmt.Errorf("cannot use empty url for proto")
+	}
+	return ctx.loadFile(ctx.protoConfig, func(data []byte, deviceConfig *config.EdgeDevConfig) error {
+		return proto.Unmarshal(data, deviceConfig)
+	})
+}
+
+func (ctx *protoChanger) setControllerAndDev(ctrl controller.Cloud, dev *device.Ctx) error {
+	res, apply, err := ctx.changed(ctrl, dev)
+	if err != nil || !apply {
+		return err
+	}
+	if res, err = controller.VersionIncrement(res); err != nil {
+		return fmt.Errorf("VersionIncrement error: %s", err)
+	}
+	if res, err = controller.ConfigJSONToProto(res); err != nil {
+		return fmt.Errorf("ConfigJSONToProto error: %s", err)
+	}
+	if err = ioutil.WriteFile(ctx.protoConfig, res, 0755); err != nil {
+		return fmt.Errorf("WriteFile error: %s", err)
+	}
+	log.Debug("config modification done")
+	return nil
+}
+
 type adamChanger struct {
+	configState
 	adamUrl string
-	oldHash [32]byte
 }
 
 func (ctx *adamChanger) getControllerAndDev() (controller.Cloud, *device.Ctx, error) {
@@ -97,47 +239,97 @@ func (ctx *adamChanger) getControllerAndDev() (controller.Cloud, *device.Ctx, er
 	if err != nil {
 		return nil, nil, fmt.Errorf("CloudPrepare error: %s", err)
 	}
-	if err := ctrl.OnBoard(); err != nil {
-		return nil, nil, fmt.Errorf("OnBoard: %s", err)
+	return ctx.loadController(ctrl)
+}
+
+func (ctx *adamChanger) setControllerAndDev(ctrl controller.Cloud, dev *device.Ctx) error {
+	_, apply, err := ctx.changed(ctrl, dev)
+	if err != nil || !apply {
+		return err
 	}
-	devFirst, err := ctrl.GetDeviceFirst()
+	return syncController(ctrl, dev)
+}
+
+//zedcloudChanger works with zedcloud-compatible controller
+//url in mode overrides zedcloud.url from config
+type zedcloudChanger struct {
+	configState
+	zedcloudURL string
+}
+
+func (ctx *zedcloudChanger) getControllerAndDev() (controller.Cloud, *device.Ctx, error) {
+	vars, err := utils.InitVars()
 	if err != nil {
-		return nil, nil, fmt.Errorf("GetDeviceFirst error: %s", err)
+		return nil, nil, fmt.Errorf("InitVars error: %s", err)
 	}
-	configString, err := ctrl.ConfigGet(devFirst.GetID())
+	if ctx.zedcloudURL != "" {
+		vars.ZedcloudURL = fmt.Sprintf("https://%s", ctx.zedcloudURL)
+	}
+	ctrl, err := controller.CloudPrepareWithController(&zedcloud.Ctx{}, vars)
 	if err != nil {
-		return nil, nil, fmt.Errorf("ConfigGet error: %s", err)
+		return nil, nil, fmt.Errorf("CloudPrepareWithController error: %s", err)
 	}
-	var deviceConfig config.EdgeDevConfig
-	err = json.Unmarshal([]byte(configString), &deviceConfig)
+	return ctx.loadController(ctrl)
+}
+
+func (ctx *zedcloudChanger) setControllerAndDev(ctrl controller.Cloud, dev *device.Ctx) error {
+	_, apply, err := ctx.changed(ctrl, dev)
+	if err != nil || !apply {
+		return err
+	}
+	return syncController(ctrl, dev)
+}
+
+//fakeController is in-memory controller used by fake:// mode
+//the same instance is used by all commands inside of process to keep state between them
+var fakeController controller.Controller = &fake.Ctx{}
+
+//SetFakeController replace controller used by fake:// mode, e.g. with prepared fake.Ctx in tests
+func SetFakeController(ctrl controller.Controller) {
+	fakeController = ctrl
+}
+
+type fakeChanger struct {
+	configState
+	controller controller.Controller
+}
+
+func (ctx *fakeChanger) getControllerAndDev() (controller.Cloud, *device.Ctx, error) {
+	vars, err := utils.InitVars()
 	if err != nil {
-		return nil, nil, fmt.Errorf("unmarshal error: %s", err)
+		return nil, nil, fmt.Errorf("InitVars error: %s", err)
 	}
-	dev, err := ctrl.ConfigParse(&deviceConfig)
+	ctrl, err := controller.CloudPrepareWithController(ctx.controller, vars)
 	if err != nil {
-		return nil, nil, fmt.Errorf("configParse error: %s", err)
+		return nil, nil, fmt.Errorf("CloudPrepareWithController error: %s", err)
 	}
-	res, err := ctrl.GetConfigBytes(dev, false)
+	if err := ctrl.OnBoard(); err != nil {
+		return nil, nil, fmt.Errorf("OnBoard: %s", err)
+	}
+	dev, err := ctrl.GetDevice(deviceSelector)
 	if err != nil {
-		return nil, nil, fmt.Errorf("GetConfigBytes error: %s", err)
+		return nil, nil, fmt.Errorf("GetDevice error: %s", err)
+	}
+	if err = ctx.remember(ctrl, dev); err != nil {
+		return nil, nil, err
 	}
-	ctx.oldHash = sha256.Sum256(res)
 	return ctrl, dev, nil
 }
 
-func (ctx *adamChanger) setControllerAndDev(ctrl controller.Cloud, dev *device.Ctx) error {
-	res, err := ctrl.GetConfigBytes(dev, false)
-	if err != nil {
-		return fmt.Errorf("GetConfigBytes error: %s", err)
-	}
-	newHash := sha256.Sum256(res)
-	if ctx.oldHash == newHash {
-		log.Debug("config not modified")
-		return nil
+func (ctx *fakeChanger) setControllerAndDev(ctrl controller.Cloud, dev *device.Ctx) error {
+	_, apply, err := ctx.changed(ctrl, dev)
+	if err != nil || !apply {
+		return err
 	}
 	dev.SetConfigVersion(dev.GetConfigVersion() + 1)
-	if err := ctrl.ConfigSync(dev); err != nil {
-		return fmt.Errorf("configSync error: %s", err)
+	//state file is not updated for fake controller, so only validation of ConfigSync is used
+	res, err := ctrl.GetConfigValidated(dev)
+	if err != nil {
+		return fmt.Errorf("GetConfigValidated error: %s", err)
+	}
+	if err = ctrl.ConfigSet(dev.GetID(), res); err != nil {
+		return fmt.Errorf("ConfigSet error: %s", err)
 	}
+	log.Debugf("config for fake controller: %s", res)
 	return nil
 }
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/info"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var deviceCmd = &cobra.Command{
+	Use:   "device",
+	Short: "work with devices registered in controller",
+	Long: `Work with devices registered in controller.
+Use global --device flag with uuid, serial or name of device to select device for other commands.`,
+}
+
+var deviceLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "list devices",
+	Long:  `List devices registered in controller with their serials, onboarding state and time of last info.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		_, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading config: %s", err.Error())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		ctrl, err := controller.CloudPrepare()
+		if err != nil {
+			log.Fatalf("CloudPrepare: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 1, 1, 2, ' ', 0)
+		if _, err = fmt.Fprintln(w, "UUID\tSERIAL\tNAME\tSTATE\tLAST INFO"); err != nil {
+			log.Fatal(err)
+		}
+		for _, dev := range ctrl.ListDevices() {
+			var lastInfo time.Time
+			//order of existing info depends on loader, so keep the newest timestamp of all messages
+			handler := func(im *info.ZInfoMsg, ds []*einfo.ZInfoMsgInterface, infoType einfo.ZInfoType) bool {
+				if t, err := ptypes.Timestamp(im.AtTimeStamp); err == nil && t.After(lastInfo) {
+					lastInfo = t
+				}
+				return false
+			}
+			if err = ctrl.InfoLastCallback(dev.GetID(), map[string]string{}, einfo.ZAll, handler); err != nil {
+				log.Debugf("InfoLastCallback for %s: %s", dev.GetID(), err)
+			}
+			state, lastInfoString := "registered", "-"
+			if !lastInfo.IsZero() {
+				state = "onboarded"
+				lastInfoString = lastInfo.Format(time.RFC3339)
+			}
+			if _, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", dev.GetID(), dev.GetSerial(), dev.GetName(), state, lastInfoString); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err = w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func deviceInit() {
+	deviceCmd.AddCommand(deviceLsCmd)
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/info"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+var devModelName, devModelOutput string
+
+//devModelPreRun loads config for devmodel commands
+func devModelPreRun(cmd *cobra.Command, args []string) error {
+	assingCobraToViper(cmd)
+	_, err := utils.LoadConfigFile(configFile)
+	if err != nil {
+		return fmt.Errorf("error reading config: %s", err.Error())
+	}
+	return nil
+}
+
+var devModelCmd = &cobra.Command{
+	Use:   "devmodel",
+	Short: "device models of EVE",
+	Long: `Device models describe physicalIOs, networks, system adapters and adapters for switches of EVE.
+Models are defined in yaml files inside eden.models directory of config, Empty and Qemu models are built-in.`,
+}
+
+var devModelLsCmd = &cobra.Command{
+	Use:     "ls",
+	Short:   "list device models",
+	Long:    `List built-in device models and models from yaml files inside eden.models directory of config.`,
+	PreRunE: devModelPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		models, err := controller.ListDevModelFiles()
+		if err != nil {
+			log.Fatalf("ListDevModelFiles: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+		if _, err = fmt.Fprintln(w, "NAME\tSOURCE\tPHYSICALIOS\tADAPTERS\tADAPTERS FOR SWITCHES"); err != nil {
+			log.Fatal(err)
+		}
+		for _, model := range models {
+			var physicalIOs, adapters []string
+			for _, el := range model.PhysicalIOs {
+				physicalIOs = append(physicalIOs, fmt.Sprintf("%s(%s)", el.Phylabel, el.Type))
+			}
+			for _, el := range model.Adapters {
+				adapters = append(adapters, el.Name)
+			}
+			name := model.Name
+			if name == viper.GetString("eve.devmodel") {
+				name += "*"
+			}
+			if _, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, model.Source, strings.Join(physicalIOs, ","),
+				strings.Join(adapters, ","), strings.Join(model.AdaptersForSwitches, ",")); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err = w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var devModelShowCmd = &cobra.Command{
+	Use:   "show [name|file.yaml]",
+	Short: "show device model",
+	Long: `Validate and print device model in yaml by name or from file.
+Model from eve.devmodel of config is used if not set.`,
+	Args:    cobra.MaximumNArgs(1),
+	PreRunE: devModelPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		name := viper.GetString("eve.devmodel")
+		if len(args) > 0 {
+			name = args[0]
+		}
+		model, err := controller.FindDevModelFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		data, err := yaml.Marshal(model)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("#source: %s\n%s", model.Source, data)
+	},
+}
+
+var devModelDiscoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "generate device model from info of EVE",
+	Long: `Generate device model from network interfaces and assignable adapters in the last device info of EVE.
+Save it into eden.models directory (-o) and set eve.devmodel to use it for onboarding of device.`,
+	PreRunE: devModelPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		changer, err := getControllerChanger()
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctrl, dev, err := changer.getControllerAndDev()
+		if err != nil {
+			log.Fatalf("getControllerAndDev error: %s", err)
+		}
+		devInfo, err := latestInfo(ctrl, dev, einfo.ZInfoDinfo, func(im *info.ZInfoMsg) string {
+			if len(im.GetDinfo().GetNetwork()) > 0 || len(im.GetDinfo().GetAssignableAdapters()) > 0 {
+				return "dinfo"
+			}
+			return ""
+		})
+		if err != nil {
+			log.Fatalf("cannot obtain device info: %s", err)
+		}
+		im, ok := devInfo["dinfo"]
+		if !ok {
+			log.Fatalf("no device info with network interfaces or adapters from %s", dev.GetID())
+		}
+		dinfo := im.GetDinfo()
+		name := devModelName
+		if name == "" {
+			name = regexp.MustCompile("[^A-Za-z0-9_.-]+").ReplaceAllString(dinfo.GetMinfo().GetProductName(), "-")
+		}
+		if name == "" || name == "-" {
+			name = "Discovered"
+		}
+		model := controller.DevModelDiscover(name, dinfo)
+		if problems := model.Validate(); len(problems) > 0 {
+			log.Warnf("discovered model needs editing:\n%s", strings.Join(problems, "\n"))
+		}
+		data, err := yaml.Marshal(model)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if devModelOutput == "" {
+			fmt.Print(string(data))
+			return
+		}
+		if err = ioutil.WriteFile(devModelOutput, data, 0644); err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("device model %s saved into %s", name, devModelOutput)
+	},
+}
+
+func devModelInit() {
+	devModelCmd.AddCommand(devModelLsCmd)
+	devModelCmd.AddCommand(devModelShowCmd)
+	devModelCmd.AddCommand(devModelDiscoverCmd)
+	discoverFlags := devModelDiscoverCmd.Flags()
+	discoverFlags.StringVarP(&controllerMode, "mode", "m", "adam://", "mode to use [file|proto|adam|zedcloud|fake]://<URL>")
+	discoverFlags.StringVarP(&devModelName, "name", "n", "", "name of model (product name of device if empty)")
+	discoverFlags.StringVarP(&devModelOutput, "output", "o", "", "file to save model (stdout if empty)")
+}
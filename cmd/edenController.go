@@ -1,25 +1,33 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/docker/docker/pkg/fileutils"
+	"github.com/lf-edge/eden/pkg/controller"
 	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/utils"
 	"github.com/lf-edge/eve/api/go/config"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 var controllerMode string
 var baseOSImageActivate bool
 var configItems map[string]string
+var configPlan, configDryRun, configApply bool
+var lintFile, lintDevModel string
 
 func getParams(line, regEx string) (paramsMap map[string]string) {
 
@@ -38,18 +46,42 @@ func getParams(line, regEx string) (paramsMap map[string]string) {
 func getControllerMode() (modeType, modeURL string, err error) {
 	params := getParams(controllerMode, defaults.DefaultControllerModePattern)
 	if len(params) == 0 {
-		return "", "", fmt.Errorf("cannot parse mode (not [file|proto|adam|zedcloud]://<URL>): %s", controllerMode)
+		return "", "", fmt.Errorf("cannot parse mode (not [file|proto|adam|zedcloud|fake]://<URL>): %s", controllerMode)
 	}
 	ok := false
 	if modeType, ok = params["Type"]; !ok {
-		return "", "", fmt.Errorf("cannot parse modeType (not [file|proto|adam|zedcloud]://<URL>): %s", controllerMode)
+		return "", "", fmt.Errorf("cannot parse modeType (not [file|proto|adam|zedcloud|fake]://<URL>): %s", controllerMode)
 	}
 	if modeURL, ok = params["URL"]; !ok {
-		return "", "", fmt.Errorf("cannot parse modeURL (not [file|proto|adam|zedcloud]://<URL>): %s", controllerMode)
+		return "", "", fmt.Errorf("cannot parse modeURL (not [file|proto|adam|zedcloud|fake]://<URL>): %s", controllerMode)
 	}
 	return
 }
 
+//getControllerChanger return configChanger for mode defined in controllerMode
+func getControllerChanger() (configChanger, error) {
+	modeType, modeURL, err := getControllerMode()
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Mode type: %s", modeType)
+	log.Infof("Mode url: %s", modeURL)
+	switch modeType {
+	case "file":
+		return &fileChanger{fileConfig: modeURL}, nil
+	case "proto":
+		return &protoChanger{protoConfig: modeURL}, nil
+	case "adam":
+		return &adamChanger{adamUrl: modeURL}, nil
+	case "zedcloud":
+		return &zedcloudChanger{zedcloudURL: modeURL}, nil
+	case "fake":
+		return &fakeChanger{controller: fakeController}, nil
+	default:
+		return nil, fmt.Errorf("not implemented type: %s", modeType)
+	}
+}
+
 var controllerCmd = &cobra.Command{
 	Use:   "controller",
 	Short: "interact with controller",
@@ -80,22 +112,10 @@ var edgeNodeReboot = &cobra.Command{
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		modeType, modeURL, err := getControllerMode()
+		changer, err := getControllerChanger()
 		if err != nil {
 			log.Fatal(err)
 		}
-		log.Infof("Mode type: %s", modeType)
-		log.Infof("Mode url: %s", modeURL)
-		var changer configChanger
-		switch modeType {
-		case "file":
-			changer = &fileChanger{fileConfig: modeURL}
-		case "adam":
-			changer = &adamChanger{adamUrl: modeURL}
-
-		default:
-			log.Fatalf("Not implemented type: %s", modeType)
-		}
 
 		ctrl, dev, err := changer.getControllerAndDev()
 		if err != nil {
@@ -175,22 +195,10 @@ var edgeNodeEVEImageUpdate = &cobra.Command{
 				log.Fatalf("DownloadFile error: %s", err)
 			}
 		}
-		modeType, modeURL, err := getControllerMode()
+		changer, err := getControllerChanger()
 		if err != nil {
 			log.Fatal(err)
 		}
-		log.Infof("Mode type: %s", modeType)
-		log.Infof("Mode url: %s", modeURL)
-		var changer configChanger
-		switch modeType {
-		case "file":
-			changer = &fileChanger{fileConfig: modeURL}
-		case "adam":
-			changer = &adamChanger{adamUrl: modeURL}
-
-		default:
-			log.Fatalf("Not implemented type: %s", modeType)
-		}
 
 		ctrl, dev, err := changer.getControllerAndDev()
 		if err != nil {
@@ -362,22 +370,10 @@ var edgeNodeEVEImageRemove = &cobra.Command{
 				log.Fatalf("DownloadFile error: %s", err)
 			}
 		}
-		modeType, modeURL, err := getControllerMode()
+		changer, err := getControllerChanger()
 		if err != nil {
 			log.Fatal(err)
 		}
-		log.Infof("Mode type: %s", modeType)
-		log.Infof("Mode url: %s", modeURL)
-		var changer configChanger
-		switch modeType {
-		case "file":
-			changer = &fileChanger{fileConfig: modeURL}
-		case "adam":
-			changer = &adamChanger{adamUrl: modeURL}
-
-		default:
-			log.Fatalf("Not implemented type: %s", modeType)
-		}
 
 		ctrl, dev, err := changer.getControllerAndDev()
 		if err != nil {
@@ -448,22 +444,10 @@ var edgeNodeUpdate = &cobra.Command{
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		modeType, modeURL, err := getControllerMode()
+		changer, err := getControllerChanger()
 		if err != nil {
 			log.Fatal(err)
 		}
-		log.Infof("Mode type: %s", modeType)
-		log.Infof("Mode url: %s", modeURL)
-		var changer configChanger
-		switch modeType {
-		case "file":
-			changer = &fileChanger{fileConfig: modeURL}
-		case "adam":
-			changer = &adamChanger{adamUrl: modeURL}
-
-		default:
-			log.Fatalf("Not implemented type: %s", modeType)
-		}
 
 		ctrl, dev, err := changer.getControllerAndDev()
 		if err != nil {
@@ -492,22 +476,10 @@ var edgeNodeGetConfig = &cobra.Command{
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		modeType, modeURL, err := getControllerMode()
+		changer, err := getControllerChanger()
 		if err != nil {
 			log.Fatal(err)
 		}
-		log.Infof("Mode type: %s", modeType)
-		log.Infof("Mode url: %s", modeURL)
-		var changer configChanger
-		switch modeType {
-		case "file":
-			changer = &fileChanger{fileConfig: modeURL}
-		case "adam":
-			changer = &adamChanger{adamUrl: modeURL}
-
-		default:
-			log.Fatalf("Not implemented type: %s", modeType)
-		}
 
 		ctrl, dev, err := changer.getControllerAndDev()
 		if err != nil {
@@ -523,18 +495,218 @@ var edgeNodeGetConfig = &cobra.Command{
 	},
 }
 
+var edgeNodeHistory = &cobra.Command{
+	Use:   "history",
+	Short: "list configs pushed into controller",
+	Long:  `List versions of EVE config pushed into controller with time and command, which pushed them.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		_, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading configFile: %s", err.Error())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		changer, err := getControllerChanger()
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctrl, dev, err := changer.getControllerAndDev()
+		if err != nil {
+			log.Fatalf("getControllerAndDev error: %s", err)
+		}
+		snapshots, err := ctrl.ConfigHistory(dev.GetID())
+		if err != nil {
+			log.Fatalf("ConfigHistory error: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 1, 1, 2, ' ', 0)
+		if _, err = fmt.Fprintln(w, "VERSION\tTIMESTAMP\tCOMMAND"); err != nil {
+			log.Fatal(err)
+		}
+		for _, snapshot := range snapshots {
+			if _, err = fmt.Fprintf(w, "%s\t%s\t%s\n", snapshot.Version, snapshot.Timestamp.Format(time.RFC3339), snapshot.Command); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err = w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var edgeNodeShow = &cobra.Command{
+	Use:   "show <version>",
+	Short: "show config from history",
+	Long:  `Show EVE config with version from history of controller.`,
+	Args:  cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		_, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading configFile: %s", err.Error())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		changer, err := getControllerChanger()
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctrl, dev, err := changer.getControllerAndDev()
+		if err != nil {
+			log.Fatalf("getControllerAndDev error: %s", err)
+		}
+		snapshot, err := ctrl.ConfigHistoryGet(dev.GetID(), args[0])
+		if err != nil {
+			log.Fatalf("ConfigHistoryGet error: %s", err)
+		}
+		var out bytes.Buffer
+		if err = json.Indent(&out, snapshot.Config, "", "    "); err != nil {
+			log.Fatalf("Indent error: %s", err)
+		}
+		fmt.Printf("# version %s pushed at %s by '%s'\n", snapshot.Version, snapshot.Timestamp.Format(time.RFC3339), snapshot.Command)
+		fmt.Println(out.String())
+	},
+}
+
+var edgeNodeRollback = &cobra.Command{
+	Use:   "rollback <version>",
+	Short: "push config from history",
+	Long:  `Push EVE config with version from history of controller again with bumped version.`,
+	Args:  cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		_, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading configFile: %s", err.Error())
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		changer, err := getControllerChanger()
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctrl, dev, err := changer.getControllerAndDev()
+		if err != nil {
+			log.Fatalf("getControllerAndDev error: %s", err)
+		}
+		if configPlan || configDryRun {
+			snapshot, err := ctrl.ConfigHistoryGet(dev.GetID(), args[0])
+			if err != nil {
+				log.Fatalf("ConfigHistoryGet error: %s", err)
+			}
+			current, err := ctrl.GetConfigBytes(dev, false)
+			if err != nil {
+				log.Fatalf("GetConfigBytes error: %s", err)
+			}
+			apply, err := planChanges(current, snapshot.Config)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if !apply {
+				return
+			}
+		}
+		if err = ctrl.ConfigRollback(dev, args[0]); err != nil {
+			log.Fatalf("ConfigRollback error: %s", err)
+		}
+		log.Infof("config version %s pushed as version %d", args[0], dev.GetConfigVersion()+1)
+	},
+}
+
+var controllerLint = &cobra.Command{
+	Use:   "lint",
+	Short: "check EVE config",
+	Long: `Check references between objects of EVE config of device in controller or in file (-f) in json or protobuf.
+Adapters of switch network instances are checked against device model of device or --devmodel for file.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		_, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading configFile: %s", err.Error())
+		}
+		if lintFile != "" {
+			//mode is required for controller commands, but not used for file
+			if err = cmd.Flags().Set("mode", fmt.Sprintf("file://%s", lintFile)); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var deviceConfig config.EdgeDevConfig
+		var adaptersForSwitch []string
+		if lintFile != "" {
+			data, err := ioutil.ReadFile(lintFile)
+			if err != nil {
+				log.Fatalf("file reading error: %s", err)
+			}
+			if err = json.Unmarshal(data, &deviceConfig); err != nil {
+				if data, err = controller.ConfigProtoToJSON(data); err != nil {
+					log.Fatalf("%s is neither json nor protobuf config: %s", lintFile, err)
+				}
+				if err = json.Unmarshal(data, &deviceConfig); err != nil {
+					log.Fatalf("unmarshal error: %s", err)
+				}
+			}
+			if lintDevModel != "" {
+				devModel, err := (&controller.CloudCtx{}).GetDevModelByName(lintDevModel)
+				if err != nil {
+					log.Fatalf("GetDevModelByName error: %s", err)
+				}
+				adaptersForSwitch = devModel.GetAdaptersForSwitches()
+			}
+		} else {
+			changer, err := getControllerChanger()
+			if err != nil {
+				log.Fatal(err)
+			}
+			ctrl, dev, err := changer.getControllerAndDev()
+			if err != nil {
+				log.Fatalf("getControllerAndDev error: %s", err)
+			}
+			res, err := ctrl.GetConfigBytes(dev, false)
+			if err != nil {
+				log.Fatalf("GetConfigBytes error: %s", err)
+			}
+			if err = json.Unmarshal(res, &deviceConfig); err != nil {
+				log.Fatalf("unmarshal error: %s", err)
+			}
+			adaptersForSwitch = dev.GetAdaptersForSwitch()
+		}
+		problems := controller.ConfigValidate(&deviceConfig, adaptersForSwitch)
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		if len(problems) > 0 {
+			log.Fatalf("%d problems found in config", len(problems))
+		}
+		log.Info("no problems found in config")
+	},
+}
+
 func controllerInit() {
 	controllerCmd.AddCommand(edgeNode)
+	controllerCmd.AddCommand(controllerLint)
 	edgeNode.AddCommand(edgeNodeReboot)
 	edgeNode.AddCommand(edgeNodeEVEImageUpdate)
 	edgeNode.AddCommand(edgeNodeEVEImageRemove)
 	edgeNode.AddCommand(edgeNodeUpdate)
 	edgeNode.AddCommand(edgeNodeGetConfig)
+	edgeNode.AddCommand(edgeNodeHistory)
+	edgeNode.AddCommand(edgeNodeShow)
+	edgeNode.AddCommand(edgeNodeRollback)
 	pf := controllerCmd.PersistentFlags()
-	pf.StringVarP(&controllerMode, "mode", "m", "", "mode to use [file|proto|adam|zedcloud]://<URL> (required)")
+	pf.StringVarP(&controllerMode, "mode", "m", "", "mode to use [file|proto|adam|zedcloud|fake]://<URL> (required)")
 	if err := cobra.MarkFlagRequired(pf, "mode"); err != nil {
 		log.Fatal(err)
 	}
+	controllerLint.Flags().StringVarP(&lintFile, "file", "f", "", "file with config to check instead of config in controller")
+	controllerLint.Flags().StringVar(&lintDevModel, "devmodel", "", "device model to check adapters of switch network instances in file")
+	edgeNodeFlags := edgeNode.PersistentFlags()
+	planFlags(edgeNodeFlags)
 	edgeNodeEVEImageUpdateFlags := edgeNodeEVEImageUpdate.Flags()
 	edgeNodeEVEImageUpdateFlags.StringVarP(&baseOSVersion, "os-version", "", fmt.Sprintf("%s-%s-%s", defaults.DefaultBaseOSVersion, eveHV, eveArch), "version of ROOTFS")
 	edgeNodeEVEImageUpdateFlags.BoolVarP(&getFromFileName, "from-filename", "", true, "get version from filename")
@@ -8,7 +8,6 @@ import (
 	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/utils"
 	"github.com/lf-edge/eve/api/go/config"
-	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -155,52 +154,38 @@ var eveUpdateCmd = &cobra.Command{
 			log.Fatalf("AddBaseOsConfig: %s", err)
 		}
 
-		devices, err := ctrl.DeviceList()
+		dev, err := ctrl.GetDevice(deviceSelector)
 		if err != nil {
-			log.Fatalf("DeviceList: %s", err)
-		}
-		for _, devID := range devices {
-			devUUID, err := uuid.FromString(devID)
-			if err != nil {
-				log.Fatalf("uuidGet: %s", err)
+			log.Fatalf("GetDevice: %s", err)
+		}
+		devUUID := dev.GetID()
+		if eveSSHKey != "" {
+			b, err := ioutil.ReadFile(eveSSHKey)
+			switch {
+			case err != nil && os.IsNotExist(err):
+				log.Fatalf("sshKey file %s does not exist", eveSSHKey)
+			case err != nil:
+				log.Fatalf("error reading sshKey file %s: %v", eveSSHKey, err)
 			}
-
-			deviceCtx, err := ctrl.GetDeviceUUID(devUUID)
-			if err != nil {
-				deviceCtx, err = ctrl.AddDevice(devUUID)
-				{
-					log.Fatal("Fail in add device: ", err)
-				}
+			dev.SetConfigItem("debug.enable.ssh", string(b))
+		}
+		dev.SetBaseOSConfig([]string{defaults.DefaultBaseID})
+		err = ctrl.ConfigSync(dev)
+		log.Info("Request for update sended")
+		if wait {
+			log.Info("Please wait for operation ending")
+			if err := ctrl.InfoChecker(devUUID, map[string]string{"devId": devUUID.String(), "shortVersion": baseOSVersion}, einfo.ZInfoDevSW, einfo.HandleFirst, einfo.InfoAny, 500); err != nil {
+				log.Fatal("Fail in waiting for base image update init: ", err)
 			}
-			if eveSSHKey != "" {
-				b, err := ioutil.ReadFile(eveSSHKey)
-				switch {
-				case err != nil && os.IsNotExist(err):
-					log.Fatalf("sshKey file %s does not exist", eveSSHKey)
-				case err != nil:
-					log.Fatalf("error reading sshKey file %s: %v", eveSSHKey, err)
-				}
-				deviceCtx.SetConfigItem("debug.enable.ssh", string(b))
+			log.Info("Request for update received by EVE")
+			if err := ctrl.InfoChecker(devUUID, map[string]string{"devId": devUUID.String(), "shortVersion": baseOSVersion, "downloadProgress": "100"}, einfo.ZInfoDevSW, einfo.HandleFirst, einfo.InfoAny, 1000); err != nil {
+				log.Fatal("Fail in waiting for base image download progress: ", err)
 			}
-			deviceCtx.SetBaseOSConfig([]string{defaults.DefaultBaseID})
-			err = ctrl.ConfigSync(deviceCtx)
-			log.Info("Request for update sended")
-			if wait {
-				log.Info("Please wait for operation ending")
-				if err := ctrl.InfoChecker(devUUID, map[string]string{"devId": devUUID.String(), "shortVersion": baseOSVersion}, einfo.ZInfoDevSW, einfo.HandleFirst, einfo.InfoAny, 500); err != nil {
-					log.Fatal("Fail in waiting for base image update init: ", err)
-				}
-				log.Info("Request for update received by EVE")
-				if err := ctrl.InfoChecker(devUUID, map[string]string{"devId": devUUID.String(), "shortVersion": baseOSVersion, "downloadProgress": "100"}, einfo.ZInfoDevSW, einfo.HandleFirst, einfo.InfoAny, 1000); err != nil {
-					log.Fatal("Fail in waiting for base image download progress: ", err)
-				}
-				log.Info("New image downloaded by EVE")
-				if err := ctrl.InfoChecker(devUUID, map[string]string{"devId": devUUID.String(), "shortVersion": baseOSVersion, "status": "INSTALLED", "partitionState": "(inprogress|active)"}, einfo.ZInfoDevSW, einfo.HandleFirst, einfo.InfoAny, 1000); err != nil {
-					log.Fatal("Fail in waiting for base image installed status: ", err)
-				}
-				log.Info("Update done")
+			log.Info("New image downloaded by EVE")
+			if err := ctrl.InfoChecker(devUUID, map[string]string{"devId": devUUID.String(), "shortVersion": baseOSVersion, "status": "INSTALLED", "partitionState": "(inprogress|active)"}, einfo.ZInfoDevSW, einfo.HandleFirst, einfo.InfoAny, 1000); err != nil {
+				log.Fatal("Fail in waiting for base image installed status: ", err)
 			}
-			break
+			log.Info("Update done")
 		}
 
 	},
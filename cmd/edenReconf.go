@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/lf-edge/eden/pkg/controller"
 	"github.com/lf-edge/eden/pkg/utils"
-	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -41,36 +40,30 @@ var reconfCmd = &cobra.Command{
 		if err := ctrl.OnBoard(); err != nil {
 			log.Fatalf("OnBoard: %s", err)
 		}
-		devices, err := ctrl.DeviceList()
+		dev, err := ctrl.GetDevice(deviceSelector)
 		if err != nil {
-			log.Fatalf("DeviceList: %s", err)
+			log.Fatalf("GetDevice: %s", err)
 		}
-		for _, devID := range devices {
-			devUUID, err := uuid.FromString(devID)
+		devUUID := dev.GetID()
+		if getConfig {
+			data, err := ctrl.ConfigGet(devUUID)
 			if err != nil {
-				log.Fatalf("uuidGet: %s", err)
+				log.Fatalf("ConfigSet: %s", err)
 			}
-			if getConfig {
-				data, err := ctrl.ConfigGet(devUUID)
-				if err != nil {
-					log.Fatalf("ConfigSet: %s", err)
-				}
-				if err = ioutil.WriteFile(args[0], []byte(data), 0755); err != nil {
-					log.Fatalf("WriteFile: %s", err)
-				}
-				log.Infof("File saved: %s", args[0])
-			} else {
-				data, err := ioutil.ReadFile(args[0])
-				if err != nil {
-					log.Fatalf("File reading error:", err)
-					return
-				}
-				if err = ctrl.ConfigSet(devUUID, data); err != nil {
-					log.Fatalf("ConfigSet: %s", err)
-				}
-				log.Infof("File loaded: %s", args[0])
+			if err = ioutil.WriteFile(args[0], []byte(data), 0755); err != nil {
+				log.Fatalf("WriteFile: %s", err)
 			}
-			break
+			log.Infof("File saved: %s", args[0])
+		} else {
+			data, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				log.Fatalf("File reading error:", err)
+				return
+			}
+			if err = ctrl.ConfigSet(devUUID, data); err != nil {
+				log.Fatalf("ConfigSet: %s", err)
+			}
+			log.Infof("File loaded: %s", args[0])
 		}
 	},
 }
@@ -42,6 +42,9 @@ var startCmd = &cobra.Command{
 			adamForce = viper.GetBool("adam.force")
 			adamRemoteRedisURL = viper.GetString("adam.redis.adam")
 			adamRemoteRedis = viper.GetBool("adam.remote.redis")
+			adamEmbedded = viper.GetBool("adam.embedded")
+			adamPidFile = utils.ResolveAbsPath(viper.GetString("adam.pid"))
+			adamLogFile = utils.ResolveAbsPath(viper.GetString("adam.log"))
 			redisTag = viper.GetString("redis.tag")
 			redisPort = viper.GetInt("redis.port")
 			redisDist = utils.ResolveAbsPath(viper.GetString("redis.dist"))
@@ -76,18 +79,28 @@ var startCmd = &cobra.Command{
 		if err != nil {
 			log.Fatalf("cannot obtain executable path: %s", err)
 		}
-		if err := utils.StartRedis(redisPort, redisPath, redisForce, redisTag); err != nil {
+		if adamEmbedded {
+			log.Info("Skip redis start: embedded adam uses file storage")
+		} else if err := utils.StartRedis(redisPort, redisPath, redisForce, redisTag); err != nil {
 			log.Errorf("cannot start redis: %s", err)
 		} else {
 			log.Infof("Redis is running and accessible on port %d", redisPort)
 		}
-		if !adamRemoteRedis {
-			adamRemoteRedisURL = ""
-		}
-		if err := utils.StartAdam(adamPort, adamPath, adamForce, adamTag, adamRemoteRedisURL); err != nil {
-			log.Errorf("cannot start adam: %s", err)
+		if adamEmbedded {
+			if err := utils.StartAdamEmbedded(command, adamPort, adamPath, adamLogFile, adamPidFile); err != nil {
+				log.Errorf("cannot start embedded adam: %s", err)
+			} else {
+				log.Infof("Embedded adam is running and accesible on port %d", adamPort)
+			}
 		} else {
-			log.Infof("Adam is running and accesible on port %d", adamPort)
+			if !adamRemoteRedis {
+				adamRemoteRedisURL = ""
+			}
+			if err := utils.StartAdam(adamPort, adamPath, adamForce, adamTag, adamRemoteRedisURL); err != nil {
+				log.Errorf("cannot start adam: %s", err)
+			} else {
+				log.Infof("Adam is running and accesible on port %d", adamPort)
+			}
 		}
 		if err := utils.StartEServer(command, eserverPort, eserverImageDist, eserverLogFile, eserverPidFile); err != nil {
 			log.Errorf("cannot start eserver: %s", err)
@@ -113,6 +126,9 @@ func startInit() {
 	startCmd.Flags().BoolVarP(&adamForce, "adam-force", "", false, "adam force rebuild")
 	startCmd.Flags().StringVarP(&adamRemoteRedisURL, "adam-redis-url", "", "", "adam remote redis url")
 	startCmd.Flags().BoolVarP(&adamRemoteRedis, "adam-redis", "", true, "use adam remote redis")
+	startCmd.Flags().BoolVarP(&adamEmbedded, "adam-embedded", "", false, "run adam inside eden process instead of docker")
+	startCmd.Flags().StringVarP(&adamPidFile, "adam-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "adam.pid"), "file for save embedded adam pid")
+	startCmd.Flags().StringVarP(&adamLogFile, "adam-log", "", filepath.Join(currentPath, defaults.DefaultDist, "adam.log"), "file for save embedded adam log")
 	startCmd.Flags().StringVarP(&redisTag, "redis-tag", "", defaults.DefaultRedisTag, "tag on redis container to pull")
 	startCmd.Flags().StringVarP(&redisDist, "redis-dist", "", filepath.Join(currentPath, defaults.DefaultDist, defaults.DefaultRedisDist), "redis dist to start (required)")
 	startCmd.Flags().IntVarP(&redisPort, "redis-port", "", defaults.DefaultRedisPort, "redis dist to start")
@@ -24,6 +24,9 @@ var statusCmd = &cobra.Command{
 		if viperLoaded {
 			eserverPidFile = utils.ResolveAbsPath(viper.GetString("eden.eserver.pid"))
 			evePidFile = utils.ResolveAbsPath(viper.GetString("eve.pid"))
+			adamEmbedded = viper.GetBool("adam.embedded")
+			adamPidFile = utils.ResolveAbsPath(viper.GetString("adam.pid"))
+			adamLogFile = utils.ResolveAbsPath(viper.GetString("adam.log"))
 		}
 		return nil
 	},
@@ -45,21 +48,31 @@ var statusCmd = &cobra.Command{
 			}
 		}
 		fmt.Println()
-		statusAdam, err := utils.StatusAdam()
-		if err != nil {
+		if adamEmbedded {
+			statusAdam, err := utils.StatusAdamEmbedded(adamPidFile)
+			if err != nil {
+				log.Errorf("cannot obtain status of embedded adam: %s", err)
+			} else {
+				fmt.Printf("Embedded adam process status: %s\n", statusAdam)
+				fmt.Printf("\tAdam is expected at https://%s:%d\n", viper.GetString("adam.ip"), viper.GetInt("adam.port"))
+				fmt.Printf("\tLogs for embedded adam at: %s\n", adamLogFile)
+			}
+		} else if statusAdam, err := utils.StatusAdam(); err != nil {
 			log.Errorf("cannot obtain status of adam: %s", err)
 		} else {
 			fmt.Printf("Adam status: %s\n", statusAdam)
 			fmt.Printf("\tAdam is expected at https://%s:%d\n", viper.GetString("adam.ip"), viper.GetInt("adam.port"))
 			fmt.Printf("\tFor local Adam you can run 'docker logs %s' to see logs\n", defaults.DefaultAdamContainerName)
 		}
-		statusRedis, err := utils.StatusRedis()
-		if err != nil {
-			log.Errorf("cannot obtain status of redis: %s", err)
-		} else {
-			fmt.Printf("Redis status: %s\n", statusRedis)
-			fmt.Printf("\tRedis is expected at %s\n", viper.GetString("adam.redis.eden"))
-			fmt.Printf("\tFor local Redis you can run 'docker logs %s' to see logs\n", defaults.DefaultRedisContainerName)
+		if !adamEmbedded {
+			statusRedis, err := utils.StatusRedis()
+			if err != nil {
+				log.Errorf("cannot obtain status of redis: %s", err)
+			} else {
+				fmt.Printf("Redis status: %s\n", statusRedis)
+				fmt.Printf("\tRedis is expected at %s\n", viper.GetString("adam.redis.eden"))
+				fmt.Printf("\tFor local Redis you can run 'docker logs %s' to see logs\n", defaults.DefaultRedisContainerName)
+			}
 		}
 		statusEServer, err := utils.StatusEServer(eserverPidFile)
 		if err != nil {
@@ -86,4 +99,6 @@ func statusInit() {
 	}
 	statusCmd.Flags().StringVarP(&eserverPidFile, "eserver-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "eserver.pid"), "file with eserver pid")
 	statusCmd.Flags().StringVarP(&evePidFile, "eve-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "eve.pid"), "file with EVE pid")
+	statusCmd.Flags().BoolVarP(&adamEmbedded, "adam-embedded", "", false, "status of embedded adam")
+	statusCmd.Flags().StringVarP(&adamPidFile, "adam-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "adam.pid"), "file with embedded adam pid")
 }
@@ -28,19 +28,29 @@ var stopCmd = &cobra.Command{
 		if viperLoaded {
 			eserverPidFile = utils.ResolveAbsPath(viper.GetString("eden.eserver.pid"))
 			evePidFile = utils.ResolveAbsPath(viper.GetString("eve.pid"))
+			adamEmbedded = viper.GetBool("adam.embedded")
+			adamPidFile = utils.ResolveAbsPath(viper.GetString("adam.pid"))
 		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := utils.StopAdam(adamRm); err != nil {
+		if adamEmbedded {
+			if err := utils.StopAdamEmbedded(adamPidFile); err != nil {
+				log.Infof("cannot stop embedded adam: %s", err)
+			} else {
+				log.Infof("embedded adam stopped")
+			}
+		} else if err := utils.StopAdam(adamRm); err != nil {
 			log.Infof("cannot stop adam: %s", err)
 		} else {
 			log.Infof("adam stopped")
 		}
-		if err := utils.StopRedis(redisRm); err != nil {
-			log.Infof("cannot stop redis: %s", err)
-		} else {
-			log.Infof("redis stopped")
+		if !adamEmbedded {
+			if err := utils.StopRedis(redisRm); err != nil {
+				log.Infof("cannot stop redis: %s", err)
+			} else {
+				log.Infof("redis stopped")
+			}
 		}
 		if err := utils.StopEServer(eserverPidFile); err != nil {
 			log.Infof("cannot stop eserver: %s", err)
@@ -61,6 +71,8 @@ func stopInit() {
 		log.Fatal(err)
 	}
 	stopCmd.Flags().BoolVarP(&adamRm, "adam-rm", "", false, "adam rm on stop")
+	stopCmd.Flags().BoolVarP(&adamEmbedded, "adam-embedded", "", false, "stop embedded adam")
+	stopCmd.Flags().StringVarP(&adamPidFile, "adam-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "adam.pid"), "file with embedded adam pid")
 	stopCmd.Flags().BoolVarP(&redisRm, "redis-rm", "", false, "redis rm on stop")
 	stopCmd.Flags().StringVarP(&eserverPidFile, "eserver-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "eserver.pid"), "file with eserver pid")
 	stopCmd.Flags().StringVarP(&evePidFile, "eve-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "eve.pid"), "file with EVE pid")
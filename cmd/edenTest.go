@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bufio"
+	"fmt"
 	"github.com/lf-edge/eden/pkg/defaults"
 	"os"
 	"os/exec"
@@ -39,6 +40,9 @@ func runTest(args []string) {
 	}
 	log.Info("Test: ", tstr)
 	tst := exec.Command(path, args...)
+	if deviceSelector != "" {
+		tst.Env = append(os.Environ(), fmt.Sprintf("%s=%s", defaults.DefaultDeviceEnv, deviceSelector))
+	}
 	tst.Stdout = os.Stdout
 	tst.Stderr = os.Stderr
 	err = tst.Run()
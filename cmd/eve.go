@@ -10,7 +10,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 )
 
 var (
@@ -24,6 +26,8 @@ var (
 	eveHost          string
 	eveSSHPort       int
 	eveTelnetPort    int
+	eveName          string
+	eveInstancesDir  string
 )
 
 var eveCmd = &cobra.Command{
@@ -49,10 +53,33 @@ var startEveCmd = &cobra.Command{
 			eveImageFile = utils.ResolveAbsPath(viper.GetString("eve.image-file"))
 			evePidFile = utils.ResolveAbsPath(viper.GetString("eve.pid"))
 			eveLogFile = utils.ResolveAbsPath(viper.GetString("eve.log"))
+			qemuFirmware = viper.GetStringSlice("eve.firmware")
+			qemuConfigPath = utils.ResolveAbsPath(viper.GetString("eve.config-part"))
+			qemuDTBPath = utils.ResolveAbsPath(viper.GetString("eve.dtb-part"))
+			qemuHostFwd = viper.GetStringMapString("eve.hostfwd")
+			certsDir = utils.ResolveAbsPath(viper.GetString("eden.certs-dist"))
+			eveInstancesDir = utils.ResolveAbsPath(viper.GetString("eve.instances"))
 		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if eveNamed() {
+			serial := eveName
+			if cmd.Flags().Changed("eve-serial") {
+				serial = qemuSMBIOSSerial
+			}
+			inst, err := prepareEveInstance(serial)
+			if err != nil {
+				log.Fatalf("cannot prepare EVE instance %s: %s", eveName, err)
+			}
+			qemuSMBIOSSerial = inst.Serial
+			qemuConfigFile = inst.QemuConfig()
+			eveImageFile = inst.ImageFile()
+			evePidFile = inst.PidFile()
+			eveLogFile = inst.LogFile()
+			eveTelnetPort = inst.TelnetPort
+			log.Infof("EVE instance %s: serial %s, ssh port %d, telnet port %d", inst.Name, inst.Serial, inst.SSHPort, inst.TelnetPort)
+		}
 		qemuCommand := ""
 		qemuOptions := fmt.Sprintf("-display none -serial telnet:localhost:%d,server,nowait -nodefaults -no-user-config ", eveTelnetPort)
 		if qemuSMBIOSSerial != "" {
@@ -119,10 +146,14 @@ var stopEveCmd = &cobra.Command{
 		}
 		if viperLoaded {
 			evePidFile = utils.ResolveAbsPath(viper.GetString("eve.pid"))
+			eveInstancesDir = utils.ResolveAbsPath(viper.GetString("eve.instances"))
 		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if inst := eveInstance(); inst != nil {
+			evePidFile = inst.PidFile()
+		}
 		if err := utils.StopEVEQemu(evePidFile); err != nil {
 			log.Errorf("cannot stop EVE: %s", err)
 		}
@@ -141,10 +172,14 @@ var statusEveCmd = &cobra.Command{
 		}
 		if viperLoaded {
 			evePidFile = utils.ResolveAbsPath(viper.GetString("eve.pid"))
+			eveInstancesDir = utils.ResolveAbsPath(viper.GetString("eve.instances"))
 		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if inst := eveInstance(); inst != nil {
+			evePidFile = inst.PidFile()
+		}
 		statusEVE, err := utils.StatusEVEQemu(evePidFile)
 		if err != nil {
 			log.Errorf("cannot obtain status of EVE: %s", err)
@@ -160,13 +195,19 @@ var consoleEveCmd = &cobra.Command{
 	Long:  `Telnet into eve.`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		assingCobraToViper(cmd)
-		_, err := utils.LoadConfigFile(configFile)
+		viperLoaded, err := utils.LoadConfigFile(configFile)
 		if err != nil {
 			return fmt.Errorf("error reading config: %s", err.Error())
 		}
+		if viperLoaded {
+			eveInstancesDir = utils.ResolveAbsPath(viper.GetString("eve.instances"))
+		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if inst := eveInstance(); inst != nil {
+			eveTelnetPort = inst.TelnetPort
+		}
 		log.Infof("Try to telnet %s:%d", eveHost, eveTelnetPort)
 		if err := utils.RunCommandForeground("telnet", strings.Fields(fmt.Sprintf("%s %d", eveHost, eveTelnetPort))...); err != nil {
 			log.Fatalf("telnet error: %s", err)
@@ -188,10 +229,14 @@ var sshEveCmd = &cobra.Command{
 			eveSSHKey = utils.ResolveAbsPath(viper.GetString("eden.ssh-key"))
 			extension := filepath.Ext(eveSSHKey)
 			eveSSHKey = strings.TrimRight(eveSSHKey, extension)
+			eveInstancesDir = utils.ResolveAbsPath(viper.GetString("eve.instances"))
 		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if inst := eveInstance(); inst != nil {
+			eveSSHPort = inst.SSHPort
+		}
 		if _, err := os.Stat(eveSSHKey); !os.IsNotExist(err) {
 			log.Infof("Try to SHH %s:%d with key %s", eveHost, eveSSHPort, eveSSHKey)
 			if err := utils.RunCommandForeground("ssh", strings.Fields(fmt.Sprintf("-o ConnectTimeout=3 -oStrictHostKeyChecking=no -i %s -p %d root@%s", eveSSHKey, eveSSHPort, eveHost))...); err != nil {
@@ -209,13 +254,25 @@ var onboardEveCmd = &cobra.Command{
 	Long:  `Adding an EVE onboarding certificate to Adam and waiting for EVE to register.`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		assingCobraToViper(cmd)
-		_, err := utils.LoadConfigFile(configFile)
+		viperLoaded, err := utils.LoadConfigFile(configFile)
 		if err != nil {
 			return fmt.Errorf("error reading config: %s", err.Error())
 		}
+		if viperLoaded {
+			eveInstancesDir = utils.ResolveAbsPath(viper.GetString("eve.instances"))
+		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if inst := eveInstance(); inst != nil {
+			//onboard instance with its own cert and serial
+			viper.Set("eve.cert", inst.CertFile())
+			viper.Set("eve.serial", inst.Serial)
+			viper.Set("eve.uuid", inst.UUID)
+			if deviceSelector == "" {
+				deviceSelector = inst.Serial
+			}
+		}
 		eveUUID := viper.GetString("eve.uuid")
 		edenDir, err := utils.DefaultEdenDir()
 		if err != nil {
@@ -237,6 +294,113 @@ var onboardEveCmd = &cobra.Command{
 	},
 }
 
+var lsEveCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "list EVE instances",
+	Long:  `List EVE from config and named EVE instances with their serials, ports and status.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		viperLoaded, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading config: %s", err.Error())
+		}
+		if viperLoaded {
+			qemuSMBIOSSerial = viper.GetString("eve.serial")
+			qemuHostFwd = viper.GetStringMapString("eve.hostfwd")
+			evePidFile = utils.ResolveAbsPath(viper.GetString("eve.pid"))
+			eveInstancesDir = utils.ResolveAbsPath(viper.GetString("eve.instances"))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		instances, err := utils.EVEInstanceList(eveInstancesDir)
+		if err != nil {
+			log.Fatalf("cannot list EVE instances: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 1, 1, 2, ' ', 0)
+		if _, err = fmt.Fprintln(w, "NAME\tSERIAL\tUUID\tSSH\tTELNET\tSTATUS"); err != nil {
+			log.Fatal(err)
+		}
+		sshPort := defaults.DefaultSSHPort
+		for ext, internal := range qemuHostFwd {
+			if port, err := strconv.Atoi(ext); err == nil && internal == "22" {
+				sshPort = port
+			}
+		}
+		printEve := func(name, serial, uuid string, ssh, telnet int, pidFile string) {
+			status, err := utils.StatusEVEQemu(pidFile)
+			if err != nil {
+				status = fmt.Sprintf("error: %s", err)
+			}
+			if _, err = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", name, serial, uuid, ssh, telnet, strings.TrimSpace(status)); err != nil {
+				log.Fatal(err)
+			}
+		}
+		printEve(defaults.DefaultEVEName, qemuSMBIOSSerial, viper.GetString("eve.uuid"), sshPort, eveTelnetPort, evePidFile)
+		for _, inst := range instances {
+			printEve(inst.Name, inst.Serial, inst.UUID, inst.SSHPort, inst.TelnetPort, inst.PidFile())
+		}
+		if err = w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+//eveNamed checks if command should work with named EVE instance instead of EVE from config
+func eveNamed() bool {
+	return eveName != "" && eveName != defaults.DefaultEVEName
+}
+
+//eveInstance return EVE instance selected with --name or nil for EVE from config
+func eveInstance() *utils.EVEInstance {
+	if !eveNamed() {
+		return nil
+	}
+	inst, err := utils.EVEInstanceLoad(eveInstancesDir, eveName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return inst
+}
+
+//prepareEveInstance loads named EVE instance or creates it with own copy of image and qemu config on first start
+func prepareEveInstance(serial string) (*utils.EVEInstance, error) {
+	inst, err := utils.EVEInstanceLoad(eveInstancesDir, eveName)
+	if err == nil {
+		return inst, nil
+	}
+	if _, statErr := os.Stat(filepath.Join(eveInstancesDir, eveName)); !os.IsNotExist(statErr) {
+		return nil, err
+	}
+	if len(qemuHostFwd) == 0 {
+		qemuHostFwd = defaults.DefaultQemuHostFwd
+	}
+	log.Infof("Creating EVE instance %s in %s", eveName, eveInstancesDir)
+	inst, err = utils.EVEInstanceCreate(eveInstancesDir, eveName, serial, eveImageFile, qemuConfigPath, certsDir, eveTelnetPort, qemuHostFwd)
+	if err != nil {
+		return nil, err
+	}
+	var firmware []string
+	for _, el := range qemuFirmware {
+		firmware = append(firmware, utils.ResolveAbsPath(el))
+	}
+	nets, err := utils.GetSubnetsNotUsed(2)
+	if err != nil {
+		return nil, err
+	}
+	settings := utils.QemuSettings{
+		DTBDrive: qemuDTBPath,
+		Firmware: firmware,
+		MemoryMB: defaults.DefaultQemuMemory,
+		CPUs:     defaults.DefaultQemuCpus,
+		NetDevs:  nets,
+	}
+	if err = inst.WriteQemuConfig(settings); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
 func eveInit() {
 	eveCmd.AddCommand(confChangerCmd)
 	confChangerInit()
@@ -248,6 +412,7 @@ func eveInit() {
 	eveCmd.AddCommand(sshEveCmd)
 	eveCmd.AddCommand(consoleEveCmd)
 	eveCmd.AddCommand(onboardEveCmd)
+	eveCmd.AddCommand(lsEveCmd)
 	currentPath, err := os.Getwd()
 	if err != nil {
 		log.Fatal(err)
@@ -270,4 +435,10 @@ func eveInit() {
 	consoleEveCmd.Flags().StringVarP(&eveHost, "eve-host", "", defaults.DefaultEVEHost, "IP of eve")
 	consoleEveCmd.Flags().IntVarP(&eveTelnetPort, "eve-telnet-port", "", defaults.DefaultTelnetPort, "Port for telnet access")
 	eveCmd.PersistentFlags().StringVar(&configFile, "config", "", "path to config file")
+	for _, cmd := range []*cobra.Command{startEveCmd, stopEveCmd, statusEveCmd, sshEveCmd, consoleEveCmd, onboardEveCmd} {
+		cmd.Flags().StringVarP(&eveName, "name", "", "", "name of EVE instance to run side by side with EVE from config")
+	}
+	for _, cmd := range []*cobra.Command{startEveCmd, stopEveCmd, statusEveCmd, sshEveCmd, consoleEveCmd, onboardEveCmd, lsEveCmd} {
+		cmd.Flags().StringVarP(&eveInstancesDir, "eve-instances", "", filepath.Join(currentPath, defaults.DefaultDist, defaults.DefaultEVEInstancesDist), "directory for named EVE instances")
+	}
 }
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/eflowlog"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var flowLogType string
+
+var flowLogCmd = &cobra.Command{
+	Use:   "flowlog [field:regexp ...]",
+	Short: "Get flow logs of network instances from a running EVE device",
+	Long: `
+Scans the ADAM flow logs for correspondence with regular expressions requests to json fields.
+Nested fields are addressed with dots and fields of scope are checked for the whole message,
+e.g. 'eden flowlog --type flow scope.netInstUUID:<uuid> flow.destPort:^80$ aclName:.*'.`,
+	PreRunE: metricPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctrl, err := controller.CloudPrepare()
+		if err != nil {
+			log.Fatalf("CloudPrepare: %s", err)
+		}
+		if err := ctrl.OnBoard(); err != nil {
+			log.Fatalf("OnBoard: %s", err)
+		}
+		dev, err := ctrl.GetDevice(deviceSelector)
+		if err != nil {
+			log.Fatalf("GetDevice: %s", err)
+		}
+		follow, err := cmd.Flags().GetBool("follow")
+		if err != nil {
+			log.Fatalf("Error in get param 'follow': %s", err)
+		}
+		fType, err := eflowlog.GetFlowLogType(flowLogType)
+		if err != nil {
+			log.Fatalf("Error in get param 'type': %s", err)
+		}
+		q, err := parseFieldQuery(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if follow {
+			if err = ctrl.FlowLogChecker(dev.GetID(), q, fType, eflowlog.HandleAll, eflowlog.FlowLogNew, 0); err != nil {
+				log.Fatalf("FlowLogChecker: %s", err)
+			}
+		} else {
+			if err = ctrl.FlowLogLastCallback(dev.GetID(), q, fType, eflowlog.HandleAll); err != nil {
+				log.Fatalf("FlowLogLastCallback: %s", err)
+			}
+		}
+	},
+}
+
+func flowLogInit() {
+	flowLogCmd.Flags().BoolP("follow", "f", false, "Monitor new flow logs")
+	flowLogCmd.Flags().StringVarP(&flowLogType, "type", "", "flow", fmt.Sprintf("flow log type (%s)", strings.Join(eflowlog.ListFlowLogType(), ",")))
+}
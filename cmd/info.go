@@ -3,38 +3,80 @@ package cmd
 import (
 	"fmt"
 	"github.com/lf-edge/eden/pkg/controller"
-	"github.com/lf-edge/eden/pkg/utils"
-	uuid "github.com/satori/go.uuid"
+	"github.com/lf-edge/eden/pkg/controller/eformat"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eve/api/go/info"
 	log "github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 	"strings"
+	"time"
 
 	"github.com/lf-edge/eden/pkg/controller/einfo"
 	"github.com/spf13/cobra"
 )
 
-var infoType string
+var (
+	infoType   string
+	infoSelect string
+)
+
+//eveInfoType return info type selected with --select or --type
+func eveInfoType() einfo.ZInfoType {
+	if infoSelect != "" {
+		zInfoType, err := einfo.ZInfoSelect(infoSelect)
+		if err != nil {
+			log.Fatalf("Error in get param 'select': %s", err)
+		}
+		return zInfoType
+	}
+	zInfoType, err := einfo.GetZInfoType(infoType)
+	if err != nil {
+		log.Fatalf("Error in get param 'type': %s", err)
+	}
+	return zInfoType
+}
 
 var infoCmd = &cobra.Command{
 	Use:   "info [field:regexp ...]",
 	Short: "Get information reports from a running EVE device",
 	Long: `
-Scans the ADAM Info for correspondence with regular expressions requests to json fields.`,
-	PreRunE: func(cmd *cobra.Command, args []string) error {
-		assingCobraToViper(cmd)
-		viperLoaded, err := utils.LoadConfigFile(configFile)
+Scans the ADAM Info for correspondence with regular expressions requests to json fields
+and with expression (--query), e.g. 'ainfo.state = RUNNING and atTimeStamp > "2020-05-13 10:00:00"'.
+Fields are relative to the selected type, e.g. 'devName = eth0' for dinfo-network.
+Objects inside info may be selected by path (--select) instead of type, e.g. 'dinfo.storage[*]' or 'ainfo.network[*].ipAddrs'.
+Info may be limited by time (--since and --until) and by number of the last ones (--tail),
+with --follow they are printed before new ones.
+Info may be loaded from support bundle or Adam's device directory of previous run (--from),
+with --follow it is replayed from oldest to newest with original timing (--replay).
+Info is printed in text, json, jsonl, table or go template format (--format), e.g. 'template={{.devId}} {{.items}}'.`,
+	PreRunE: logPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		follow, err := cmd.Flags().GetBool("follow")
 		if err != nil {
-			return fmt.Errorf("error reading config: %s", err.Error())
+			fmt.Printf("Error in get param 'follow'")
+			return
 		}
-		if viperLoaded {
-			certsIP = viper.GetString("adam.ip")
-			adamPort = viper.GetInt("adam.port")
-			adamDist = utils.ResolveAbsPath(viper.GetString("adam.dist"))
-			adamCA = utils.ResolveAbsPath(viper.GetString("adam.ca"))
+		zInfoType := eveInfoType()
+		q, err := eveQuery(args)
+		if err != nil {
+			log.Fatal(err)
 		}
-		return nil
-	},
-	Run: func(cmd *cobra.Command, args []string) {
+		format := eveOutputFormat(follow)
+		if loader := eveBundleLoader(); loader != nil {
+			eveRange(q, false)
+			if follow {
+				err = bundleInfoWatch(loader, q, zInfoType, einfo.HandleAllFormat(format), 0)
+			} else {
+				err = einfo.InfoLast(loader, q, einfo.ZInfoFind, einfo.HandleAllFormat(format), zInfoType)
+			}
+			if err != nil {
+				log.Fatalf("load info from %s: %s", bundleFrom, err)
+			}
+			if err = format.Flush(); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		inRange := eveRange(q, follow)
 		ctrl, err := controller.CloudPrepare()
 		if err != nil {
 			log.Fatalf("CloudPrepare: %s", err)
@@ -42,46 +84,99 @@ Scans the ADAM Info for correspondence with regular expressions requests to json
 		if err := ctrl.OnBoard(); err != nil {
 			log.Fatalf("OnBoard: %s", err)
 		}
-		devices, err := ctrl.DeviceList()
+		dev, err := ctrl.GetDevice(deviceSelector)
 		if err != nil {
-			log.Fatalf("DeviceList: %s", err)
+			log.Fatalf("GetDevice: %s", err)
 		}
-		for _, devID := range devices {
-			devUUID, err := uuid.FromString(devID)
-			if err != nil {
-				log.Fatalf("uuidGet: %s", err)
-			}
-			follow, err := cmd.Flags().GetBool("follow")
-			if err != nil {
-				fmt.Printf("Error in get param 'follow'")
-				return
+		devUUID := dev.GetID()
+
+		if follow {
+			if inRange {
+				if err = ctrl.InfoLastCallback(devUUID, q, zInfoType, einfo.HandleAllFormat(format)); err != nil {
+					log.Fatalf("InfoLastCallback: %s", err)
+				}
 			}
-			zInfoType, err := einfo.GetZInfoType(infoType)
-			if err != nil {
-				fmt.Printf("Error in get param 'type': %s", err)
-				return
+			if err = ctrl.InfoChecker(devUUID, q, zInfoType, einfo.HandleAllFormat(format), einfo.InfoNew, 0); err != nil {
+				log.Fatalf("InfoChecker: %s", err)
 			}
-			q := make(map[string]string)
-			for _, a := range args[0:] {
-				s := strings.Split(a, ":")
-				q[s[0]] = s[1]
+		} else {
+			if err = ctrl.InfoLastCallback(devUUID, q, zInfoType, einfo.HandleAllFormat(format)); err != nil {
+				log.Fatalf("InfoChecker: %s", err)
 			}
+		}
+		if err = format.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
 
-			if follow {
-				if err = ctrl.InfoChecker(devUUID, q, zInfoType, einfo.HandleAll, einfo.InfoNew, 0); err != nil {
-					log.Fatalf("InfoChecker: %s", err)
-				}
-			} else {
-				if err = ctrl.InfoLastCallback(devUUID, q, zInfoType, einfo.HandleAll); err != nil {
-					log.Fatalf("InfoChecker: %s", err)
-				}
+var infoWatchCmd = &cobra.Command{
+	Use:   "infowatch [field:regexp ...]",
+	Short: "Watch for new information reports from a running EVE device",
+	Long: `
+Waits for new info which correspond to regular expressions requests to json fields and to expression (--query)
+and prints them in selected format (--format). Exits with error if no info found before timeout.
+Watcher with name (--name) resumes from the last processed info of the previous run with this name,
+watchers with the same name share info between them (requires redis of adam).
+Info of support bundle or Adam's device directory of previous run (--from) is replayed with original timing (--replay).`,
+	PreRunE: logPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		zInfoType := eveInfoType()
+		q, err := eveQuery(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		eveWatchName(q)
+		format := eveOutputFormat(true)
+		found := 0
+		handler := einfo.HandleAllFormat(format)
+		foundHandler := func(im *info.ZInfoMsg, ds []*einfo.ZInfoMsgInterface, infoType einfo.ZInfoType) bool {
+			found++
+			return handler(im, ds, infoType)
+		}
+		if loader := eveBundleLoader(); loader != nil {
+			if err = bundleInfoWatch(loader, q, zInfoType, foundHandler, timeoutSeconds(watchTimeout)); found == 0 {
+				log.Fatalf("no info found in %s: %v", bundleFrom, err)
 			}
-			break
+			return
+		}
+		ctrl, err := controller.CloudPrepare()
+		if err != nil {
+			log.Fatalf("CloudPrepare: %s", err)
+		}
+		if err := ctrl.OnBoard(); err != nil {
+			log.Fatalf("OnBoard: %s", err)
+		}
+		dev, err := ctrl.GetDevice(deviceSelector)
+		if err != nil {
+			log.Fatalf("GetDevice: %s", err)
+		}
+		if err = ctrl.InfoChecker(dev.GetID(), q, zInfoType, foundHandler, einfo.InfoNew, timeoutSeconds(watchTimeout)); err != nil && found == 0 {
+			log.Fatalf("InfoChecker: %s", err)
 		}
 	},
 }
 
+//bundleInfoWatch replay info of bundle in range from query with handler
+func bundleInfoWatch(loader loaders.Loader, q map[string]string, infoType einfo.ZInfoType, handler einfo.HandlerFunc, timeoutSeconds time.Duration) error {
+	_, r, err := loaders.RangeFromQuery(q)
+	if err != nil {
+		return err
+	}
+	loader.SetRange(r)
+	return einfo.InfoWatch(loader, q, einfo.ZInfoFind, handler, infoType, timeoutSeconds)
+}
+
 func infoInit() {
 	infoCmd.Flags().BoolP("follow", "f", false, "Monitor changes in selected directory")
-	infoCmd.PersistentFlags().StringVarP(&infoType, "type", "", "all", fmt.Sprintf("info type (%s)", strings.Join(einfo.ListZInfoType(), ",")))
+	for _, el := range []*cobra.Command{infoCmd, infoWatchCmd} {
+		el.Flags().StringVarP(&queryExpr, "query", "q", "", "expression to filter info (and, or, not, = != < <= > >= ~ !~ in)")
+		el.Flags().StringVar(&outputFormat, "format", "text", fmt.Sprintf("output format (%s)", strings.Join(eformat.List(), ", ")))
+		el.PersistentFlags().StringVarP(&infoType, "type", "", "all", fmt.Sprintf("info type (%s)", strings.Join(einfo.ListZInfoType(), ",")))
+		bundleFlags(el)
+		el.Flags().StringVar(&infoSelect, "select", "", "path to objects inside info instead of type ([*] for all and [N] for element N of list), e.g. dinfo.network[*]")
+	}
+	rangeFlags(infoCmd, "info")
+	infoWatchCmd.Flags().DurationVar(&watchTimeout, "timeout", 0, "time to wait for info (0 for infinite)")
+	infoWatchCmd.Flags().StringVar(&watchName, "name", "", "name of watcher (group or group:consumer) to resume from the last processed info and share it between watchers (redis only)")
 }
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/device"
+	"github.com/lf-edge/eve/api/go/info"
+)
+
+//latestInfo return the newest info messages of infoType from dev grouped by key,
+//messages with empty key are skipped
+func latestInfo(ctrl controller.Cloud, dev *device.Ctx, infoType einfo.ZInfoType, key func(im *info.ZInfoMsg) string) (map[string]*info.ZInfoMsg, error) {
+	result := make(map[string]*info.ZInfoMsg)
+	handleInfo := func(im *info.ZInfoMsg, ds []*einfo.ZInfoMsgInterface, infoType einfo.ZInfoType) bool {
+		k := key(im)
+		if k == "" {
+			return false
+		}
+		if last, ok := result[k]; ok {
+			lastTime, curTime := last.GetAtTimeStamp(), im.GetAtTimeStamp()
+			if lastTime.GetSeconds() > curTime.GetSeconds() ||
+				(lastTime.GetSeconds() == curTime.GetSeconds() && lastTime.GetNanos() >= curTime.GetNanos()) {
+				return false
+			}
+		}
+		result[k] = im
+		return false
+	}
+	return result, ctrl.InfoLastCallback(dev.GetID(), map[string]string{}, infoType, handleInfo)
+}
@@ -3,36 +3,180 @@ package cmd
 import (
 	"fmt"
 	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/eformat"
+	"github.com/lf-edge/eden/pkg/controller/equery"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
 	"github.com/lf-edge/eden/pkg/utils"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"strings"
+	"time"
 
 	"github.com/lf-edge/eden/pkg/controller/elog"
 	"github.com/spf13/cobra"
 )
 
+var (
+	//queryExpr is expression for log and info commands
+	queryExpr string
+	//outputFormat is format of output for log and info commands
+	outputFormat string
+	//watchTimeout is timeout for logwatch and infowatch commands
+	watchTimeout time.Duration
+	//watchName is name of watcher for logwatch and infowatch commands to resume from the last processed object
+	watchName string
+	//rangeSince, rangeUntil and rangeTail limit logs and info of log and info commands
+	rangeSince string
+	rangeUntil string
+	rangeTail  int
+	//bundleFrom is support bundle or Adam's device directory to load logs and info from instead of controller
+	bundleFrom string
+	//bundleReplay is speed of replay of bundle with original timing for --follow, logwatch and infowatch
+	bundleReplay float64
+)
+
+//logPreRun loads adam settings from config for log and info commands
+func logPreRun(cmd *cobra.Command, args []string) error {
+	assingCobraToViper(cmd)
+	viperLoaded, err := utils.LoadConfigFile(configFile)
+	if err != nil {
+		return fmt.Errorf("error reading config: %s", err.Error())
+	}
+	if viperLoaded {
+		certsIP = viper.GetString("adam.ip")
+		adamPort = viper.GetInt("adam.port")
+		adamDist = utils.ResolveAbsPath(viper.GetString("adam.dist"))
+		adamCA = utils.ResolveAbsPath(viper.GetString("adam.ca"))
+	}
+	return nil
+}
+
+//eveQuery return query from field:regexp arguments and expression from --query
+func eveQuery(args []string) (map[string]string, error) {
+	q, err := parseFieldQuery(args)
+	if err != nil {
+		return nil, err
+	}
+	if queryExpr != "" {
+		if _, err = equery.Parse(queryExpr); err != nil {
+			return nil, err
+		}
+		q[equery.ExprKey] = queryExpr
+	}
+	return q, nil
+}
+
+//eveRange put range from --since, --until and --tail into query and return true if range is set
+func eveRange(q map[string]string, follow bool) bool {
+	var r loaders.Range
+	var err error
+	now := time.Now()
+	if rangeSince != "" {
+		if r.Since, err = loaders.ParseTime(rangeSince, now); err != nil {
+			log.Fatalf("Error in get param 'since': %s", err)
+		}
+	}
+	if rangeUntil != "" {
+		if follow {
+			log.Fatal("--until cannot be used with --follow")
+		}
+		if r.Until, err = loaders.ParseTime(rangeUntil, now); err != nil {
+			log.Fatalf("Error in get param 'until': %s", err)
+		}
+	}
+	if rangeTail < 0 {
+		log.Fatalf("wrong --tail %d", rangeTail)
+	}
+	r.Tail = rangeTail
+	r.ToQuery(q)
+	return !r.IsEmpty()
+}
+
+//eveWatchName put name of watcher from --name into query
+func eveWatchName(q map[string]string) {
+	if watchName == "" {
+		return
+	}
+	if _, _, err := loaders.ParseConsumer(watchName); err != nil {
+		log.Fatalf("Error in get param 'name': %s", err)
+	}
+	q[loaders.ConsumerKey] = watchName
+}
+
+//eveBundleLoader return loader from --from bundle for device with uuid from --device (all devices if empty)
+//or nil if --from is not set
+func eveBundleLoader() loaders.Loader {
+	if bundleFrom == "" {
+		return nil
+	}
+	//bundle keeps objects by uuid of device only, so serial or name cannot be resolved without controller
+	var devUUID uuid.UUID
+	if deviceSelector != "" {
+		var err error
+		if devUUID, err = uuid.FromString(deviceSelector); err != nil {
+			log.Fatalf("--device must be uuid of device with --from: %s", deviceSelector)
+		}
+	}
+	loader, err := loaders.BundleLoader(bundleFrom)
+	if err != nil {
+		log.Fatal(err)
+	}
+	loader.SetReplay(bundleReplay)
+	if devUUID != uuid.Nil {
+		loader.SetUUID(devUUID)
+	}
+	return loader
+}
+
+//eveOutputFormat return format from --format, table is flushed after every row if stream
+func eveOutputFormat(stream bool) *eformat.Format {
+	format, err := eformat.Parse(outputFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	format.SetStream(stream)
+	return format
+}
+
 var logCmd = &cobra.Command{
 	Use:   "log [field:regexp ...]",
 	Short: "Get logs from a running EVE device",
 	Long: `
-Scans the ADAM logs for correspondence with regular expressions requests to json fields.`,
-	PreRunE: func(cmd *cobra.Command, args []string) error {
-		assingCobraToViper(cmd)
-		viperLoaded, err := utils.LoadConfigFile(configFile)
+Scans the ADAM logs for correspondence with regular expressions requests to json fields
+and with expression (--query), e.g. 'level in (error, fatal) and (source = zedagent or source = zedmanager) and not msg ~ lisp'.
+Logs are printed in text, json, jsonl, table or go template format (--format), e.g. 'template={{.Time}} {{.Msg}}'.
+Logs may be limited by time (--since and --until) and by number of the last ones (--tail),
+with --follow they are printed before new ones.
+Logs may be loaded from support bundle or Adam's device directory of previous run (--from),
+with --follow they are replayed from oldest to newest with original timing (--replay).`,
+	PreRunE: logPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		follow, err := cmd.Flags().GetBool("follow")
 		if err != nil {
-			return fmt.Errorf("error reading config: %s", err.Error())
+			log.Fatalf("Error in get param 'follow'")
 		}
-		if viperLoaded {
-			certsIP = viper.GetString("adam.ip")
-			adamPort = viper.GetInt("adam.port")
-			adamDist = utils.ResolveAbsPath(viper.GetString("adam.dist"))
-			adamCA = utils.ResolveAbsPath(viper.GetString("adam.ca"))
+		q, err := eveQuery(args)
+		if err != nil {
+			log.Fatal(err)
 		}
-		return nil
-	},
-	Run: func(cmd *cobra.Command, args []string) {
+		format := eveOutputFormat(follow)
+		if loader := eveBundleLoader(); loader != nil {
+			eveRange(q, false)
+			if follow {
+				err = bundleLogWatch(loader, q, elog.HandleAllFormat(format), 0)
+			} else {
+				err = elog.LogLast(loader, q, elog.HandleAllFormat(format))
+			}
+			if err != nil {
+				log.Fatalf("load logs from %s: %s", bundleFrom, err)
+			}
+			if err = format.Flush(); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		inRange := eveRange(q, follow)
 		ctrl, err := controller.CloudPrepare()
 		if err != nil {
 			log.Fatalf("CloudPrepare: %s", err)
@@ -40,41 +184,110 @@ Scans the ADAM logs for correspondence with regular expressions requests to json
 		if err := ctrl.OnBoard(); err != nil {
 			log.Fatalf("OnBoard: %s", err)
 		}
-		devices, err := ctrl.DeviceList()
+		dev, err := ctrl.GetDevice(deviceSelector)
 		if err != nil {
-			log.Fatalf("DeviceList: %s", err)
+			log.Fatalf("GetDevice: %s", err)
 		}
-		for _, devID := range devices {
-			devUUID, err := uuid.FromString(devID)
-			if err != nil {
-				log.Fatalf("uuidGet: %s", err)
+		devUUID := dev.GetID()
+
+		if follow {
+			if inRange {
+				if err = ctrl.LogLastCallback(devUUID, q, elog.HandleAllFormat(format)); err != nil {
+					log.Fatalf("LogLastCallback: %s", err)
+				}
 			}
-			follow, err := cmd.Flags().GetBool("follow")
-			if err != nil {
-				log.Fatalf("Error in get param 'follow'")
+			// Monitoring of new files
+			if err = ctrl.LogChecker(devUUID, q, elog.HandleAllFormat(format), elog.LogNew, 0); err != nil {
+				log.Fatalf("LogChecker: %s", err)
 			}
-
-			q := make(map[string]string)
-
-			for _, a := range args[0:] {
-				s := strings.Split(a, ":")
-				q[s[0]] = s[1]
+		} else {
+			if err = ctrl.LogLastCallback(devUUID, q, elog.HandleAllFormat(format)); err != nil {
+				log.Fatalf("LogChecker: %s", err)
 			}
+		}
+		if err = format.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
 
-			if follow {
-				// Monitoring of new files
-				if err = ctrl.LogChecker(devUUID, q, elog.HandleAll, elog.LogNew, 0); err != nil {
-					log.Fatalf("LogChecker: %s", err)
-				}
-			} else {
-				if err = ctrl.LogLastCallback(devUUID, q, elog.HandleAll); err != nil {
-					log.Fatalf("LogChecker: %s", err)
-				}
+var logWatchCmd = &cobra.Command{
+	Use:   "logwatch [field:regexp ...]",
+	Short: "Watch for new logs from a running EVE device",
+	Long: `
+Waits for new logs which correspond to regular expressions requests to json fields and to expression (--query)
+and prints them in selected format (--format). Exits with error if no logs found before timeout.
+Watcher with name (--name) resumes from the last processed logs of the previous run with this name,
+watchers with the same name share logs between them (requires redis of adam).
+Logs of support bundle or Adam's device directory of previous run (--from) are replayed with original timing (--replay).`,
+	PreRunE: logPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		q, err := eveQuery(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		eveWatchName(q)
+		format := eveOutputFormat(true)
+		found := 0
+		handler := elog.HandleAllFormat(format)
+		foundHandler := func(le *elog.LogItem) bool {
+			found++
+			return handler(le)
+		}
+		if loader := eveBundleLoader(); loader != nil {
+			if err = bundleLogWatch(loader, q, foundHandler, timeoutSeconds(watchTimeout)); found == 0 {
+				log.Fatalf("no logs found in %s: %v", bundleFrom, err)
 			}
+			return
+		}
+		ctrl, err := controller.CloudPrepare()
+		if err != nil {
+			log.Fatalf("CloudPrepare: %s", err)
+		}
+		if err := ctrl.OnBoard(); err != nil {
+			log.Fatalf("OnBoard: %s", err)
+		}
+		dev, err := ctrl.GetDevice(deviceSelector)
+		if err != nil {
+			log.Fatalf("GetDevice: %s", err)
+		}
+		if err = ctrl.LogChecker(dev.GetID(), q, foundHandler, elog.LogNew, timeoutSeconds(watchTimeout)); err != nil && found == 0 {
+			log.Fatalf("LogChecker: %s", err)
 		}
 	},
 }
 
+//bundleLogWatch replay logs of bundle in range from query with handler
+func bundleLogWatch(loader loaders.Loader, q map[string]string, handler elog.HandlerFunc, timeoutSeconds time.Duration) error {
+	_, r, err := loaders.RangeFromQuery(q)
+	if err != nil {
+		return err
+	}
+	loader.SetRange(r)
+	return elog.LogWatch(loader, q, handler, timeoutSeconds)
+}
+
+//bundleFlags add --from and --replay flags to cmd
+func bundleFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&bundleFrom, "from", "", "load from support bundle or Adam's device directory (directory, tar or tar.gz) instead of controller")
+	cmd.Flags().Float64Var(&bundleReplay, "replay", 0, "speed of replay of --from bundle with original timing for new ones (e.g. 1 or 10 for ten times faster, 0 without delays)")
+}
+
+//rangeFlags add --since, --until and --tail flags to cmd
+func rangeFlags(cmd *cobra.Command, what string) {
+	cmd.Flags().StringVar(&rangeSince, "since", "", fmt.Sprintf("show %s since time (RFC3339 or \"2006-01-02 15:04:05\") or duration ago (e.g. 5m)", what))
+	cmd.Flags().StringVar(&rangeUntil, "until", "", fmt.Sprintf("show %s until time (RFC3339 or \"2006-01-02 15:04:05\") or duration ago (e.g. 5m)", what))
+	cmd.Flags().IntVar(&rangeTail, "tail", 0, fmt.Sprintf("show only the last N %s (0 for all)", what))
+}
+
 func logInit() {
 	logCmd.Flags().BoolP("follow", "f", false, "Monitor changes in selected directory")
+	for _, el := range []*cobra.Command{logCmd, logWatchCmd} {
+		el.Flags().StringVarP(&queryExpr, "query", "q", "", "expression to filter logs (and, or, not, = != < <= > >= ~ !~ in)")
+		el.Flags().StringVar(&outputFormat, "format", "text", fmt.Sprintf("output format (%s)", strings.Join(eformat.List(), ", ")))
+		bundleFlags(el)
+	}
+	rangeFlags(logCmd, "logs")
+	logWatchCmd.Flags().DurationVar(&watchTimeout, "timeout", 0, "time to wait for logs (0 for infinite)")
+	logWatchCmd.Flags().StringVar(&watchName, "name", "", "name of watcher (group or group:consumer) to resume from the last processed logs and share them between watchers (redis only)")
 }
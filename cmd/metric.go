@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/emetric"
+	"github.com/lf-edge/eden/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"strings"
+	"time"
+)
+
+var (
+	metricType    string
+	metricTimeout time.Duration
+)
+
+//metricPreRun loads adam settings from config for metric commands
+func metricPreRun(cmd *cobra.Command, args []string) error {
+	assingCobraToViper(cmd)
+	viperLoaded, err := utils.LoadConfigFile(configFile)
+	if err != nil {
+		return fmt.Errorf("error reading config: %s", err.Error())
+	}
+	if viperLoaded {
+		certsIP = viper.GetString("adam.ip")
+		adamPort = viper.GetInt("adam.port")
+		adamDist = utils.ResolveAbsPath(viper.GetString("adam.dist"))
+		adamCA = utils.ResolveAbsPath(viper.GetString("adam.ca"))
+	}
+	return nil
+}
+
+//timeoutSeconds converts timeout into seconds for checkers rounding up,
+//so sub-second timeout does not turn into 0 which means infinite wait
+func timeoutSeconds(timeout time.Duration) time.Duration {
+	return (timeout + time.Second - 1) / time.Second
+}
+
+//parseFieldQuery parses field:regexp arguments, regexp may contain colons
+func parseFieldQuery(args []string) (map[string]string, error) {
+	q := make(map[string]string)
+	for _, a := range args {
+		s := strings.SplitN(a, ":", 2)
+		if len(s) != 2 {
+			return nil, fmt.Errorf("wrong query %q, expected field:regexp", a)
+		}
+		q[s[0]] = s[1]
+	}
+	return q, nil
+}
+
+var metricCmd = &cobra.Command{
+	Use:   "metric [field:regexp ...]",
+	Short: "Get metrics from a running EVE device",
+	Long: `
+Scans the ADAM metrics for correspondence with regular expressions requests to json fields.
+Nested fields are addressed with dots, e.g. 'eden metric --type am appName:nginx memory.usedMem:.*'.`,
+	PreRunE: metricPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctrl, err := controller.CloudPrepare()
+		if err != nil {
+			log.Fatalf("CloudPrepare: %s", err)
+		}
+		if err := ctrl.OnBoard(); err != nil {
+			log.Fatalf("OnBoard: %s", err)
+		}
+		dev, err := ctrl.GetDevice(deviceSelector)
+		if err != nil {
+			log.Fatalf("GetDevice: %s", err)
+		}
+		follow, err := cmd.Flags().GetBool("follow")
+		if err != nil {
+			log.Fatalf("Error in get param 'follow': %s", err)
+		}
+		zMetricType, err := emetric.GetZMetricType(metricType)
+		if err != nil {
+			log.Fatalf("Error in get param 'type': %s", err)
+		}
+		q, err := parseFieldQuery(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if follow {
+			if err = ctrl.MetricChecker(dev.GetID(), q, zMetricType, emetric.HandleAll, emetric.MetricNew, 0); err != nil {
+				log.Fatalf("MetricChecker: %s", err)
+			}
+		} else {
+			if err = ctrl.MetricLastCallback(dev.GetID(), q, zMetricType, emetric.HandleAll); err != nil {
+				log.Fatalf("MetricLastCallback: %s", err)
+			}
+		}
+	},
+}
+
+var metricWatchCmd = &cobra.Command{
+	Use:   "metricwatch [field:regexp ...]",
+	Short: "Wait for metrics matching the query from a running EVE device",
+	Long: `
+Waits for new metrics which correspond to regular expressions requests to json fields,
+prints the first one and exits. Exits with error if no metrics found before timeout.`,
+	PreRunE: metricPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctrl, err := controller.CloudPrepare()
+		if err != nil {
+			log.Fatalf("CloudPrepare: %s", err)
+		}
+		if err := ctrl.OnBoard(); err != nil {
+			log.Fatalf("OnBoard: %s", err)
+		}
+		dev, err := ctrl.GetDevice(deviceSelector)
+		if err != nil {
+			log.Fatalf("GetDevice: %s", err)
+		}
+		zMetricType, err := emetric.GetZMetricType(metricType)
+		if err != nil {
+			log.Fatalf("Error in get param 'type': %s", err)
+		}
+		q, err := parseFieldQuery(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err = ctrl.MetricChecker(dev.GetID(), q, zMetricType, emetric.HandleFirst, emetric.MetricNew, timeoutSeconds(metricTimeout)); err != nil {
+			log.Fatalf("MetricChecker: %s", err)
+		}
+	},
+}
+
+func metricInit() {
+	metricCmd.Flags().BoolP("follow", "f", false, "Monitor new metrics")
+	metricCmd.Flags().StringVarP(&metricType, "type", "", "all", fmt.Sprintf("metric type (%s)", strings.Join(emetric.ListZMetricType(), ",")))
+	metricWatchCmd.Flags().StringVarP(&metricType, "type", "", "all", fmt.Sprintf("metric type (%s)", strings.Join(emetric.ListZMetricType(), ",")))
+	metricWatchCmd.Flags().DurationVar(&metricTimeout, "timeout", 0, "time to wait for metrics (0 for infinite)")
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/device"
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/lf-edge/eve/api/go/info"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	networkType      string
+	networkSubnet    string
+	networkGateway   string
+	networkDNS       []string
+	networkDhcpRange string
+	networkUplink    string
+	networkDevModel  string
+	networkVPN       controller.ManifestVPN
+)
+
+//networkByName return config of network instance on dev with name
+func networkByName(ctrl controller.Cloud, dev *device.Ctx, name string) (*config.NetworkInstanceConfig, error) {
+	for _, id := range dev.GetNetworkInstances() {
+		ni, err := ctrl.GetNetworkInstanceConfig(id)
+		if err != nil {
+			return nil, err
+		}
+		if ni.GetDisplayname() == name {
+			return ni, nil
+		}
+	}
+	return nil, fmt.Errorf("no network instance with name %s", name)
+}
+
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "manage network instances on EVE",
+	Long:  `Create, list and delete network instances on EVE.`,
+}
+
+var networkCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "create network instance on EVE",
+	Long: `Create network instance of local, switch or cloud type on EVE.
+Gateway, dns and dhcp range of local network instance are calculated from subnet if not set.
+Switch network instance uses first adapter for switches of device model if --uplink is not set.
+Cloud network instance requires --vpn-gateway, --vpn-subnet and --vpn-psk.`,
+	Args:    cobra.ExactArgs(1),
+	PreRunE: devicePreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		ni := &controller.ManifestNetworkInstance{
+			Name:    args[0],
+			Type:    networkType,
+			Port:    networkUplink,
+			Subnet:  networkSubnet,
+			Gateway: networkGateway,
+			DNS:     networkDNS,
+		}
+		if networkDhcpRange != "" {
+			parts := strings.SplitN(networkDhcpRange, "-", 2)
+			if len(parts) != 2 {
+				log.Fatalf("wrong dhcp range %s, use start-end format", networkDhcpRange)
+			}
+			ni.DhcpRange = &controller.ManifestDhcpRange{Start: parts[0], End: parts[1]}
+		}
+		if ni.Type == "cloud" {
+			ni.VPN = &networkVPN
+		}
+		deviceRun(func(ctrl controller.Cloud, dev *device.Ctx) error {
+			if _, err := networkByName(ctrl, dev, ni.Name); err == nil {
+				return fmt.Errorf("network instance %s already exists", ni.Name)
+			}
+			if ni.Type == "switch" && ni.Port == "" {
+				devModelName := networkDevModel
+				if devModelName == "" {
+					devModelName = dev.GetDevModel()
+				}
+				if devModelName == "" {
+					devModelName = viper.GetString("eve.devmodel")
+				}
+				devModel, err := ctrl.GetDevModelByName(devModelName)
+				if err != nil {
+					return fmt.Errorf("GetDevModelByName error: %s", err)
+				}
+				ni.Port = devModel.GetFirstAdapterForSwitches()
+			}
+			if err := ctrl.ManifestApply(dev, &controller.Manifest{NetworkInstances: []*controller.ManifestNetworkInstance{ni}}); err != nil {
+				return err
+			}
+			log.Infof("create network instance %s with uuid %s", ni.Name, controller.ManifestID("networkInstance", ni.Name))
+			return nil
+		})
+	},
+}
+
+var networkLsCmd = &cobra.Command{
+	Use:     "ls",
+	Short:   "list network instances on EVE",
+	Long:    `List network instances on EVE with their state, bridge and assigned IPs from info of EVE.`,
+	PreRunE: devicePreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		changer, err := getControllerChanger()
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctrl, dev, err := changer.getControllerAndDev()
+		if err != nil {
+			log.Fatalf("getControllerAndDev error: %s", err)
+		}
+		niInfo, err := latestInfo(ctrl, dev, einfo.ZInfoNetworkInstance, func(im *info.ZInfoMsg) string {
+			return im.GetNiinfo().GetNetworkID()
+		})
+		if err != nil {
+			log.Warnf("cannot obtain info of network instances: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+		if _, err = fmt.Fprintln(w, "NAME\tUUID\tTYPE\tSUBNET\tUPLINK\tSTATE\tBRIDGE\tIPS"); err != nil {
+			log.Fatal(err)
+		}
+		for _, id := range dev.GetNetworkInstances() {
+			ni, err := ctrl.GetNetworkInstanceConfig(id)
+			if err != nil {
+				log.Fatalf("GetNetworkInstanceConfig: %s", err)
+			}
+			niType := strings.ToLower(strings.TrimPrefix(ni.GetInstType().String(), "ZnetInst"))
+			state, bridge, uplink := "-", "-", ni.GetPort().GetName()
+			var ips []string
+			if im, ok := niInfo[id]; ok {
+				el := im.GetNiinfo()
+				state = "inactive"
+				if el.GetActivated() {
+					state = "activated"
+				}
+				for _, networkErr := range el.GetNetworkErr() {
+					state = fmt.Sprintf("error: %s", networkErr.GetDescription())
+				}
+				if el.GetBridgeName() != "" {
+					bridge = el.GetBridgeName()
+				}
+				if el.GetCurrentUplinkIntf() != "" {
+					uplink = el.GetCurrentUplinkIntf()
+				}
+				for _, assignment := range el.GetIpAssignments() {
+					ips = append(ips, fmt.Sprintf("%s=%s", assignment.GetMacAddress(), strings.Join(assignment.GetIpAddress(), "|")))
+				}
+			}
+			if _, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", ni.GetDisplayname(), id, niType,
+				ni.GetIp().GetSubnet(), uplink, state, bridge, strings.Join(ips, ",")); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err = w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var networkDeleteCmd = &cobra.Command{
+	Use:     "delete <name>",
+	Short:   "delete network instance from EVE",
+	Long:    `Delete network instance from EVE. Network instances used by apps are not deleted.`,
+	Args:    cobra.ExactArgs(1),
+	PreRunE: devicePreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		deviceRun(func(ctrl controller.Cloud, dev *device.Ctx) error {
+			ni, err := networkByName(ctrl, dev, args[0])
+			if err != nil {
+				return err
+			}
+			id := ni.GetUuidandversion().GetUuid()
+			for _, appID := range dev.GetApplicationInstances() {
+				app, err := ctrl.GetApplicationInstanceConfig(appID)
+				if err != nil {
+					return err
+				}
+				for _, iface := range app.GetInterfaces() {
+					if iface.GetNetworkId() == id {
+						return fmt.Errorf("network instance %s is used by app %s", args[0], app.GetDisplayname())
+					}
+				}
+			}
+			var networkInstances []string
+			for _, el := range dev.GetNetworkInstances() {
+				if el != id {
+					networkInstances = append(networkInstances, el)
+				}
+			}
+			dev.SetNetworkInstanceConfig(networkInstances)
+			return ctrl.RemoveNetworkInstanceConfig(id)
+		})
+	},
+}
+
+func networkInit() {
+	networkCmd.AddCommand(networkCreateCmd)
+	networkCmd.AddCommand(networkLsCmd)
+	networkCmd.AddCommand(networkDeleteCmd)
+	networkCmd.PersistentFlags().StringVarP(&controllerMode, "mode", "m", "adam://", "mode to use [file|proto|adam|zedcloud|fake]://<URL>")
+	createFlags := networkCreateCmd.Flags()
+	createFlags.StringVar(&networkType, "type", "local", "type of network instance: local, switch or cloud")
+	createFlags.StringVar(&networkSubnet, "subnet", "", "subnet of local or cloud network instance, e.g. 10.1.0.0/24")
+	createFlags.StringVar(&networkGateway, "gateway", "", "gateway (first host of subnet if empty)")
+	createFlags.StringSliceVar(&networkDNS, "dns", nil, "dns servers (gateway if empty)")
+	createFlags.StringVar(&networkDhcpRange, "dhcp-range", "", "dhcp range in format start-end (from second to last host of subnet if empty)")
+	createFlags.StringVar(&networkUplink, "uplink", "", "uplink adapter (first adapter for switches of device model for switch and uplink for others if empty)")
+	createFlags.StringVar(&networkVPN.Gateway, "vpn-gateway", "", "IP of VPN gateway for cloud network instance")
+	createFlags.StringVar(&networkVPN.Subnet, "vpn-subnet", "", "subnet behind VPN gateway for cloud network instance")
+	createFlags.StringVar(&networkVPN.PreSharedKey, "vpn-psk", "", "pre-shared key of VPN for cloud network instance")
+	createFlags.StringVar(&networkDevModel, "devmodel", "", "device model to get adapter for switch (model of device or eve.devmodel from config if empty)")
+}
@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/docker/docker/pkg/fileutils"
+	"github.com/dustin/go-humanize"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/device"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/lf-edge/eve/api/go/info"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	podName     string
+	podMemory   string
+	podCPUs     uint32
+	podNetworks []string
+	podPorts    []string
+	podMetadata string
+)
+
+//podDefaultNetwork is network instance created for pods without --networks
+var podDefaultNetwork = &controller.ManifestNetworkInstance{
+	Name:      "default",
+	Type:      "local",
+	Subnet:    "10.11.12.0/24",
+	Gateway:   "10.11.12.1",
+	DNS:       []string{"10.11.12.1"},
+	DhcpRange: &controller.ManifestDhcpRange{Start: "10.11.12.2", End: "10.11.12.254"},
+}
+
+//podPreRun loads eserver settings from config for pod commands
+func podPreRun(cmd *cobra.Command, args []string) error {
+	assingCobraToViper(cmd)
+	viperLoaded, err := utils.LoadConfigFile(configFile)
+	if err != nil {
+		return fmt.Errorf("error reading config: %s", err.Error())
+	}
+	if viperLoaded {
+		eserverIP = viper.GetString("eden.eserver.ip")
+		eserverPort = viper.GetInt("eden.eserver.port")
+		eserverImageDist = utils.ResolveAbsPath(viper.GetString("eden.images.dist"))
+	}
+	return nil
+}
+
+//podByName return config of app on dev with name
+func podByName(ctrl controller.Cloud, dev *device.Ctx, name string) (*config.AppInstanceConfig, error) {
+	for _, id := range dev.GetApplicationInstances() {
+		app, err := ctrl.GetApplicationInstanceConfig(id)
+		if err != nil {
+			return nil, err
+		}
+		if app.GetDisplayname() == name {
+			return app, nil
+		}
+	}
+	return nil, fmt.Errorf("no pod with name %s", name)
+}
+
+//podImage return manifest datastore and image for docker://reference or local file
+func podImage(ref string) (*controller.ManifestDatastore, *controller.ManifestImage, error) {
+	if strings.HasPrefix(ref, "docker://") {
+		ref = strings.TrimPrefix(ref, "docker://")
+		registry := "docker.io"
+		if parts := strings.SplitN(ref, "/", 2); len(parts) == 2 && strings.ContainsAny(parts[0], ".:") {
+			registry, ref = parts[0], parts[1]
+		}
+		if !strings.Contains(filepath.Base(ref), ":") {
+			ref += ":latest"
+		}
+		ds := &controller.ManifestDatastore{Name: registry, Type: "container", FQDN: fmt.Sprintf("docker://%s", registry)}
+		return ds, &controller.ManifestImage{Name: fmt.Sprintf("%s/%s", registry, ref), Datastore: ds.Name, Path: ref, Format: "container"}, nil
+	}
+	filePath := strings.TrimPrefix(ref, "file://")
+	format := strings.TrimPrefix(filepath.Ext(filePath), ".")
+	if format == "img" {
+		format = "raw"
+	}
+	if _, ok := config.Format_value[strings.ToUpper(format)]; !ok {
+		return nil, nil, fmt.Errorf("unknown format of image %s", filePath)
+	}
+	imagePath := filepath.Join(eserverImageDist, "vm", filepath.Base(filePath))
+	if _, err := fileutils.CopyFile(filePath, imagePath); err != nil {
+		return nil, nil, fmt.Errorf("CopyFile problem: %s", err)
+	}
+	ds := &controller.ManifestDatastore{Name: "eserver", Type: "http", FQDN: fmt.Sprintf("http://%s:%d", eserverIP, eserverPort)}
+	return ds, &controller.ManifestImage{
+		Name:      filepath.Base(filePath),
+		Datastore: ds.Name,
+		Path:      fmt.Sprintf("vm/%s", filepath.Base(filePath)),
+		Format:    format,
+		File:      imagePath,
+	}, nil
+}
+
+var podCmd = &cobra.Command{
+	Use:   "pod",
+	Short: "manage applications on EVE",
+	Long:  `Deploy and manage applications on EVE.`,
+}
+
+var podDeployCmd = &cobra.Command{
+	Use:   "deploy <docker://image | file.qcow2>",
+	Short: "deploy application on EVE",
+	Long: `Deploy container from registry (docker://image) or VM image (local file, copied into eserver) on EVE.
+Without --networks application is connected to local network instance "default".`,
+	Args:    cobra.ExactArgs(1),
+	PreRunE: podPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		memory, err := humanize.ParseBytes(podMemory)
+		if err != nil {
+			log.Fatalf("wrong memory %s: %s", podMemory, err)
+		}
+		ds, img, err := podImage(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		name := podName
+		if name == "" {
+			name = strings.Split(strings.TrimSuffix(filepath.Base(img.Path), filepath.Ext(img.Path)), ":")[0]
+		}
+		deviceRun(func(ctrl controller.Cloud, dev *device.Ctx) error {
+			if _, err := podByName(ctrl, dev, name); err == nil {
+				return fmt.Errorf("pod %s already exists, use --name to set another name", name)
+			}
+			manifest := &controller.Manifest{
+				Datastores: []*controller.ManifestDatastore{ds},
+				Images:     []*controller.ManifestImage{img},
+				Apps: []*controller.ManifestApp{{
+					Name:     name,
+					Image:    img.Name,
+					Memory:   uint32(memory / 1024),
+					CPUs:     podCPUs,
+					Networks: podNetworks,
+					Ports:    podPorts,
+					UserData: podMetadata,
+				}},
+			}
+			if len(podNetworks) == 0 {
+				manifest.Apps[0].Networks = []string{podDefaultNetwork.Name}
+				found := false
+				for _, id := range dev.GetNetworkInstances() {
+					if ni, err := ctrl.GetNetworkInstanceConfig(id); err == nil && ni.GetDisplayname() == podDefaultNetwork.Name {
+						found = true
+					}
+				}
+				if !found {
+					manifest.NetworkInstances = []*controller.ManifestNetworkInstance{podDefaultNetwork}
+				}
+			}
+			if err := ctrl.ManifestApply(dev, manifest); err != nil {
+				return err
+			}
+			log.Infof("deploy pod %s with uuid %s", name, controller.ManifestID("app", name))
+			return nil
+		})
+	},
+}
+
+var podPsCmd = &cobra.Command{
+	Use:     "ps",
+	Short:   "list applications on EVE",
+	Long:    `List applications on EVE with their state, IPs and published ports.`,
+	PreRunE: podPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		changer, err := getControllerChanger()
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctrl, dev, err := changer.getControllerAndDev()
+		if err != nil {
+			log.Fatalf("getControllerAndDev error: %s", err)
+		}
+		appInfo, err := latestInfo(ctrl, dev, einfo.ZInfoAppInstance, func(im *info.ZInfoMsg) string {
+			return im.GetAinfo().GetAppID()
+		})
+		if err != nil {
+			log.Warnf("cannot obtain info of apps: %s", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+		if _, err = fmt.Fprintln(w, "NAME\tUUID\tIMAGE\tSTATE\tIP\tPORTS"); err != nil {
+			log.Fatal(err)
+		}
+		for _, id := range dev.GetApplicationInstances() {
+			app, err := ctrl.GetApplicationInstanceConfig(id)
+			if err != nil {
+				log.Fatalf("GetApplicationInstanceConfig: %s", err)
+			}
+			var images, ports, ips []string
+			for _, drive := range app.GetDrives() {
+				images = append(images, drive.GetImage().GetName())
+			}
+			for _, iface := range app.GetInterfaces() {
+				for _, acl := range iface.GetAcls() {
+					for _, action := range acl.GetActions() {
+						if !action.GetPortmap() {
+							continue
+						}
+						for _, match := range acl.GetMatches() {
+							if match.GetType() == "lport" {
+								ports = append(ports, fmt.Sprintf("%s->%d", match.GetValue(), action.GetAppPort()))
+							}
+						}
+					}
+				}
+			}
+			state := "-"
+			if !app.GetActivate() {
+				state = "stopped"
+			}
+			if im, ok := appInfo[id]; ok {
+				ai := im.GetAinfo()
+				state = strings.ToLower(ai.GetState().String())
+				for _, network := range ai.GetNetwork() {
+					ips = append(ips, network.GetIPAddrs()...)
+				}
+			}
+			if _, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", app.GetDisplayname(), id, strings.Join(images, ","),
+				state, strings.Join(ips, ","), strings.Join(ports, ",")); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err = w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+//podActivateCmd return command which sets activate of app to value
+func podActivateCmd(use, short string, activate bool) *cobra.Command {
+	return &cobra.Command{
+		Use:     fmt.Sprintf("%s <name>", use),
+		Short:   short,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: podPreRun,
+		Run: func(cmd *cobra.Command, args []string) {
+			deviceRun(func(ctrl controller.Cloud, dev *device.Ctx) error {
+				app, err := podByName(ctrl, dev, args[0])
+				if err != nil {
+					return err
+				}
+				if app.Activate == activate {
+					return nil
+				}
+				app.Activate = activate
+				version, _ := strconv.Atoi(app.GetUuidandversion().GetVersion())
+				app.Uuidandversion.Version = strconv.Itoa(version + 1)
+				return nil
+			})
+		},
+	}
+}
+
+var podStopCmd = podActivateCmd("stop", "stop application on EVE", false)
+
+var podStartCmd = podActivateCmd("start", "start stopped application on EVE", true)
+
+var podDeleteCmd = &cobra.Command{
+	Use:     "delete <name>",
+	Short:   "delete application from EVE",
+	Args:    cobra.ExactArgs(1),
+	PreRunE: podPreRun,
+	Run: func(cmd *cobra.Command, args []string) {
+		deviceRun(func(ctrl controller.Cloud, dev *device.Ctx) error {
+			app, err := podByName(ctrl, dev, args[0])
+			if err != nil {
+				return err
+			}
+			id := app.GetUuidandversion().GetUuid()
+			var apps []string
+			for _, el := range dev.GetApplicationInstances() {
+				if el != id {
+					apps = append(apps, el)
+				}
+			}
+			dev.SetApplicationInstanceConfig(apps)
+			return ctrl.RemoveApplicationInstanceConfig(id)
+		})
+	},
+}
+
+func podInit() {
+	podCmd.AddCommand(podDeployCmd)
+	podCmd.AddCommand(podPsCmd)
+	podCmd.AddCommand(podStopCmd)
+	podCmd.AddCommand(podStartCmd)
+	podCmd.AddCommand(podDeleteCmd)
+	podCmd.PersistentFlags().StringVarP(&controllerMode, "mode", "m", "adam://", "mode to use [file|proto|adam|zedcloud|fake]://<URL>")
+	deployFlags := podDeployCmd.Flags()
+	deployFlags.StringVarP(&podName, "name", "n", "", "name of pod (base name of image if empty)")
+	deployFlags.StringVar(&podMemory, "memory", "1G", "memory for pod")
+	deployFlags.Uint32Var(&podCPUs, "cpus", 1, "cpus for pod")
+	deployFlags.StringSliceVar(&podNetworks, "networks", nil, "network instances to connect pod to")
+	deployFlags.StringSliceVarP(&podPorts, "port-publish", "p", nil, "ports to publish in format port:appPort[/udp]")
+	deployFlags.StringVar(&podMetadata, "metadata", "", "metadata (user data) for pod")
+}
@@ -16,6 +16,7 @@ import (
 
 var verbosity string
 var configFile string
+var deviceSelector string
 var rootCmd = &cobra.Command{Use: "eden", PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 	if err := setUpLogs(os.Stdout, verbosity); err != nil {
 		return err
@@ -43,9 +44,16 @@ func assingCobraToViper(cmd *cobra.Command) {
 
 func init() {
 	rootCmd.AddCommand(infoCmd)
+	rootCmd.AddCommand(infoWatchCmd)
 	infoInit()
 	rootCmd.AddCommand(logCmd)
+	rootCmd.AddCommand(logWatchCmd)
 	logInit()
+	rootCmd.AddCommand(metricCmd)
+	rootCmd.AddCommand(metricWatchCmd)
+	metricInit()
+	rootCmd.AddCommand(flowLogCmd)
+	flowLogInit()
 	rootCmd.AddCommand(certsCmd)
 	certsInit()
 	rootCmd.AddCommand(serverCmd)
@@ -80,6 +88,25 @@ func init() {
 	testInit()
 	rootCmd.AddCommand(controllerCmd)
 	controllerInit()
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(deleteCmd)
+	manifestInit()
+	rootCmd.AddCommand(podCmd)
+	podInit()
+	rootCmd.AddCommand(networkCmd)
+	networkInit()
+	rootCmd.AddCommand(devModelCmd)
+	devModelInit()
+	rootCmd.AddCommand(simCmd)
+	simInit()
+	rootCmd.AddCommand(zedcloudCmd)
+	zedcloudInit()
+	rootCmd.AddCommand(deviceCmd)
+	deviceInit()
+	rootCmd.AddCommand(supportBundleCmd)
+	supportBundleInit()
+	rootCmd.AddCommand(cacheCmd)
+	cacheInit()
 }
 
 // Execute primary function for cobra
@@ -89,6 +116,7 @@ func Execute() {
 		log.Fatal(err)
 	}
 	rootCmd.PersistentFlags().StringVar(&configFile, "config-file", configPath, "path to config file")
+	rootCmd.PersistentFlags().StringVar(&deviceSelector, "device", "", "uuid, serial or name of device to work with (first device if empty)")
 	rootCmd.PersistentFlags().StringVarP(&verbosity, "verbosity", "v", logrus.InfoLevel.String(), "Log level (debug, info, warn, error, fatal, panic")
 	_ = rootCmd.Execute()
 }
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/sim"
+	"github.com/lf-edge/eden/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	simDist         string
+	simCount        int
+	simSerialPrefix string
+	simInterval     int
+	simPidFile      string
+	simLogFile      string
+)
+
+var simCmd = &cobra.Command{
+	Use:   "sim",
+	Short: "simulated EVE devices",
+	Long:  `Simulated EVE devices, which onboard into adam and report synthetic info, logs and metrics without QEMU.`,
+}
+
+var runSimCmd = &cobra.Command{
+	Use:   "run",
+	Short: "run simulated devices in foreground",
+	Long:  `Run simulated devices in foreground until interrupted.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		viperLoaded, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading config: %s", err.Error())
+		}
+		if viperLoaded {
+			simDist = utils.ResolveAbsPath(viper.GetString("sim.dist"))
+			simCount = viper.GetInt("sim.count")
+			simSerialPrefix = viper.GetString("sim.serial-prefix")
+			simInterval = viper.GetInt("sim.interval")
+			certsDir = utils.ResolveAbsPath(viper.GetString("eden.certs-dist"))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		vars, err := utils.InitVars()
+		if err != nil {
+			log.Fatalf("InitVars error: %s", err)
+		}
+		ctrl, err := controller.CloudPrepare()
+		if err != nil {
+			log.Fatalf("CloudPrepare: %s", err)
+		}
+		var serials []string
+		for i := 0; i < simCount; i++ {
+			serials = append(serials, fmt.Sprintf("%s-%d", simSerialPrefix, i))
+		}
+		//keep serial of EVE in the list to not break onboarding of real device
+		if err = ctrl.Register(vars.EveCert, strings.Join(append([]string{vars.EveSerial}, serials...), ",")); err != nil {
+			log.Fatalf("cannot register serials in controller: %s", err)
+		}
+		url, ca := fmt.Sprintf("https://%s:%s", vars.AdamIP, vars.AdamPort), vars.AdamCA
+		if vars.ZedcloudEnabled {
+			url, ca = vars.ZedcloudURL, vars.ZedcloudCA
+		}
+		var devices []*sim.Device
+		for _, serial := range serials {
+			devices = append(devices, &sim.Device{
+				Serial:      serial,
+				URL:         url,
+				CA:          ca,
+				OnboardCert: vars.EveCert,
+				OnboardKey:  filepath.Join(certsDir, "onboard.key.pem"),
+				Dir:         simDist,
+				Interval:    time.Duration(simInterval) * time.Second,
+				APIv2:       vars.ZedcloudEnabled,
+			})
+		}
+		stop := make(chan struct{})
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			<-sigChan
+			close(stop)
+		}()
+		log.Infof("Running %d simulated devices", len(devices))
+		sim.RunDevices(devices, stop)
+	},
+}
+
+var startSimCmd = &cobra.Command{
+	Use:   "start",
+	Short: "start simulated devices",
+	Long:  `Start simulated devices in background.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		viperLoaded, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading config: %s", err.Error())
+		}
+		if viperLoaded {
+			simDist = utils.ResolveAbsPath(viper.GetString("sim.dist"))
+			simCount = viper.GetInt("sim.count")
+			simSerialPrefix = viper.GetString("sim.serial-prefix")
+			simInterval = viper.GetInt("sim.interval")
+			simPidFile = utils.ResolveAbsPath(viper.GetString("sim.pid"))
+			simLogFile = utils.ResolveAbsPath(viper.GetString("sim.log"))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		command, err := os.Executable()
+		if err != nil {
+			log.Fatalf("cannot obtain executable path: %s", err)
+		}
+		log.Infof("Executable path: %s", command)
+		if err := utils.StartSim(command, simCount, simSerialPrefix, simInterval, simDist, simLogFile, simPidFile); err != nil {
+			log.Errorf("cannot start simulated devices: %s", err)
+		} else {
+			log.Infof("%d simulated devices are running", simCount)
+		}
+	},
+}
+
+var stopSimCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "stop simulated devices",
+	Long:  `Stop simulated devices.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		viperLoaded, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading config: %s", err.Error())
+		}
+		if viperLoaded {
+			simPidFile = utils.ResolveAbsPath(viper.GetString("sim.pid"))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := utils.StopSim(simPidFile); err != nil {
+			log.Errorf("cannot stop simulated devices: %s", err)
+		}
+	},
+}
+
+var statusSimCmd = &cobra.Command{
+	Use:   "status",
+	Short: "status of simulated devices",
+	Long:  `Status of simulated devices.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		viperLoaded, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading config: %s", err.Error())
+		}
+		if viperLoaded {
+			simPidFile = utils.ResolveAbsPath(viper.GetString("sim.pid"))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		statusSim, err := utils.StatusSim(simPidFile)
+		if err != nil {
+			log.Errorf("cannot obtain status of simulated devices: %s", err)
+		} else {
+			fmt.Printf("Simulated devices status: %s\n", statusSim)
+		}
+	},
+}
+
+func simInit() {
+	simCmd.AddCommand(runSimCmd)
+	simCmd.AddCommand(startSimCmd)
+	simCmd.AddCommand(stopSimCmd)
+	simCmd.AddCommand(statusSimCmd)
+	currentPath, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, cmd := range []*cobra.Command{runSimCmd, startSimCmd} {
+		cmd.Flags().StringVarP(&simDist, "sim-dist", "", filepath.Join(currentPath, defaults.DefaultDist, defaults.DefaultSimDist), "directory to save certs of simulated devices")
+		cmd.Flags().IntVarP(&simCount, "sim-count", "", defaults.DefaultSimCount, "number of simulated devices")
+		cmd.Flags().StringVarP(&simSerialPrefix, "sim-serial-prefix", "", defaults.DefaultSimSerialPrefix, "prefix for serials of simulated devices")
+		cmd.Flags().IntVarP(&simInterval, "sim-interval", "", defaults.DefaultSimInterval, "interval in seconds to poll config")
+	}
+	runSimCmd.Flags().StringVarP(&certsDir, "certs-dist", "", filepath.Join(currentPath, defaults.DefaultDist, defaults.DefaultCertsDist), "directory with onboarding certs")
+	startSimCmd.Flags().StringVarP(&simPidFile, "sim-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "sim.pid"), "file for save simulated devices pid")
+	startSimCmd.Flags().StringVarP(&simLogFile, "sim-log", "", filepath.Join(currentPath, defaults.DefaultDist, "sim.log"), "file for save simulated devices log")
+	stopSimCmd.Flags().StringVarP(&simPidFile, "sim-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "sim.pid"), "file for save simulated devices pid")
+	statusSimCmd.Flags().StringVarP(&simPidFile, "sim-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "sim.pid"), "file for save simulated devices pid")
+}
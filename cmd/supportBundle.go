@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/adam"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/device"
+	"github.com/lf-edge/eden/pkg/supportbundle"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/info"
+	"github.com/lf-edge/eve/api/go/logs"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	supportBundleOutput   string
+	supportBundleTail     int
+	supportBundleSSH      bool
+	supportBundleEveFiles []string
+)
+
+var supportBundleCmd = &cobra.Command{
+	Use:   "support-bundle",
+	Short: "collect support bundle",
+	Long: `Collect logs, info, configs and status of harness and EVE into tar.gz archive for bug reports.
+Secrets (private keys, passwords, tokens) inside of collected files are redacted.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		viperLoaded, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading config: %s", err.Error())
+		}
+		if viperLoaded {
+			adamDist = utils.ResolveAbsPath(viper.GetString("adam.dist"))
+			adamEmbedded = viper.GetBool("adam.embedded")
+			adamPidFile = utils.ResolveAbsPath(viper.GetString("adam.pid"))
+			eserverPidFile = utils.ResolveAbsPath(viper.GetString("eden.eserver.pid"))
+			evePidFile = utils.ResolveAbsPath(viper.GetString("eve.pid"))
+			eveLogFile = utils.ResolveAbsPath(viper.GetString("eve.log"))
+			qemuFileToSave = utils.ResolveAbsPath(viper.GetString("eve.qemu-config"))
+			eveSSHKey = utils.ResolveAbsPath(viper.GetString("eden.ssh-key"))
+			extension := filepath.Ext(eveSSHKey)
+			eveSSHKey = strings.TrimSuffix(eveSSHKey, extension)
+			eveInstancesDir = utils.ResolveAbsPath(viper.GetString("eve.instances"))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		changer, err := getControllerChanger()
+		if err != nil {
+			log.Fatalf("getControllerChanger: %s", err)
+		}
+		ctrl, dev, devErr := changer.getControllerAndDev()
+		deviceID := ""
+		if devErr == nil {
+			deviceID = dev.GetID().String()
+		}
+		inst := eveInstance()
+		//create output only after setup to not leave truncated archive if it fails
+		f, err := os.Create(supportBundleOutput)
+		if err != nil {
+			log.Fatalf("cannot create %s: %s", supportBundleOutput, err)
+		}
+		defer f.Close()
+		w := supportbundle.NewWriter(f, deviceID)
+		if devErr != nil {
+			w.Errorf("getControllerAndDev: %s", devErr)
+		}
+		supportBundleEden(w)
+		supportBundleEve(w, inst)
+		if err = w.Add("status.txt", []byte(supportBundleStatus(inst))); err != nil {
+			w.Errorf("status: %s", err)
+		}
+		if dev != nil {
+			supportBundleController(w, ctrl, dev)
+			supportBundleAdam(w, dev)
+			supportBundleRedis(w, dev)
+		}
+		if supportBundleSSH {
+			supportBundleSSHFiles(w, inst)
+		}
+		if err = w.Close(); err != nil {
+			_ = f.Close()
+			_ = os.Remove(supportBundleOutput)
+			log.Fatalf("cannot write %s: %s", supportBundleOutput, err)
+		}
+		for _, e := range w.Errors() {
+			log.Warn(e)
+		}
+		log.Infof("Support bundle saved into %s", supportBundleOutput)
+	},
+}
+
+//supportBundleFile add file into bundle with name if it exists
+func supportBundleFile(w *supportbundle.Writer, name string, file string) {
+	if file == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		w.Errorf("%s: %s", name, err)
+		return
+	}
+	if err = w.Add(name, data); err != nil {
+		w.Errorf("%s: %s", name, err)
+	}
+}
+
+//supportBundleEden add config and context of eden
+func supportBundleEden(w *supportbundle.Writer) {
+	supportBundleFile(w, "eden/config.yml", viper.ConfigFileUsed())
+	edenDir, err := utils.DefaultEdenDir()
+	if err != nil {
+		w.Errorf("eden dir: %s", err)
+		return
+	}
+	supportBundleFile(w, "eden/context.yml", filepath.Join(edenDir, defaults.DefaultContextFile))
+}
+
+//supportBundleEve add console log and qemu config of EVE
+func supportBundleEve(w *supportbundle.Writer, inst *utils.EVEInstance) {
+	logFile, qemuConfig := eveLogFile, qemuFileToSave
+	if inst != nil {
+		logFile, qemuConfig = inst.LogFile(), inst.QemuConfig()
+	}
+	supportBundleFile(w, "eve/eve.log", logFile)
+	supportBundleFile(w, "eve/qemu.conf", qemuConfig)
+}
+
+//supportBundleStatus return status of harness components
+func supportBundleStatus(inst *utils.EVEInstance) string {
+	var buf bytes.Buffer
+	status := func(name string, s string, err error) {
+		if err != nil {
+			s = fmt.Sprintf("error: %s", err)
+		}
+		fmt.Fprintf(&buf, "%s: %s\n", name, s)
+	}
+	if adamEmbedded {
+		s, err := utils.StatusAdamEmbedded(adamPidFile)
+		status("adam (embedded)", s, err)
+	} else {
+		s, err := utils.StatusAdam()
+		status("adam", s, err)
+		s, err = utils.StatusRedis()
+		status("redis", s, err)
+	}
+	s, err := utils.StatusEServer(eserverPidFile)
+	status("eserver", s, err)
+	pidFile := evePidFile
+	if inst != nil {
+		pidFile = inst.PidFile()
+	}
+	s, err = utils.StatusEVEQemu(pidFile)
+	status("eve", s, err)
+	return buf.String()
+}
+
+//supportBundleController add config of device, latest info per type and last log bundles from controller
+func supportBundleController(w *supportbundle.Writer, ctrl controller.Cloud, dev *device.Ctx) {
+	if data, err := ctrl.GetConfigBytes(dev, true); err != nil {
+		w.Errorf("config: %s", err)
+	} else if err = w.Add("controller/config.json", data); err != nil {
+		w.Errorf("config: %s", err)
+	}
+	marshaler := jsonpb.Marshaler{Indent: "  "}
+	lastInfo, err := latestInfo(ctrl, dev, einfo.ZAll, func(im *info.ZInfoMsg) string {
+		return im.GetZtype().String()
+	})
+	if err != nil {
+		w.Errorf("info: %s", err)
+	}
+	for ztype, im := range lastInfo {
+		name := path.Join(supportbundle.InfoDir, fmt.Sprintf("%s.json", ztype))
+		data, err := marshaler.MarshalToString(im)
+		if err == nil {
+			err = w.Add(name, []byte(data))
+		}
+		if err != nil {
+			w.Errorf("%s: %s", name, err)
+		}
+	}
+	i := 0
+	handleBundle := func(lb *logs.LogBundle) bool {
+		name := path.Join(supportbundle.LogsDir, fmt.Sprintf("%04d.json", i))
+		i++
+		data, err := marshaler.MarshalToString(lb)
+		if err == nil {
+			err = w.Add(name, []byte(data))
+		}
+		if err != nil {
+			w.Errorf("%s: %s", name, err)
+		}
+		return false
+	}
+	q := map[string]string{loaders.TailKey: strconv.Itoa(supportBundleTail)}
+	if err = ctrl.LogBundleLastCallback(dev.GetID(), q, handleBundle); err != nil {
+		w.Errorf("logs: %s", err)
+	}
+}
+
+//supportBundleAdam add directory of device from local Adam
+func supportBundleAdam(w *supportbundle.Writer, dev *device.Ctx) {
+	if adamDist == "" {
+		return
+	}
+	devDir := filepath.Join(adamDist, "run", "adam", "device", dev.GetID().String())
+	if _, err := os.Stat(devDir); err != nil {
+		w.Errorf("adam: %s", err)
+		return
+	}
+	if err := w.AddPath(path.Join("adam", "device", dev.GetID().String()), devDir); err != nil {
+		w.Errorf("adam: %s", err)
+	}
+}
+
+//supportBundleRedis add last entries of redis streams of device
+func supportBundleRedis(w *supportbundle.Writer, dev *device.Ctx) {
+	redisURL := viper.GetString("adam.redis.eden")
+	if adamEmbedded || redisURL == "" {
+		return
+	}
+	addr, password, databaseID, err := adam.ParseRedisURL(redisURL)
+	if err != nil || addr == "" {
+		w.Errorf("redis: wrong url %s: %v", redisURL, err)
+		return
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       databaseID,
+	})
+	defer client.Close()
+	for _, prefix := range []string{defaults.DefaultLogsRedisPrefix, defaults.DefaultInfoRedisPrefix,
+		defaults.DefaultMetricsRedisPrefix, defaults.DefaultFlowLogRedisPrefix} {
+		stream := prefix + dev.GetID().String()
+		rr, err := client.XRevRangeN(stream, "+", "-", int64(supportBundleTail)).Result()
+		if err != nil {
+			w.Errorf("redis %s: %s", stream, err)
+			continue
+		}
+		var buf bytes.Buffer
+		for i := len(rr) - 1; i >= 0; i-- {
+			line, err := json.Marshal(map[string]interface{}{"id": rr[i].ID, "values": rr[i].Values})
+			if err != nil {
+				w.Errorf("redis %s: %s", stream, err)
+				continue
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		if err = w.Add(path.Join("redis", stream+".jsonl"), buf.Bytes()); err != nil {
+			w.Errorf("redis %s: %s", stream, err)
+		}
+	}
+}
+
+//supportBundleSSHFiles add files pulled from EVE over ssh
+func supportBundleSSHFiles(w *supportbundle.Writer, inst *utils.EVEInstance) {
+	port := eveSSHPort
+	if inst != nil {
+		port = inst.SSHPort
+	}
+	if _, err := os.Stat(eveSSHKey); err != nil {
+		w.Errorf("ssh key problem: %s", err)
+		return
+	}
+	sshArgs := strings.Fields(fmt.Sprintf("-o ConnectTimeout=3 -oStrictHostKeyChecking=no -i %s -p %d root@%s", eveSSHKey, port, eveHost))
+	sshArgs = append(sshArgs, "tar", "cf", "-")
+	sshArgs = append(sshArgs, supportBundleEveFiles...)
+	log.Infof("Pull %s from EVE %s:%d", strings.Join(supportBundleEveFiles, ","), eveHost, port)
+	sshCmd := exec.Command("ssh", sshArgs...)
+	var stderr bytes.Buffer
+	sshCmd.Stderr = &stderr
+	stdout, err := sshCmd.StdoutPipe()
+	if err != nil {
+		w.Errorf("ssh: %s", err)
+		return
+	}
+	if err = sshCmd.Start(); err != nil {
+		w.Errorf("ssh: %s", err)
+		return
+	}
+	if err = w.AddTar("eve", stdout); err != nil {
+		w.Errorf("ssh: %s", err)
+	}
+	if err = sshCmd.Wait(); err != nil {
+		w.Errorf("ssh: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+}
+
+func supportBundleInit() {
+	currentPath, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	supportBundleCmd.Flags().StringVarP(&supportBundleOutput, "output", "o", "support-bundle.tar.gz", "file to save support bundle")
+	supportBundleCmd.Flags().IntVar(&supportBundleTail, "tail", 100, "number of last log bundles and redis entries to collect")
+	supportBundleCmd.Flags().StringVarP(&controllerMode, "mode", "m", "adam://", "mode to use [file|proto|adam|zedcloud|fake]://<URL>")
+	supportBundleCmd.Flags().BoolVar(&supportBundleSSH, "ssh", false, "pull files from EVE over ssh")
+	supportBundleCmd.Flags().StringSliceVar(&supportBundleEveFiles, "eve-files", []string{"/persist/log", "/persist/status"}, "files and directories to pull from EVE with --ssh")
+	supportBundleCmd.Flags().StringVarP(&eveSSHKey, "ssh-key", "", filepath.Join(currentPath, defaults.DefaultCertsDist, "id_rsa"), "file to use for ssh access")
+	supportBundleCmd.Flags().StringVarP(&eveHost, "eve-host", "", defaults.DefaultEVEHost, "IP of eve")
+	supportBundleCmd.Flags().IntVarP(&eveSSHPort, "eve-ssh-port", "", defaults.DefaultSSHPort, "Port for ssh access")
+	supportBundleCmd.Flags().StringVarP(&eveName, "name", "", "", "name of EVE instance to collect instead of EVE from config")
+	supportBundleCmd.Flags().StringVarP(&eveInstancesDir, "eve-instances", "", filepath.Join(currentPath, defaults.DefaultDist, defaults.DefaultEVEInstancesDist), "directory for named EVE instances")
+	supportBundleCmd.Flags().StringVar(&configFile, "config", "", "path to config file")
+}
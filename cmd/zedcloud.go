@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/lf-edge/adam/pkg/driver"
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eden/pkg/zedapi"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+var (
+	zedcloudDist    string
+	zedcloudPort    int
+	zedcloudPidFile string
+	zedcloudLogFile string
+)
+
+var zedcloudCmd = &cobra.Command{
+	Use:   "zedcloud",
+	Short: "local stand-in of zedcloud-compatible controller",
+	Long: `Local stand-in of zedcloud-compatible controller, which serves v2 device API with auth containers.
+Set zedcloud.enabled in config to use it (or any other compatible controller from zedcloud.url) instead of adam.`,
+}
+
+var serveZedcloudCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "run stand-in inside eden process",
+	Long:  `Run stand-in inside eden process with file storage. Used by start, blocks until killed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		storage, err := filepath.Abs(zedcloudDist)
+		if err != nil {
+			log.Fatalf("zedcloud-dist problems: %s", err)
+		}
+		if err = os.MkdirAll(storage, 0755); err != nil {
+			log.Fatalf("cannot create %s: %s", storage, err)
+		}
+		mgr := &driver.DeviceManagerFile{}
+		if _, err = mgr.Init(storage); err != nil {
+			log.Fatalf("cannot init file storage in %s: %s", storage, err)
+		}
+		mgr.SetCacheTimeout(defaults.DefaultAdamCertRefresh)
+		srv := &zedapi.Server{
+			Port:          strconv.Itoa(zedcloudPort),
+			Address:       "0.0.0.0",
+			CertPath:      filepath.Join(certsDir, "server.pem"),
+			KeyPath:       filepath.Join(certsDir, "server-key.pem"),
+			DeviceManager: mgr,
+		}
+		log.Fatal(srv.Start())
+	},
+}
+
+var startZedcloudCmd = &cobra.Command{
+	Use:   "start",
+	Short: "start stand-in",
+	Long:  `Start stand-in in background.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		viperLoaded, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading config: %s", err.Error())
+		}
+		if viperLoaded {
+			zedcloudDist = utils.ResolveAbsPath(viper.GetString("zedcloud.dist"))
+			zedcloudPort = viper.GetInt("zedcloud.port")
+			zedcloudPidFile = utils.ResolveAbsPath(viper.GetString("zedcloud.pid"))
+			zedcloudLogFile = utils.ResolveAbsPath(viper.GetString("zedcloud.log"))
+			certsDir = utils.ResolveAbsPath(viper.GetString("eden.certs-dist"))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		command, err := os.Executable()
+		if err != nil {
+			log.Fatalf("cannot obtain executable path: %s", err)
+		}
+		log.Infof("Executable path: %s", command)
+		if err := utils.StartZedcloud(command, zedcloudPort, zedcloudDist, certsDir, zedcloudLogFile, zedcloudPidFile); err != nil {
+			log.Errorf("cannot start zedcloud stand-in: %s", err)
+		} else {
+			log.Infof("Zedcloud stand-in is running and accessible on port %d", zedcloudPort)
+		}
+	},
+}
+
+var stopZedcloudCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "stop stand-in",
+	Long:  `Stop stand-in.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		viperLoaded, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading config: %s", err.Error())
+		}
+		if viperLoaded {
+			zedcloudPidFile = utils.ResolveAbsPath(viper.GetString("zedcloud.pid"))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := utils.StopZedcloud(zedcloudPidFile); err != nil {
+			log.Errorf("cannot stop zedcloud stand-in: %s", err)
+		}
+	},
+}
+
+var statusZedcloudCmd = &cobra.Command{
+	Use:   "status",
+	Short: "status of stand-in",
+	Long:  `Status of stand-in.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		assingCobraToViper(cmd)
+		viperLoaded, err := utils.LoadConfigFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error reading config: %s", err.Error())
+		}
+		if viperLoaded {
+			zedcloudPidFile = utils.ResolveAbsPath(viper.GetString("zedcloud.pid"))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		statusZedcloud, err := utils.StatusZedcloud(zedcloudPidFile)
+		if err != nil {
+			log.Errorf("cannot obtain status of zedcloud stand-in: %s", err)
+		} else {
+			fmt.Printf("Zedcloud stand-in status: %s\n", statusZedcloud)
+		}
+	},
+}
+
+func zedcloudInit() {
+	zedcloudCmd.AddCommand(serveZedcloudCmd)
+	zedcloudCmd.AddCommand(startZedcloudCmd)
+	zedcloudCmd.AddCommand(stopZedcloudCmd)
+	zedcloudCmd.AddCommand(statusZedcloudCmd)
+	currentPath, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, cmd := range []*cobra.Command{serveZedcloudCmd, startZedcloudCmd} {
+		cmd.Flags().StringVarP(&zedcloudDist, "zedcloud-dist", "", filepath.Join(currentPath, defaults.DefaultDist, defaults.DefaultZedcloudDist), "directory for storage of stand-in")
+		cmd.Flags().IntVarP(&zedcloudPort, "zedcloud-port", "", defaults.DefaultZedcloudPort, "port to serve on")
+		cmd.Flags().StringVarP(&certsDir, "certs-dist", "", filepath.Join(currentPath, defaults.DefaultDist, defaults.DefaultCertsDist), "directory with server certs")
+	}
+	startZedcloudCmd.Flags().StringVarP(&zedcloudPidFile, "zedcloud-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "zedcloud.pid"), "file for save stand-in pid")
+	startZedcloudCmd.Flags().StringVarP(&zedcloudLogFile, "zedcloud-log", "", filepath.Join(currentPath, defaults.DefaultDist, "zedcloud.log"), "file for save stand-in log")
+	stopZedcloudCmd.Flags().StringVarP(&zedcloudPidFile, "zedcloud-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "zedcloud.pid"), "file for save stand-in pid")
+	statusZedcloudCmd.Flags().StringVarP(&zedcloudPidFile, "zedcloud-pid", "", filepath.Join(currentPath, defaults.DefaultDist, "zedcloud.pid"), "file for save stand-in pid")
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"github.com/lf-edge/adam/pkg/server"
 	"github.com/lf-edge/eden/pkg/controller/cachers"
+	"github.com/lf-edge/eden/pkg/controller/eflowlog"
 	"github.com/lf-edge/eden/pkg/controller/einfo"
 	"github.com/lf-edge/eden/pkg/controller/elog"
+	"github.com/lf-edge/eden/pkg/controller/emetric"
 	"github.com/lf-edge/eden/pkg/controller/loaders"
 	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eden/pkg/utils"
@@ -32,10 +34,12 @@ type Ctx struct {
 	AdamCaching       bool   //enable caching of adam`s logs/info
 	AdamCachingRedis  bool   //caching to redis instead of files
 	AdamCachingPrefix string //custom prefix for file or stream naming for cache
+
+	AdamCachingLimits cachers.Retention //retention of cache for every device and type
 }
 
-//parseRedisUrl try to use string from config to obtain redis url
-func parseRedisUrl(s string) (addr, password string, databaseID int, err error) {
+//ParseRedisURL try to use string from config to obtain redis url
+func ParseRedisURL(s string) (addr, password string, databaseID int, err error) {
 	URL, err := url.Parse(s)
 	if err != nil || URL.Scheme != "redis" {
 		return "", "", 0, err
@@ -62,34 +66,40 @@ func (adam *Ctx) getLoader() (loader loaders.Loader) {
 	if adam.AdamRemote {
 		log.Info("will use remote adam loader")
 		if adam.AdamRemoteRedis {
-			addr, password, databaseID, err := parseRedisUrl(adam.AdamRedisUrlEden)
+			addr, password, databaseID, err := ParseRedisURL(adam.AdamRedisUrlEden)
 			if err != nil {
 				log.Fatalf("Cannot parse adam redis url: %s", err)
 			}
-			loader = loaders.RedisLoader(addr, password, databaseID, adam.getLogsRedisStream, adam.getInfoRedisStream)
+			loader = loaders.RedisLoader(addr, password, databaseID, adam.getLogsRedisStream, adam.getInfoRedisStream, adam.getMetricsRedisStream, adam.getFlowLogRedisStream)
 		} else {
-			loader = loaders.RemoteLoader(adam.getHTTPClient, adam.getLogsUrl, adam.getInfoUrl)
+			loader = loaders.RemoteLoader(adam.getHTTPClient, adam.getLogsUrl, adam.getInfoUrl, adam.getMetricsUrl, adam.getFlowLogUrl)
 		}
 	} else {
 		log.Info("will use local adam loader")
-		loader = loaders.FileLoader(adam.getLogsDir, adam.getInfoDir)
+		loader = loaders.FileLoader(adam.getLogsDir, adam.getInfoDir, adam.getMetricsDir, adam.getFlowLogDir)
 	}
 	if adam.AdamCaching {
-		var cache cachers.Cacher
-		if adam.AdamCachingRedis {
-			addr, password, databaseID, err := parseRedisUrl(adam.AdamRedisUrlEden)
-			if err != nil {
-				log.Fatalf("Cannot parse adam redis url: %s", err)
-			}
-			cache = cachers.RedisCache(addr, password, databaseID, adam.getLogsRedisStreamCache, adam.getInfoRedisStreamCache)
-		} else {
-			cache = cachers.FileCache(adam.getLogsDirCache, adam.getInfoDirCache)
-		}
-		loader.SetRemoteCache(cache)
+		loader.SetRemoteCache(adam.getCache())
 	}
 	return
 }
 
+//getCache return cache object with retention from Adam`s config
+func (adam *Ctx) getCache() cachers.Pruner {
+	var cache cachers.Pruner
+	if adam.AdamCachingRedis {
+		addr, password, databaseID, err := ParseRedisURL(adam.AdamRedisUrlEden)
+		if err != nil {
+			log.Fatalf("Cannot parse adam redis url: %s", err)
+		}
+		cache = cachers.RedisCache(addr, password, databaseID, adam.getLogsRedisStreamCache, adam.getInfoRedisStreamCache, adam.getMetricsRedisStreamCache, adam.getFlowLogRedisStreamCache)
+	} else {
+		cache = cachers.FileCache(adam.getLogsDirCache, adam.getInfoDirCache, adam.getMetricsDirCache, adam.getFlowLogDirCache)
+	}
+	cache.SetRetention(adam.AdamCachingLimits)
+	return cache
+}
+
 //EnvRead use variables from viper for init controller
 func (adam *Ctx) InitWithVars(vars *utils.ConfigVars) error {
 	adam.dir = vars.AdamDir
@@ -97,10 +107,11 @@ func (adam *Ctx) InitWithVars(vars *utils.ConfigVars) error {
 	adam.insecureTLS = len(vars.AdamCA) == 0
 	adam.serverCA = vars.AdamCA
 	adam.AdamRemote = vars.AdamRemote
-	adam.AdamRemoteRedis = vars.AdamRemoteRedis
+	adam.AdamRemoteRedis = vars.AdamRemoteRedis && !vars.AdamEmbedded //embedded adam uses file storage only
 	adam.AdamCaching = vars.AdamCaching
 	adam.AdamCachingRedis = vars.AdamCachingRedis
 	adam.AdamCachingPrefix = vars.AdamCachingPrefix
+	adam.AdamCachingLimits = cachers.Retention{MaxAge: vars.AdamCachingAge, MaxEntries: vars.AdamCachingLen, MaxBytes: vars.AdamCachingSize}
 	adam.AdamRedisUrlEden = vars.AdamRedisUrlEden
 	return nil
 }
@@ -120,6 +131,16 @@ func (adam *Ctx) getInfoRedisStream(devUUID uuid.UUID) (dir string) {
 	return fmt.Sprintf("%s%s", defaults.DefaultInfoRedisPrefix, devUUID.String())
 }
 
+//getMetricsRedisStream return metrics stream for devUUID for load from redis
+func (adam *Ctx) getMetricsRedisStream(devUUID uuid.UUID) (dir string) {
+	return fmt.Sprintf("%s%s", defaults.DefaultMetricsRedisPrefix, devUUID.String())
+}
+
+//getFlowLogRedisStream return flow log stream for devUUID for load from redis
+func (adam *Ctx) getFlowLogRedisStream(devUUID uuid.UUID) (dir string) {
+	return fmt.Sprintf("%s%s", defaults.DefaultFlowLogRedisPrefix, devUUID.String())
+}
+
 //getLogsRedisStreamCache return logs stream for devUUID for caching in redis
 func (adam *Ctx) getLogsRedisStreamCache(devUUID uuid.UUID) (dir string) {
 	if adam.AdamCachingPrefix == "" {
@@ -136,6 +157,22 @@ func (adam *Ctx) getInfoRedisStreamCache(devUUID uuid.UUID) (dir string) {
 	return fmt.Sprintf("INFO_EVE_%s_%s", adam.AdamCachingPrefix, devUUID.String())
 }
 
+//getMetricsRedisStreamCache return metrics stream for devUUID for caching in redis
+func (adam *Ctx) getMetricsRedisStreamCache(devUUID uuid.UUID) (dir string) {
+	if adam.AdamCachingPrefix == "" {
+		return adam.getMetricsRedisStream(devUUID)
+	}
+	return fmt.Sprintf("METRICS_EVE_%s_%s", adam.AdamCachingPrefix, devUUID.String())
+}
+
+//getFlowLogRedisStreamCache return flow log stream for devUUID for caching in redis
+func (adam *Ctx) getFlowLogRedisStreamCache(devUUID uuid.UUID) (dir string) {
+	if adam.AdamCachingPrefix == "" {
+		return adam.getFlowLogRedisStream(devUUID)
+	}
+	return fmt.Sprintf("FLOW_MESSAGE_EVE_%s_%s", adam.AdamCachingPrefix, devUUID.String())
+}
+
 //getRedisStreamCache return logs stream for devUUID for caching in redis
 func (adam *Ctx) getLogsDirCache(devUUID uuid.UUID) (dir string) {
 	if adam.AdamCachingPrefix == "" {
@@ -152,6 +189,22 @@ func (adam *Ctx) getInfoDirCache(devUUID uuid.UUID) (dir string) {
 	return path.Join(adam.dir, adam.AdamCachingPrefix, devUUID.String(), "info")
 }
 
+//getMetricsDirCache return metrics directory for devUUID for caching
+func (adam *Ctx) getMetricsDirCache(devUUID uuid.UUID) (dir string) {
+	if adam.AdamCachingPrefix == "" {
+		return adam.getMetricsDir(devUUID)
+	}
+	return path.Join(adam.dir, adam.AdamCachingPrefix, devUUID.String(), "metrics")
+}
+
+//getFlowLogDirCache return flow log directory for devUUID for caching
+func (adam *Ctx) getFlowLogDirCache(devUUID uuid.UUID) (dir string) {
+	if adam.AdamCachingPrefix == "" {
+		return adam.getFlowLogDir(devUUID)
+	}
+	return path.Join(adam.dir, adam.AdamCachingPrefix, devUUID.String(), "flowlog")
+}
+
 //getLogsDir return logs directory for devUUID
 func (adam *Ctx) getLogsDir(devUUID uuid.UUID) (dir string) {
 	return path.Join(adam.dir, "run", "adam", "device", devUUID.String(), "logs")
@@ -162,6 +215,16 @@ func (adam *Ctx) getInfoDir(devUUID uuid.UUID) (dir string) {
 	return path.Join(adam.dir, "run", "adam", "device", devUUID.String(), "info")
 }
 
+//getMetricsDir return metrics directory for devUUID
+func (adam *Ctx) getMetricsDir(devUUID uuid.UUID) (dir string) {
+	return path.Join(adam.dir, "run", "adam", "device", devUUID.String(), "metrics")
+}
+
+//getFlowLogDir return flow log directory for devUUID
+func (adam *Ctx) getFlowLogDir(devUUID uuid.UUID) (dir string) {
+	return path.Join(adam.dir, "run", "adam", "device", devUUID.String(), "flowlog")
+}
+
 //getLogsUrl return logs url for devUUID
 func (adam *Ctx) getLogsUrl(devUUID uuid.UUID) string {
 	resUrl, err := utils.ResolveURL(adam.url, path.Join("/admin/device", devUUID.String(), "logs"))
@@ -180,6 +243,24 @@ func (adam *Ctx) getInfoUrl(devUUID uuid.UUID) string {
 	return resUrl
 }
 
+//getMetricsUrl return metrics url for devUUID
+func (adam *Ctx) getMetricsUrl(devUUID uuid.UUID) string {
+	resUrl, err := utils.ResolveURL(adam.url, path.Join("/admin/device", devUUID.String(), "metrics"))
+	if err != nil {
+		log.Fatalf("ResolveURL: %s", err)
+	}
+	return resUrl
+}
+
+//getFlowLogUrl return flow log url for devUUID
+func (adam *Ctx) getFlowLogUrl(devUUID uuid.UUID) string {
+	resUrl, err := utils.ResolveURL(adam.url, path.Join("/admin/device", devUUID.String(), "flowlog"))
+	if err != nil {
+		log.Fatalf("ResolveURL: %s", err)
+	}
+	return resUrl
+}
+
 //Register device in adam
 func (adam *Ctx) Register(eveCert string, eveSerial string) error {
 	b, err := ioutil.ReadFile(eveCert)
@@ -214,6 +295,19 @@ func (adam *Ctx) DeviceList() (out []string, err error) {
 	return adam.getList("/admin/device")
 }
 
+//DeviceGetSerial return serial used by device for onboarding
+func (adam *Ctx) DeviceGetSerial(devUUID uuid.UUID) (serial string, err error) {
+	out, err := adam.getObj(path.Join("/admin/device", devUUID.String()))
+	if err != nil {
+		return "", err
+	}
+	var deviceCert server.DeviceCert
+	if err = json.Unmarshal([]byte(out), &deviceCert); err != nil {
+		return "", fmt.Errorf("cannot parse device %s: %s", devUUID, err)
+	}
+	return deviceCert.Serial, nil
+}
+
 //ConfigSet set config for devID
 func (adam *Ctx) ConfigSet(devUUID uuid.UUID, devConfig []byte) (err error) {
 	return adam.putObj(path.Join("/admin/device", devUUID.String(), "config"), devConfig)
@@ -236,6 +330,29 @@ func (adam *Ctx) LogLastCallback(devUUID uuid.UUID, q map[string]string, handler
 	return elog.LogLast(loader, q, handler)
 }
 
+//LogBundleLastCallback process log bundles from existing files with range from query with callback
+func (adam *Ctx) LogBundleLastCallback(devUUID uuid.UUID, q map[string]string, handler elog.BundleHandlerFunc) (err error) {
+	var loader = adam.getLoader()
+	loader.SetUUID(devUUID)
+	return elog.LogBundleLast(loader, q, handler)
+}
+
+//CacheStats return statistics of cache of logs and info of device for every type
+func (adam *Ctx) CacheStats(devUUID uuid.UUID) (stats []cachers.Stats, err error) {
+	if !adam.AdamCaching {
+		return nil, fmt.Errorf("caching is disabled, set adam.caching.enabled in config")
+	}
+	return adam.getCache().Stats(devUUID)
+}
+
+//CachePrune remove objects of device out of retention from cache and return statistics of removed objects for every type
+func (adam *Ctx) CachePrune(devUUID uuid.UUID, r cachers.Retention) (removed []cachers.Stats, err error) {
+	if !adam.AdamCaching {
+		return nil, fmt.Errorf("caching is disabled, set adam.caching.enabled in config")
+	}
+	return adam.getCache().Prune(devUUID, r)
+}
+
 //InfoChecker checks the information in the regular expression pattern 'query' and processes the info.ZInfoMsg found by the function 'handler' from existing files (mode=einfo.InfoExist), new files (mode=einfo.InfoNew) or any of them (mode=einfo.InfoAny) with timeout.
 func (adam *Ctx) InfoChecker(devUUID uuid.UUID, q map[string]string, infoType einfo.ZInfoType, handler einfo.HandlerFunc, mode einfo.InfoCheckerMode, timeout time.Duration) (err error) {
 	return einfo.InfoChecker(adam.getLoader(), devUUID, q, infoType, handler, mode, timeout)
@@ -247,3 +364,27 @@ func (adam *Ctx) InfoLastCallback(devUUID uuid.UUID, q map[string]string, infoTy
 	loader.SetUUID(devUUID)
 	return einfo.InfoLast(loader, q, einfo.ZInfoFind, handler, infoType)
 }
+
+//MetricChecker checks the metrics in the regular expression pattern 'query' and processes the metrics.ZMetricMsg found by the function 'handler' from existing files (mode=emetric.MetricExist), new files (mode=emetric.MetricNew) or any of them (mode=emetric.MetricAny) with timeout.
+func (adam *Ctx) MetricChecker(devUUID uuid.UUID, q map[string]string, metricType emetric.ZMetricType, handler emetric.HandlerFunc, mode emetric.MetricCheckerMode, timeout time.Duration) (err error) {
+	return emetric.MetricChecker(adam.getLoader(), devUUID, q, metricType, handler, mode, timeout)
+}
+
+//MetricLastCallback check metrics by pattern from existence files with callback
+func (adam *Ctx) MetricLastCallback(devUUID uuid.UUID, q map[string]string, metricType emetric.ZMetricType, handler emetric.HandlerFunc) (err error) {
+	var loader = adam.getLoader()
+	loader.SetUUID(devUUID)
+	return emetric.MetricLast(loader, q, emetric.ZMetricFind, handler, metricType)
+}
+
+//FlowLogChecker checks the flow logs in the regular expression pattern 'query' and processes the flowlog.FlowMessage found by the function 'handler' from existing files (mode=eflowlog.FlowLogExist), new files (mode=eflowlog.FlowLogNew) or any of them (mode=eflowlog.FlowLogAny) with timeout.
+func (adam *Ctx) FlowLogChecker(devUUID uuid.UUID, q map[string]string, flowLogType eflowlog.FlowLogType, handler eflowlog.HandlerFunc, mode eflowlog.FlowLogCheckerMode, timeout time.Duration) (err error) {
+	return eflowlog.FlowLogChecker(adam.getLoader(), devUUID, q, flowLogType, handler, mode, timeout)
+}
+
+//FlowLogLastCallback check flow logs by pattern from existence files with callback
+func (adam *Ctx) FlowLogLastCallback(devUUID uuid.UUID, q map[string]string, flowLogType eflowlog.FlowLogType, handler eflowlog.HandlerFunc) (err error) {
+	var loader = adam.getLoader()
+	loader.SetUUID(devUUID)
+	return eflowlog.FlowLogLast(loader, q, eflowlog.FlowLogFind, handler, flowLogType)
+}
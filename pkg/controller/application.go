@@ -36,6 +36,6 @@ func (cloud *CloudCtx) RemoveApplicationInstanceConfig(id string) error {
 	if err != nil {
 		return err
 	}
-	utils.DelEleInSlice(cloud.applicationInstances, applicationInstanceConfigInd)
+	utils.DelEleInSlice(&cloud.applicationInstances, applicationInstanceConfigInd)
 	return nil
 }
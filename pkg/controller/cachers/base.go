@@ -1,7 +1,16 @@
 package cachers
 
 import (
+	"bytes"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/lf-edge/eve/api/go/flowlog"
+	"github.com/lf-edge/eve/api/go/info"
+	"github.com/lf-edge/eve/api/go/logs"
+	"github.com/lf-edge/eve/api/go/metrics"
 	uuid "github.com/satori/go.uuid"
+	"time"
 )
 
 type Cacher interface {
@@ -15,3 +24,70 @@ var LogsType infoOrLogs = 1
 
 //InfoType for observe info
 var InfoType infoOrLogs = 2
+
+//MetricsType for observe metrics
+var MetricsType infoOrLogs = 3
+
+//FlowLogType for observe flow logs
+var FlowLogType infoOrLogs = 4
+
+//flowMessageTimestamp return the latest start time of flows or time of dns requests
+//FlowMessage has no own timestamp, so we use it to identify message
+func flowMessageTimestamp(fm *flowlog.FlowMessage) (ts *timestamp.Timestamp) {
+	later := func(t *timestamp.Timestamp) {
+		if t == nil {
+			return
+		}
+		if ts == nil || t.GetSeconds() > ts.GetSeconds() || (t.GetSeconds() == ts.GetSeconds() && t.GetNanos() > ts.GetNanos()) {
+			ts = t
+		}
+	}
+	for _, flow := range fm.GetFlows() {
+		later(flow.GetStartTime())
+	}
+	for _, dns := range fm.GetDnsReqs() {
+		later(dns.GetRequestTime())
+	}
+	return ts
+}
+
+//objectTimestamp return timestamp of object of typeToProcess which identifies it inside of cache
+func objectTimestamp(typeToProcess int, data []byte) (*timestamp.Timestamp, error) {
+	buf := bytes.NewBuffer(data)
+	switch typeToProcess {
+	case int(LogsType):
+		var emp logs.LogBundle
+		if err := jsonpb.Unmarshal(buf, &emp); err != nil {
+			return nil, err
+		}
+		return emp.Timestamp, nil
+	case int(InfoType):
+		var emp info.ZInfoMsg
+		if err := jsonpb.Unmarshal(buf, &emp); err != nil {
+			return nil, err
+		}
+		return emp.AtTimeStamp, nil
+	case int(MetricsType):
+		var emp metrics.ZMetricMsg
+		if err := jsonpb.Unmarshal(buf, &emp); err != nil {
+			return nil, err
+		}
+		return emp.AtTimeStamp, nil
+	case int(FlowLogType):
+		var emp flowlog.FlowMessage
+		if err := jsonpb.Unmarshal(buf, &emp); err != nil {
+			return nil, err
+		}
+		return flowMessageTimestamp(&emp), nil
+	default:
+		return nil, fmt.Errorf("not implemented type %d", typeToProcess)
+	}
+}
+
+//timestampTime return time from timestamp or zero time for nil
+func timestampTime(ts *timestamp.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return time.Unix(ts.GetSeconds(), int64(ts.GetNanos())).UTC()
+}
@@ -1,54 +1,63 @@
 package cachers
 
 import (
-	"bytes"
 	"fmt"
-	"github.com/golang/protobuf/jsonpb"
-	"github.com/golang/protobuf/ptypes/timestamp"
-	"github.com/lf-edge/eve/api/go/info"
-	"github.com/lf-edge/eve/api/go/logs"
 	uuid "github.com/satori/go.uuid"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type getDir = func(devUUID uuid.UUID) (dir string)
 
 type fileCache struct {
-	dirLogs getDir
-	dirInfo getDir
+	dirLogs    getDir
+	dirInfo    getDir
+	dirMetrics getDir
+	dirFlowLog getDir
+	retention  Retention
+	pruned     pruneTimes
+	pruneMu    sync.Mutex //guards pruned and prune, CheckAndSave runs concurrently for loaders sharing cache
 }
 
-func FileCache(dirLogs getDir, dirInfo getDir) *fileCache {
+func FileCache(dirLogs getDir, dirInfo getDir, dirMetrics getDir, dirFlowLog getDir) *fileCache {
 	return &fileCache{
-		dirLogs: dirLogs,
-		dirInfo: dirInfo,
+		dirLogs:    dirLogs,
+		dirInfo:    dirInfo,
+		dirMetrics: dirMetrics,
+		dirFlowLog: dirFlowLog,
+		pruned:     make(pruneTimes),
 	}
 }
 
-func (cacher *fileCache) CheckAndSave(devUUID uuid.UUID, typeToProcess int, data []byte) error {
-	var pathToCheck string
-	var itemTimeStamp *timestamp.Timestamp
-	var buf bytes.Buffer
-	buf.Write(data)
+func (cacher *fileCache) getDir(devUUID uuid.UUID, typeToProcess int) (string, error) {
 	switch typeToProcess {
 	case int(LogsType):
-		pathToCheck = cacher.dirLogs(devUUID)
-		var emp logs.LogBundle
-		if err := jsonpb.Unmarshal(&buf, &emp); err != nil {
-			return err
-		}
-		itemTimeStamp = emp.Timestamp
+		return cacher.dirLogs(devUUID), nil
 	case int(InfoType):
-		pathToCheck = cacher.dirInfo(devUUID)
-		var emp info.ZInfoMsg
-		if err := jsonpb.Unmarshal(&buf, &emp); err != nil {
-			return err
-		}
-		itemTimeStamp = emp.AtTimeStamp
+		return cacher.dirInfo(devUUID), nil
+	case int(MetricsType):
+		return cacher.dirMetrics(devUUID), nil
+	case int(FlowLogType):
+		return cacher.dirFlowLog(devUUID), nil
 	default:
-		return fmt.Errorf("not implemented type %d", typeToProcess)
+		return "", fmt.Errorf("not implemented type %d", typeToProcess)
+	}
+}
+
+func (cacher *fileCache) CheckAndSave(devUUID uuid.UUID, typeToProcess int, data []byte) error {
+	pathToCheck, err := cacher.getDir(devUUID, typeToProcess)
+	if err != nil {
+		return err
+	}
+	itemTimeStamp, err := objectTimestamp(typeToProcess, data)
+	if err != nil {
+		return err
 	}
 	if itemTimeStamp == nil {
 		return fmt.Errorf("nil timestamp for data: %s", string(data))
@@ -58,7 +67,127 @@ func (cacher *fileCache) CheckAndSave(devUUID uuid.UUID, typeToProcess int, data
 		return err
 	}
 	if _, err := os.Stat(pathToCheck); os.IsNotExist(err) {
-		return ioutil.WriteFile(pathToCheck, data, 0755)
+		if err = ioutil.WriteFile(pathToCheck, data, 0755); err != nil {
+			return err
+		}
+	}
+	if cacher.retention.IsEmpty() {
+		return nil
+	}
+	cacher.pruneMu.Lock()
+	defer cacher.pruneMu.Unlock()
+	if now := time.Now(); cacher.pruned.due(devUUID, typeToProcess, now) {
+		if _, err = cacher.prune(devUUID, infoOrLogs(typeToProcess), cacher.retention, now); err != nil {
+			return fmt.Errorf("prune: %s", err)
+		}
 	}
 	return nil
 }
+
+//SetRetention set retention enforced by CheckAndSave
+func (cacher *fileCache) SetRetention(r Retention) {
+	cacher.retention = r
+}
+
+//fileCacheTime return time of object from name of file of cache
+func fileCacheTime(name string) time.Time {
+	parts := strings.SplitN(name, ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}
+	}
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	nsec, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(sec, nsec).UTC()
+}
+
+//fileTime return time of object from name of file of cache or modification time of file with other name
+func fileTime(file os.FileInfo) time.Time {
+	if t := fileCacheTime(file.Name()); !t.IsZero() {
+		return t
+	}
+	return file.ModTime()
+}
+
+//readDir return files of cache of device and type from newest to oldest
+func (cacher *fileCache) readDir(devUUID uuid.UUID, typeToProcess infoOrLogs) (dir string, files []os.FileInfo, err error) {
+	if dir, err = cacher.getDir(devUUID, int(typeToProcess)); err != nil {
+		return "", nil, err
+	}
+	if files, err = ioutil.ReadDir(dir); err != nil {
+		if os.IsNotExist(err) {
+			return dir, nil, nil
+		}
+		return "", nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return fileTime(files[i]).After(fileTime(files[j]))
+	})
+	return dir, files, nil
+}
+
+func (cacher *fileCache) prune(devUUID uuid.UUID, typeToProcess infoOrLogs, r Retention, now time.Time) (removed Stats, err error) {
+	removed.Type = typeName(typeToProcess)
+	dir, files, err := cacher.readDir(devUUID, typeToProcess)
+	if err != nil {
+		return removed, err
+	}
+	var total int64
+	kept := 0
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		t := fileTime(file)
+		total += file.Size()
+		if r.keep(now, t, kept, total) {
+			kept++
+			continue
+		}
+		if err = os.Remove(filepath.Join(dir, file.Name())); err != nil {
+			return removed, err
+		}
+		removed.add(t, file.Size())
+	}
+	return removed, nil
+}
+
+//Stats return statistics of objects of device for every type
+func (cacher *fileCache) Stats(devUUID uuid.UUID) ([]Stats, error) {
+	var result []Stats
+	for _, typeToProcess := range cacheTypes {
+		stats := Stats{Type: typeName(typeToProcess)}
+		_, files, err := cacher.readDir(devUUID, typeToProcess)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if !file.IsDir() {
+				stats.add(fileTime(file), file.Size())
+			}
+		}
+		result = append(result, stats)
+	}
+	return result, nil
+}
+
+//Prune remove objects of device out of retention r and return statistics of removed objects for every type
+func (cacher *fileCache) Prune(devUUID uuid.UUID, r Retention) ([]Stats, error) {
+	var result []Stats
+	cacher.pruneMu.Lock()
+	defer cacher.pruneMu.Unlock()
+	now := time.Now()
+	for _, typeToProcess := range cacheTypes {
+		removed, err := cacher.prune(devUUID, typeToProcess, r, now)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, removed)
+	}
+	return result, nil
+}
@@ -1,35 +1,42 @@
 package cachers
 
 import (
-	"bytes"
 	"fmt"
 	"github.com/go-redis/redis/v7"
-	"github.com/golang/protobuf/jsonpb"
-	"github.com/golang/protobuf/ptypes/timestamp"
-	"github.com/lf-edge/eve/api/go/info"
-	"github.com/lf-edge/eve/api/go/logs"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type getStream = func(devUUID uuid.UUID) (stream string)
 
 type redisCache struct {
-	addr       string
-	password   string
-	databaseID int
-	streamLogs getStream
-	streamInfo getStream
-	client     *redis.Client
+	addr          string
+	password      string
+	databaseID    int
+	streamLogs    getStream
+	streamInfo    getStream
+	streamMetrics getStream
+	streamFlowLog getStream
+	client        *redis.Client
+	retention     Retention
+	pruned        pruneTimes
+	pruneMu       sync.Mutex //guards pruned and prune
 }
 
-func RedisCache(addr string, password string, databaseID int, dirLogs getDir, dirInfo getDir) *redisCache {
+func RedisCache(addr string, password string, databaseID int, dirLogs getDir, dirInfo getDir, dirMetrics getDir, dirFlowLog getDir) *redisCache {
 	return &redisCache{
-		addr:       addr,
-		password:   password,
-		databaseID: databaseID,
-		streamLogs: dirLogs,
-		streamInfo: dirInfo,
+		addr:          addr,
+		password:      password,
+		databaseID:    databaseID,
+		streamLogs:    dirLogs,
+		streamInfo:    dirInfo,
+		streamMetrics: dirMetrics,
+		streamFlowLog: dirFlowLog,
+		pruned:        make(pruneTimes),
 	}
 }
 
@@ -43,76 +50,197 @@ func (cacher *redisCache) newRedisClient() (*redis.Client, error) {
 	return client, err
 }
 
-func (cacher *redisCache) CheckAndSave(devUUID uuid.UUID, typeToProcess int, data []byte) (err error) {
+func (cacher *redisCache) getClient() (client *redis.Client, err error) {
 	if cacher.client == nil {
 		if cacher.client, err = cacher.newRedisClient(); err != nil {
-			return err
+			cacher.client = nil
+			return nil, err
 		}
 	}
+	return cacher.client, nil
+}
 
-	var streamToWrite string
-	var itemTimeStamp *timestamp.Timestamp
-	var buf bytes.Buffer
-	buf.Write(data)
+func (cacher *redisCache) getStream(devUUID uuid.UUID, typeToProcess int) (string, error) {
 	switch typeToProcess {
 	case int(LogsType):
-		streamToWrite = cacher.streamLogs(devUUID)
-		var emp logs.LogBundle
-		if err := jsonpb.Unmarshal(&buf, &emp); err != nil {
-			return err
-		}
-		itemTimeStamp = emp.Timestamp
+		return cacher.streamLogs(devUUID), nil
 	case int(InfoType):
-		streamToWrite = cacher.streamInfo(devUUID)
-		var emp info.ZInfoMsg
-		if err := jsonpb.Unmarshal(&buf, &emp); err != nil {
-			return err
-		}
-		itemTimeStamp = emp.AtTimeStamp
+		return cacher.streamInfo(devUUID), nil
+	case int(MetricsType):
+		return cacher.streamMetrics(devUUID), nil
+	case int(FlowLogType):
+		return cacher.streamFlowLog(devUUID), nil
 	default:
-		return fmt.Errorf("not implemented type %d", typeToProcess)
+		return "", fmt.Errorf("not implemented type %d", typeToProcess)
+	}
+}
+
+func (cacher *redisCache) CheckAndSave(devUUID uuid.UUID, typeToProcess int, data []byte) (err error) {
+	if _, err = cacher.getClient(); err != nil {
+		return err
+	}
+	streamToWrite, err := cacher.getStream(devUUID, typeToProcess)
+	if err != nil {
+		return err
+	}
+	itemTimeStamp, err := objectTimestamp(typeToProcess, data)
+	if err != nil {
+		return err
 	}
 	rr, err := cacher.client.XRange(streamToWrite, "-", "+").Result()
 	if err != nil {
 		return err
 	}
 	for _, r := range rr {
-		switch typeToProcess {
-		case int(LogsType):
-			var buf bytes.Buffer
-			buf.Write([]byte(r.Values["object"].(string)))
-			var emp logs.LogBundle
-			if err := jsonpb.Unmarshal(&buf, &emp); err != nil {
-				return err
-			}
-			if emp.Timestamp.GetSeconds() == itemTimeStamp.GetSeconds() && emp.Timestamp.GetNanos() == itemTimeStamp.GetNanos() {
-				return
-			}
-		case int(InfoType):
-			var buf bytes.Buffer
-			buf.Write([]byte(r.Values["object"].(string)))
-			var emp info.ZInfoMsg
-			if err := jsonpb.Unmarshal(&buf, &emp); err != nil {
-				return err
-			}
-			if emp.AtTimeStamp.GetSeconds() == itemTimeStamp.GetSeconds() && emp.AtTimeStamp.GetNanos() == itemTimeStamp.GetNanos() {
-				return
-			}
-		default:
-			return fmt.Errorf("not implemented type %d", typeToProcess)
+		ts, err := objectTimestamp(typeToProcess, []byte(r.Values["object"].(string)))
+		if err != nil {
+			return err
+		}
+		if ts.GetSeconds() == itemTimeStamp.GetSeconds() && ts.GetNanos() == itemTimeStamp.GetNanos() {
+			return nil
 		}
 	}
 
-	strCMD := cacher.client.XAdd(&redis.XAddArgs{
+	args := &redis.XAddArgs{
 		Stream: streamToWrite,
 		Values: map[string]interface{}{
 			"object": data,
 		},
-	})
+	}
+	if cacher.retention.MaxEntries > 0 {
+		args.MaxLenApprox = int64(cacher.retention.MaxEntries)
+	}
+	strCMD := cacher.client.XAdd(args)
 	var key string
 	if key, err = strCMD.Result(); err != nil {
 		return fmt.Errorf("XAdd error:%v\n", err)
 	}
 	log.Debugf("ready with write to redis %s: %s", key, data)
+	if cacher.retention.IsEmpty() {
+		return nil
+	}
+	cacher.pruneMu.Lock()
+	defer cacher.pruneMu.Unlock()
+	if now := time.Now(); cacher.pruned.due(devUUID, typeToProcess, now) {
+		if _, err = cacher.prune(devUUID, infoOrLogs(typeToProcess), cacher.retention, now); err != nil {
+			return fmt.Errorf("prune: %s", err)
+		}
+	}
 	return nil
 }
+
+//SetRetention set retention enforced by CheckAndSave, MaxEntries is also passed as MAXLEN to XADD
+func (cacher *redisCache) SetRetention(r Retention) {
+	cacher.retention = r
+}
+
+//streamIDTime return time of addition of object into stream from its ID
+func streamIDTime(id string) time.Time {
+	ms, err := strconv.ParseInt(strings.Split(id, "-")[0], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
+
+//walk process objects of stream of device and type from newest to oldest with time of object and its size
+func (cacher *redisCache) walk(devUUID uuid.UUID, typeToProcess infoOrLogs, process func(id string, t time.Time, size int64) error) error {
+	if _, err := cacher.getClient(); err != nil {
+		return err
+	}
+	stream, err := cacher.getStream(devUUID, int(typeToProcess))
+	if err != nil {
+		return err
+	}
+	end := "+"
+	for {
+		rr, err := cacher.client.XRevRangeN(stream, end, "-", 100).Result()
+		if err != nil {
+			return fmt.Errorf("XRevRange error: %s", err)
+		}
+		for _, r := range rr {
+			if r.ID == end {
+				continue
+			}
+			object, _ := r.Values["object"].(string)
+			t := streamIDTime(r.ID)
+			if ts, err := objectTimestamp(int(typeToProcess), []byte(object)); err == nil && ts != nil {
+				t = timestampTime(ts)
+			}
+			if err = process(r.ID, t, int64(len(object))); err != nil {
+				return err
+			}
+		}
+		if len(rr) < 100 {
+			return nil
+		}
+		end = rr[len(rr)-1].ID
+	}
+}
+
+func (cacher *redisCache) prune(devUUID uuid.UUID, typeToProcess infoOrLogs, r Retention, now time.Time) (removed Stats, err error) {
+	removed.Type = typeName(typeToProcess)
+	var ids []string
+	var total int64
+	kept := 0
+	err = cacher.walk(devUUID, typeToProcess, func(id string, t time.Time, size int64) error {
+		total += size
+		if r.keep(now, t, kept, total) {
+			kept++
+			return nil
+		}
+		ids = append(ids, id)
+		removed.add(t, size)
+		return nil
+	})
+	if err != nil || len(ids) == 0 {
+		return removed, err
+	}
+	stream, err := cacher.getStream(devUUID, int(typeToProcess))
+	if err != nil {
+		return removed, err
+	}
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > 100 {
+			n = 100
+		}
+		if err = cacher.client.XDel(stream, ids[:n]...).Err(); err != nil {
+			return removed, fmt.Errorf("XDel error: %s", err)
+		}
+		ids = ids[n:]
+	}
+	return removed, nil
+}
+
+//Stats return statistics of objects of device for every type
+func (cacher *redisCache) Stats(devUUID uuid.UUID) ([]Stats, error) {
+	var result []Stats
+	for _, typeToProcess := range cacheTypes {
+		stats := Stats{Type: typeName(typeToProcess)}
+		if err := cacher.walk(devUUID, typeToProcess, func(id string, t time.Time, size int64) error {
+			stats.add(t, size)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		result = append(result, stats)
+	}
+	return result, nil
+}
+
+//Prune remove objects of device out of retention r and return statistics of removed objects for every type
+func (cacher *redisCache) Prune(devUUID uuid.UUID, r Retention) ([]Stats, error) {
+	var result []Stats
+	cacher.pruneMu.Lock()
+	defer cacher.pruneMu.Unlock()
+	now := time.Now()
+	for _, typeToProcess := range cacheTypes {
+		removed, err := cacher.prune(devUUID, typeToProcess, r, now)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, removed)
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package cachers
+
+import (
+	"fmt"
+	uuid "github.com/satori/go.uuid"
+	"time"
+)
+
+//pruneInterval is minimal interval between enforcements of retention inside of CheckAndSave for device and type
+const pruneInterval = time.Minute
+
+//Retention limits objects kept by cache for every device and type, zero fields are not limited
+type Retention struct {
+	MaxAge     time.Duration //objects older than MaxAge are removed
+	MaxEntries int           //only MaxEntries newest objects are kept
+	MaxBytes   int64         //only newest objects with total size up to MaxBytes are kept
+}
+
+//IsEmpty checks if retention does not limit objects
+func (r Retention) IsEmpty() bool {
+	return r.MaxAge == 0 && r.MaxEntries == 0 && r.MaxBytes == 0
+}
+
+//keep checks if object with time t and index (from newest) of size with total size (including it) of newer objects must be kept
+func (r Retention) keep(now time.Time, t time.Time, index int, total int64) bool {
+	if r.MaxAge > 0 && !t.IsZero() && now.Sub(t) > r.MaxAge {
+		return false
+	}
+	if r.MaxEntries > 0 && index >= r.MaxEntries {
+		return false
+	}
+	if r.MaxBytes > 0 && total > r.MaxBytes {
+		return false
+	}
+	return true
+}
+
+//Stats describes objects of cache for device and type
+type Stats struct {
+	Type    string
+	Entries int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+func (s *Stats) add(t time.Time, size int64) {
+	s.Entries++
+	s.Bytes += size
+	if t.IsZero() {
+		return
+	}
+	if s.Oldest.IsZero() || t.Before(s.Oldest) {
+		s.Oldest = t
+	}
+	if s.Newest.IsZero() || t.After(s.Newest) {
+		s.Newest = t
+	}
+}
+
+//Pruner is cache which limits kept objects with retention
+type Pruner interface {
+	Cacher
+	//SetRetention set retention enforced by CheckAndSave
+	SetRetention(r Retention)
+	//Stats return statistics of objects of device for every type
+	Stats(devUUID uuid.UUID) ([]Stats, error)
+	//Prune remove objects of device out of retention r and return statistics of removed objects for every type
+	Prune(devUUID uuid.UUID, r Retention) ([]Stats, error)
+}
+
+//cacheTypes are types of objects kept by caches
+var cacheTypes = []infoOrLogs{LogsType, InfoType, MetricsType, FlowLogType}
+
+//typeName return name of type of objects
+func typeName(typeToProcess infoOrLogs) string {
+	switch typeToProcess {
+	case LogsType:
+		return "logs"
+	case InfoType:
+		return "info"
+	case MetricsType:
+		return "metrics"
+	case FlowLogType:
+		return "flowlog"
+	default:
+		return fmt.Sprintf("type %d", typeToProcess)
+	}
+}
+
+//pruneTimes keeps times of the last enforcement of retention for device and type
+type pruneTimes map[string]time.Time
+
+//due checks if retention must be enforced for device and type now and remembers the time of enforcement
+func (p pruneTimes) due(devUUID uuid.UUID, typeToProcess int, now time.Time) bool {
+	key := fmt.Sprintf("%s:%d", devUUID, typeToProcess)
+	if last, ok := p[key]; ok && now.Sub(last) < pruneInterval {
+		return false
+	}
+	p[key] = now
+	return true
+}
@@ -0,0 +1,130 @@
+package cachers
+
+import (
+	"fmt"
+	uuid "github.com/satori/go.uuid"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testInfo(t time.Time) []byte {
+	return []byte(fmt.Sprintf(`{"devId":"dev","atTimeStamp":"%s"}`, t.Format(time.RFC3339Nano)))
+}
+
+//TestFileCacheRetention test retention of file cache
+func TestFileCacheRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eden-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	getDir := func(name string) getDir {
+		return func(devUUID uuid.UUID) string {
+			return filepath.Join(dir, devUUID.String(), name)
+		}
+	}
+	cache := FileCache(getDir("logs"), getDir("info"), getDir("metrics"), getDir("flowlog"))
+	devUUID, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i := 10; i > 0; i-- {
+		if err = cache.CheckAndSave(devUUID, int(InfoType), testInfo(now.Add(time.Duration(-i)*time.Hour))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err := cache.Stats(devUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != len(cacheTypes) || stats[1].Type != "info" || stats[1].Entries != 10 || stats[0].Entries != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if !stats[1].Oldest.Equal(now.Add(-10*time.Hour)) || !stats[1].Newest.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("unexpected time range of stats: %+v", stats[1])
+	}
+	size := stats[1].Bytes / 10
+
+	removed, err := cache.Prune(devUUID, Retention{MaxAge: 7*time.Hour + time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed[1].Entries != 3 || !removed[1].Newest.Equal(now.Add(-8*time.Hour)) {
+		t.Fatalf("expected removal of 3 objects older than 7 hours: %+v", removed[1])
+	}
+	if removed, err = cache.Prune(devUUID, Retention{MaxEntries: 5}); err != nil || removed[1].Entries != 2 {
+		t.Fatalf("expected removal of 2 objects out of 5 newest: %+v %v", removed, err)
+	}
+	if removed, err = cache.Prune(devUUID, Retention{MaxBytes: 3*size + size/2}); err != nil || removed[1].Entries != 2 {
+		t.Fatalf("expected removal of 2 objects out of size: %+v %v", removed, err)
+	}
+
+	//retention is enforced on saving
+	cache.SetRetention(Retention{MaxEntries: 2})
+	if err = cache.CheckAndSave(devUUID, int(InfoType), testInfo(now)); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err = cache.Stats(devUUID); err != nil || stats[1].Entries != 2 || !stats[1].Newest.Equal(now) {
+		t.Fatalf("expected 2 newest objects in cache: %+v %v", stats, err)
+	}
+}
+
+//TestFileCacheConcurrentSave test enforcement of retention by CheckAndSave called concurrently, run it with -race
+func TestFileCacheConcurrentSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eden-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	getDir := func(name string) getDir {
+		return func(devUUID uuid.UUID) string {
+			return filepath.Join(dir, devUUID.String(), name)
+		}
+	}
+	cache := FileCache(getDir("logs"), getDir("info"), getDir("metrics"), getDir("flowlog"))
+	now := time.Now()
+	var devices []uuid.UUID
+	for d := 0; d < 20; d++ {
+		devUUID, err := uuid.NewV4()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			if err = cache.CheckAndSave(devUUID, int(InfoType), testInfo(now.Add(time.Duration(i)*time.Second))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		devices = append(devices, devUUID)
+	}
+	//newest saved objects are not written again, so only enforcement of retention is done concurrently
+	cache.SetRetention(Retention{MaxEntries: 5})
+	start := make(chan struct{})
+	errs := make(chan error, 2)
+	for g := 0; g < 2; g++ {
+		go func() {
+			<-start
+			for _, devUUID := range devices {
+				if err := cache.CheckAndSave(devUUID, int(InfoType), testInfo(now.Add(9*time.Second))); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+	close(start)
+	for g := 0; g < 2; g++ {
+		if err = <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, devUUID := range devices {
+		if stats, err := cache.Stats(devUUID); err != nil || stats[1].Entries != 5 || !stats[1].Newest.Equal(now.Add(9*time.Second)) {
+			t.Fatalf("expected 5 newest objects in cache: %+v %v", stats, err)
+		}
+	}
+}
@@ -42,9 +42,17 @@ type Cloud interface {
 	AddImage(imageConfig *config.Image) error
 	RemoveImage(id string) error
 	GetConfigBytes(dev *device.Ctx, pretty bool) ([]byte, error)
+	GetConfigValidated(dev *device.Ctx) ([]byte, error)
 	GetDeviceFirst() (dev *device.Ctx, err error)
+	GetDevice(selector string) (dev *device.Ctx, err error)
+	ListDevices() []*device.Ctx
 	ConfigSync(dev *device.Ctx) (err error)
+	ConfigHistory(devUUID uuid.UUID) (snapshots []*ConfigSnapshot, err error)
+	ConfigHistoryGet(devUUID uuid.UUID, version string) (*ConfigSnapshot, error)
+	ConfigRollback(dev *device.Ctx, version string) error
 	ConfigParse(config *config.EdgeDevConfig) (dev *device.Ctx, err error)
+	ManifestApply(dev *device.Ctx, manifest *Manifest) error
+	ManifestDelete(dev *device.Ctx, manifest *Manifest) error
 	GetNetworkConfig(id string) (networkConfig *config.NetworkConfig, err error)
 	AddNetworkConfig(networkInstanceConfig *config.NetworkConfig) error
 	RemoveNetworkConfig(id string) error
@@ -1,8 +1,11 @@
 package controller
 
 import (
+	"github.com/lf-edge/eden/pkg/controller/cachers"
+	"github.com/lf-edge/eden/pkg/controller/eflowlog"
 	"github.com/lf-edge/eden/pkg/controller/einfo"
 	"github.com/lf-edge/eden/pkg/controller/elog"
+	"github.com/lf-edge/eden/pkg/controller/emetric"
 	"github.com/lf-edge/eden/pkg/utils"
 	uuid "github.com/satori/go.uuid"
 	"time"
@@ -14,10 +17,18 @@ type Controller interface {
 	ConfigSet(devUUID uuid.UUID, devConfig []byte) (err error)
 	LogChecker(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc, mode elog.LogCheckerMode, timeout time.Duration) (err error)
 	LogLastCallback(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc) (err error)
+	LogBundleLastCallback(devUUID uuid.UUID, q map[string]string, handler elog.BundleHandlerFunc) (err error)
+	CacheStats(devUUID uuid.UUID) (stats []cachers.Stats, err error)
+	CachePrune(devUUID uuid.UUID, r cachers.Retention) (removed []cachers.Stats, err error)
 	InfoChecker(devUUID uuid.UUID, q map[string]string, infoType einfo.ZInfoType, handler einfo.HandlerFunc, mode einfo.InfoCheckerMode, timeout time.Duration) (err error)
 	InfoLastCallback(devUUID uuid.UUID, q map[string]string, infoType einfo.ZInfoType, handler einfo.HandlerFunc) (err error)
+	MetricChecker(devUUID uuid.UUID, q map[string]string, metricType emetric.ZMetricType, handler emetric.HandlerFunc, mode emetric.MetricCheckerMode, timeout time.Duration) (err error)
+	MetricLastCallback(devUUID uuid.UUID, q map[string]string, metricType emetric.ZMetricType, handler emetric.HandlerFunc) (err error)
+	FlowLogChecker(devUUID uuid.UUID, q map[string]string, flowLogType eflowlog.FlowLogType, handler eflowlog.HandlerFunc, mode eflowlog.FlowLogCheckerMode, timeout time.Duration) (err error)
+	FlowLogLastCallback(devUUID uuid.UUID, q map[string]string, flowLogType eflowlog.FlowLogType, handler eflowlog.HandlerFunc) (err error)
 	OnBoardList() (out []string, err error)
 	DeviceList() (out []string, err error)
+	DeviceGetSerial(devUUID uuid.UUID) (serial string, err error)
 	Register(eveCert string, eveSerial string) error
 	GetDir() (dir string)
 	InitWithVars(vars *utils.ConfigVars) error
@@ -1,10 +1,8 @@
 package controller
 
 import (
-	"fmt"
 	"github.com/lf-edge/eden/pkg/defaults"
 	"github.com/lf-edge/eve/api/go/config"
-	"github.com/lf-edge/eve/api/go/evecommon"
 )
 
 //DevModelType is type of dev model
@@ -31,6 +29,11 @@ func (ctx *DevModel) GetFirstAdapterForSwitches() string {
 	return "uplink"
 }
 
+//GetAdaptersForSwitches return adapters available for switch networkInstance
+func (ctx *DevModel) GetAdaptersForSwitches() []string {
+	return ctx.adapterForSwitches
+}
+
 //GetNetDHCPID return netDHCPID id
 func (ctx *DevModel) GetNetDHCPID() string {
 	return defaults.NetDHCPID
@@ -63,68 +66,12 @@ func (cloud *CloudCtx) GetDevModelByName(devModelType string) (*DevModel, error)
 }
 
 //GetDevModel return DevModel object by DevModelType
+//DevModelType is name of built-in model, name of model in DevModelsDir or path to yaml file of model
 func (cloud *CloudCtx) GetDevModel(devModelType DevModelType) (*DevModel, error) {
-	switch devModelType {
-	case DevModelTypeEmpty:
-		return cloud.CreateDevModel(nil, nil, nil, nil, DevModelTypeEmpty), nil
-	case DevModelTypeQemu:
-		return cloud.CreateDevModel(
-				[]*config.PhysicalIO{{
-					Ptype:        evecommon.PhyIoType_PhyIoNetEth,
-					Phylabel:     "eth0",
-					Logicallabel: "eth0",
-					Assigngrp:    "eth0",
-					Phyaddrs:     map[string]string{"Ifname": "eth0"},
-					Usage:        evecommon.PhyIoMemberUsage_PhyIoUsageMgmtAndApps,
-					UsagePolicy: &config.PhyIOUsagePolicy{
-						FreeUplink: true,
-					},
-				}, {
-					Ptype:        evecommon.PhyIoType_PhyIoNetEth,
-					Phylabel:     "eth1",
-					Logicallabel: "eth1",
-					Assigngrp:    "eth1",
-					Phyaddrs:     map[string]string{"Ifname": "eth1"},
-					Usage:        evecommon.PhyIoMemberUsage_PhyIoUsageShared,
-					UsagePolicy: &config.PhyIOUsagePolicy{
-						FreeUplink: true,
-					},
-				},
-				},
-				[]*config.NetworkConfig{
-					{
-						Id:   defaults.NetDHCPID,
-						Type: config.NetworkType_V4,
-						Ip: &config.Ipspec{
-							Dhcp:      config.DHCPType_Client,
-							DhcpRange: &config.IpRange{},
-						},
-						Wireless: nil,
-					},
-					{
-						Id:   defaults.NetNoDHCPID,
-						Type: config.NetworkType_V4,
-						Ip: &config.Ipspec{
-							Dhcp:      config.DHCPType_DHCPNone,
-							DhcpRange: &config.IpRange{},
-						},
-						Wireless: nil,
-					},
-				},
-				[]*config.SystemAdapter{
-					{
-						Name:        "eth0",
-						Uplink:      true,
-						NetworkUUID: defaults.NetDHCPID,
-					},
-					{
-						Name:        "eth1",
-						NetworkUUID: defaults.NetNoDHCPID,
-					},
-				},
-				[]string{"eth1"},
-				DevModelTypeQemu),
-			nil
+	model, err := FindDevModelFile(string(devModelType))
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("not implemented type: %s", devModelType)
+	devModel := model.DevModel()
+	return cloud.CreateDevModel(devModel.physicalIOs, devModel.networks, devModel.adapters, devModel.adapterForSwitches, devModel.devModelType), nil
 }
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"github.com/lf-edge/eve/api/go/evecommon"
+	"github.com/lf-edge/eve/api/go/info"
+)
+
+const (
+	discoverNetworkDHCP   = "dhcp"
+	discoverNetworkNoDHCP = "nodhcp"
+)
+
+//DevModelDiscover return device model with name built from network interfaces and assignable adapters of device info.
+//Interfaces with IP addresses become adapters with DHCP client network (uplink ones are used for management),
+//ethernet interfaces without addresses become adapters without DHCP and adapters for switch network instances.
+//Wireless interfaces without addresses have no adapters as SSID and APN cannot be discovered.
+func DevModelDiscover(name string, dinfo *info.ZInfoDevice) *DevModelFile {
+	model := &DevModelFile{Name: name}
+	ioTypes := make(map[evecommon.PhyIoType]string)
+	for k, v := range physicalIOTypes {
+		ioTypes[v] = k
+	}
+	ioUsages := make(map[evecommon.PhyIoMemberUsage]string)
+	for k, v := range physicalIOUsages {
+		if k != "" {
+			ioUsages[v] = k
+		}
+	}
+	interfaces := make(map[string]*info.ZInfoNetwork)
+	for _, el := range dinfo.GetNetwork() {
+		for _, ifName := range []string{el.GetDevName(), el.GetLocalName()} {
+			if ifName != "" {
+				interfaces[ifName] = el
+			}
+		}
+	}
+	networks := make(map[string]bool)
+	addIO := func(ioType evecommon.PhyIoType, label, group string, usage evecommon.PhyIoMemberUsage) {
+		physicalIO := &DevModelPhysicalIO{Type: ioTypes[ioType], Phylabel: label, Assigngrp: group}
+		if physicalIO.Type == "" {
+			physicalIO.Type = "other"
+		}
+		if group == label {
+			physicalIO.Assigngrp = ""
+		}
+		isNet := ioType == evecommon.PhyIoType_PhyIoNetEth || ioType == evecommon.PhyIoType_PhyIoNetWLAN || ioType == evecommon.PhyIoType_PhyIoNetWWAN
+		iface, found := interfaces[label]
+		if isNet {
+			ifName := label
+			if found && iface.GetDevName() != "" {
+				ifName = iface.GetDevName()
+			}
+			physicalIO.Phyaddrs = map[string]string{"Ifname": ifName}
+			adapter := &DevModelAdapter{Name: label}
+			switch {
+			case found && iface.GetUplink():
+				adapter.Network, adapter.Uplink = discoverNetworkDHCP, true
+				usage = evecommon.PhyIoMemberUsage_PhyIoUsageMgmtAndApps
+				physicalIO.FreeUplink = true
+			case found && len(iface.GetIPAddrs()) > 0:
+				adapter.Network = discoverNetworkDHCP
+				if usage == evecommon.PhyIoMemberUsage_PhyIoUsageNone {
+					usage = evecommon.PhyIoMemberUsage_PhyIoUsageShared
+				}
+			case ioType == evecommon.PhyIoType_PhyIoNetEth:
+				adapter.Network = discoverNetworkNoDHCP
+				if usage == evecommon.PhyIoMemberUsage_PhyIoUsageNone {
+					usage = evecommon.PhyIoMemberUsage_PhyIoUsageShared
+				}
+				model.AdaptersForSwitches = append(model.AdaptersForSwitches, label)
+			default:
+				adapter = nil
+			}
+			if adapter != nil {
+				networks[adapter.Network] = true
+				model.Adapters = append(model.Adapters, adapter)
+			}
+		}
+		physicalIO.Usage = ioUsages[usage]
+		model.PhysicalIOs = append(model.PhysicalIOs, physicalIO)
+	}
+	if len(dinfo.GetAssignableAdapters()) > 0 {
+		for _, bundle := range dinfo.GetAssignableAdapters() {
+			members := bundle.GetMembers()
+			if len(members) == 0 {
+				members = []string{bundle.GetName()}
+			}
+			for _, member := range members {
+				addIO(bundle.GetType(), member, bundle.GetName(), bundle.GetUsage())
+			}
+		}
+	} else {
+		//older EVE do not report assignable adapters, use network interfaces only
+		for _, el := range dinfo.GetNetwork() {
+			label := el.GetLocalName()
+			if label == "" {
+				label = el.GetDevName()
+			}
+			addIO(evecommon.PhyIoType_PhyIoNetEth, label, label, evecommon.PhyIoMemberUsage_PhyIoUsageNone)
+		}
+	}
+	if networks[discoverNetworkDHCP] {
+		model.Networks = append(model.Networks, &DevModelNetwork{Name: discoverNetworkDHCP, DHCP: "client"})
+	}
+	if networks[discoverNetworkNoDHCP] {
+		model.Networks = append(model.Networks, &DevModelNetwork{Name: discoverNetworkNoDHCP, DHCP: "none"})
+	}
+	return model
+}
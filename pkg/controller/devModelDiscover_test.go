@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"github.com/lf-edge/eve/api/go/evecommon"
+	"github.com/lf-edge/eve/api/go/info"
+	"testing"
+)
+
+//TestDevModelDiscover test device model built from device info
+func TestDevModelDiscover(t *testing.T) {
+	dinfo := &info.ZInfoDevice{
+		Network: []*info.ZInfoNetwork{
+			{DevName: "eth0", LocalName: "eth0", IPAddrs: []string{"192.168.1.10/24"}, Uplink: true, Up: true},
+			{DevName: "eth1", LocalName: "eth1", Up: true},
+			{DevName: "wlan0", LocalName: "wlan0", IPAddrs: []string{"10.0.0.5/24"}},
+		},
+		AssignableAdapters: []*info.ZioBundle{
+			{Type: evecommon.PhyIoType_PhyIoNetEth, Name: "eth0", Members: []string{"eth0"}},
+			{Type: evecommon.PhyIoType_PhyIoNetEth, Name: "eth1", Members: []string{"eth1"}},
+			{Type: evecommon.PhyIoType_PhyIoNetWLAN, Name: "wlan0", Members: []string{"wlan0"}},
+			{Type: evecommon.PhyIoType_PhyIoNetWWAN, Name: "wwan0", Members: []string{"wwan0"}},
+			{Type: evecommon.PhyIoType_PhyIoCOM, Name: "COM", Members: []string{"COM1", "COM2"}},
+		},
+	}
+	model := DevModelDiscover("Board", dinfo)
+	if problems := model.Validate(); len(problems) > 0 {
+		t.Fatalf("discovered model is not valid: %v", problems)
+	}
+	if len(model.PhysicalIOs) != 6 || model.PhysicalIOs[5].Phylabel != "COM2" || model.PhysicalIOs[5].Assigngrp != "COM" {
+		t.Fatalf("wrong physicalIOs: %v", model.PhysicalIOs)
+	}
+	if model.PhysicalIOs[0].Usage != "mgmtAndApps" || model.PhysicalIOs[1].Usage != "shared" {
+		t.Fatalf("wrong usage: %s %s", model.PhysicalIOs[0].Usage, model.PhysicalIOs[1].Usage)
+	}
+	if len(model.Adapters) != 3 || !model.Adapters[0].Uplink || model.Adapters[1].Network != discoverNetworkNoDHCP || model.Adapters[2].Network != discoverNetworkDHCP {
+		t.Fatalf("wrong adapters: %v", model.Adapters)
+	}
+	if model.PhysicalIOs[3].Type != "wwan" || model.PhysicalIOs[3].Usage != "none" {
+		t.Fatalf("wrong wwan physicalIO: %v", model.PhysicalIOs[3])
+	}
+	if len(model.AdaptersForSwitches) != 1 || model.AdaptersForSwitches[0] != "eth1" {
+		t.Fatalf("wrong adapters for switches: %v", model.AdaptersForSwitches)
+	}
+	//without assignable adapters network interfaces are used
+	dinfo.AssignableAdapters = nil
+	model = DevModelDiscover("Board", dinfo)
+	if problems := model.Validate(); len(problems) > 0 || len(model.PhysicalIOs) != 3 || len(model.Networks) != 2 {
+		t.Fatalf("wrong model from network interfaces: %v %v", problems, model)
+	}
+}
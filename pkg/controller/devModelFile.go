@@ -0,0 +1,412 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/lf-edge/eve/api/go/evecommon"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//DevModelFile describes device model in yaml
+type DevModelFile struct {
+	Name                string                `yaml:"name"`
+	PhysicalIOs         []*DevModelPhysicalIO `yaml:"physicalIOs,omitempty"`
+	Networks            []*DevModelNetwork    `yaml:"networks,omitempty"`
+	Adapters            []*DevModelAdapter    `yaml:"adapters,omitempty"`
+	AdaptersForSwitches []string              `yaml:"adaptersForSwitches,omitempty"`
+	//Source is file of model or "built-in"
+	Source string `yaml:"-"`
+}
+
+//DevModelPhysicalIO describes physical IO of device model
+type DevModelPhysicalIO struct {
+	Type             string            `yaml:"type"` //eth, wlan, wwan, usb, com, audio, hdmi or other
+	Phylabel         string            `yaml:"phylabel"`
+	Logicallabel     string            `yaml:"logicallabel,omitempty"` //phylabel if empty
+	Assigngrp        string            `yaml:"assigngrp,omitempty"`    //logicallabel if empty
+	Phyaddrs         map[string]string `yaml:"phyaddrs,omitempty"`
+	Usage            string            `yaml:"usage,omitempty"` //none, mgmtAndApps, shared, dedicated, disabled or mgmtOnly
+	FreeUplink       bool              `yaml:"freeUplink,omitempty"`
+	FallBackPriority uint32            `yaml:"fallBackPriority,omitempty"`
+}
+
+//DevModelNetwork describes network of device model
+type DevModelNetwork struct {
+	Name     string          `yaml:"name"`
+	ID       string          `yaml:"id,omitempty"`   //generated from name if empty
+	Type     string          `yaml:"type,omitempty"` //v4 or v6, v4 if empty
+	DHCP     string          `yaml:"dhcp"`           //client, static or none
+	Subnet   string          `yaml:"subnet,omitempty"`
+	Gateway  string          `yaml:"gateway,omitempty"`
+	DNS      []string        `yaml:"dns,omitempty"`
+	NTP      string          `yaml:"ntp,omitempty"`
+	Wifi     []*DevModelWifi `yaml:"wifi,omitempty"`
+	Cellular []string        `yaml:"cellular,omitempty"` //APNs
+}
+
+//DevModelWifi describes wifi access point of network
+type DevModelWifi struct {
+	SSID      string `yaml:"ssid"`
+	KeyScheme string `yaml:"keyScheme,omitempty"` //wpaPsk or wpaEap
+	Identity  string `yaml:"identity,omitempty"`
+	Password  string `yaml:"password,omitempty"`
+	Priority  int32  `yaml:"priority,omitempty"`
+}
+
+//DevModelAdapter describes system adapter of device model
+type DevModelAdapter struct {
+	Name           string `yaml:"name"`
+	Network        string `yaml:"network,omitempty"` //name of network of model
+	Uplink         bool   `yaml:"uplink,omitempty"`
+	FreeUplink     bool   `yaml:"freeUplink,omitempty"`
+	Addr           string `yaml:"addr,omitempty"`
+	Alias          string `yaml:"alias,omitempty"`
+	LowerLayerName string `yaml:"lowerLayerName,omitempty"`
+}
+
+var physicalIOTypes = map[string]evecommon.PhyIoType{
+	"eth":   evecommon.PhyIoType_PhyIoNetEth,
+	"wlan":  evecommon.PhyIoType_PhyIoNetWLAN,
+	"wwan":  evecommon.PhyIoType_PhyIoNetWWAN,
+	"usb":   evecommon.PhyIoType_PhyIoUSB,
+	"com":   evecommon.PhyIoType_PhyIoCOM,
+	"audio": evecommon.PhyIoType_PhyIoAudio,
+	"hdmi":  evecommon.PhyIoType_PhyIoHDMI,
+	"other": evecommon.PhyIoType_PhyIoOther,
+}
+
+var physicalIOUsages = map[string]evecommon.PhyIoMemberUsage{
+	"":            evecommon.PhyIoMemberUsage_PhyIoUsageNone,
+	"none":        evecommon.PhyIoMemberUsage_PhyIoUsageNone,
+	"mgmtAndApps": evecommon.PhyIoMemberUsage_PhyIoUsageMgmtAndApps,
+	"shared":      evecommon.PhyIoMemberUsage_PhyIoUsageShared,
+	"dedicated":   evecommon.PhyIoMemberUsage_PhyIoUsageDedicated,
+	"disabled":    evecommon.PhyIoMemberUsage_PhyIoUsageDisabled,
+	"mgmtOnly":    evecommon.PhyIoMemberUsage_PhyIoUsageMgmtOnly,
+}
+
+var networkTypes = map[string]config.NetworkType{
+	"":   config.NetworkType_V4,
+	"v4": config.NetworkType_V4,
+	"v6": config.NetworkType_V6,
+}
+
+var dhcpTypes = map[string]config.DHCPType{
+	"client": config.DHCPType_Client,
+	"static": config.DHCPType_Static,
+	"none":   config.DHCPType_DHCPNone,
+}
+
+var wifiKeySchemes = map[string]config.WiFiKeyScheme{
+	"":       config.WiFiKeyScheme_SchemeNOOP,
+	"wpaPsk": config.WiFiKeyScheme_WPAPSK,
+	"wpaEap": config.WiFiKeyScheme_WPAEAP,
+}
+
+//builtInDevModels are device models available without models directory
+var builtInDevModels = []string{`
+name: ` + string(DevModelTypeEmpty) + `
+`, `
+name: ` + string(DevModelTypeQemu) + `
+physicalIOs:
+  - type: eth
+    phylabel: eth0
+    phyaddrs: {Ifname: eth0}
+    usage: mgmtAndApps
+    freeUplink: true
+  - type: eth
+    phylabel: eth1
+    phyaddrs: {Ifname: eth1}
+    usage: shared
+    freeUplink: true
+networks:
+  - name: dhcp
+    id: ` + defaults.NetDHCPID + `
+    dhcp: client
+  - name: nodhcp
+    id: ` + defaults.NetNoDHCPID + `
+    dhcp: none
+adapters:
+  - name: eth0
+    network: dhcp
+    uplink: true
+  - name: eth1
+    network: nodhcp
+adaptersForSwitches: [eth1]
+`}
+
+//LoadDevModelFile parses device model from yaml and validates it
+func LoadDevModelFile(data []byte) (*DevModelFile, error) {
+	var model DevModelFile
+	if err := yaml.UnmarshalStrict(data, &model); err != nil {
+		return nil, fmt.Errorf("cannot parse device model: %s", err)
+	}
+	if problems := model.Validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("device model %s is not valid:\n%s", model.Name, strings.Join(problems, "\n"))
+	}
+	return &model, nil
+}
+
+//Validate return problems of device model: unknown types, duplicates and references to unknown networks and IOs
+func (model *DevModelFile) Validate() []string {
+	var problems []string
+	if model.Name == "" {
+		problems = append(problems, "name is empty")
+	}
+	logicalLabels := make(map[string]bool)
+	for _, el := range model.PhysicalIOs {
+		if el.Phylabel == "" {
+			problems = append(problems, "physicalIO with empty phylabel")
+			continue
+		}
+		ioType, ok := physicalIOTypes[el.Type]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("physicalIO %s: unknown type %q", el.Phylabel, el.Type))
+		}
+		if _, ok := physicalIOUsages[el.Usage]; !ok {
+			problems = append(problems, fmt.Sprintf("physicalIO %s: unknown usage %q", el.Phylabel, el.Usage))
+		}
+		isNet := ioType == evecommon.PhyIoType_PhyIoNetEth || ioType == evecommon.PhyIoType_PhyIoNetWLAN || ioType == evecommon.PhyIoType_PhyIoNetWWAN
+		if _, ok := el.Phyaddrs["Ifname"]; isNet && !ok {
+			problems = append(problems, fmt.Sprintf("physicalIO %s: Ifname in phyaddrs is required for network IO", el.Phylabel))
+		}
+		label := el.Logicallabel
+		if label == "" {
+			label = el.Phylabel
+		}
+		if logicalLabels[label] {
+			problems = append(problems, fmt.Sprintf("physicalIO %s: duplicate logicallabel %s", el.Phylabel, label))
+		}
+		logicalLabels[label] = true
+	}
+	networks := make(map[string]bool)
+	for _, el := range model.Networks {
+		if el.Name == "" {
+			problems = append(problems, "network with empty name")
+			continue
+		}
+		if networks[el.Name] {
+			problems = append(problems, fmt.Sprintf("duplicate network %s", el.Name))
+		}
+		networks[el.Name] = true
+		if el.ID != "" {
+			if _, err := uuid.FromString(el.ID); err != nil {
+				problems = append(problems, fmt.Sprintf("network %s: wrong id %s", el.Name, el.ID))
+			}
+		}
+		if _, ok := networkTypes[el.Type]; !ok {
+			problems = append(problems, fmt.Sprintf("network %s: unknown type %q", el.Name, el.Type))
+		}
+		if _, ok := dhcpTypes[el.DHCP]; !ok {
+			problems = append(problems, fmt.Sprintf("network %s: unknown dhcp %q", el.Name, el.DHCP))
+		}
+		if el.DHCP == "static" || el.Subnet != "" {
+			_, subnet, err := net.ParseCIDR(el.Subnet)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("network %s: wrong subnet %q", el.Name, el.Subnet))
+			} else if el.Gateway != "" && !subnet.Contains(net.ParseIP(el.Gateway)) {
+				problems = append(problems, fmt.Sprintf("network %s: gateway %s is not in subnet %s", el.Name, el.Gateway, el.Subnet))
+			}
+		}
+		if len(el.Wifi) > 0 && len(el.Cellular) > 0 {
+			problems = append(problems, fmt.Sprintf("network %s: both wifi and cellular are set", el.Name))
+		}
+		for _, wifi := range el.Wifi {
+			if _, ok := wifiKeySchemes[wifi.KeyScheme]; !ok {
+				problems = append(problems, fmt.Sprintf("network %s: unknown keyScheme %q of wifi %s", el.Name, wifi.KeyScheme, wifi.SSID))
+			}
+		}
+	}
+	adapters := make(map[string]bool)
+	for _, el := range model.Adapters {
+		if adapters[el.Name] {
+			problems = append(problems, fmt.Sprintf("duplicate adapter %s", el.Name))
+		}
+		adapters[el.Name] = true
+		if len(model.PhysicalIOs) > 0 && !logicalLabels[el.Name] && !logicalLabels[el.LowerLayerName] {
+			problems = append(problems, fmt.Sprintf("adapter %s: no physicalIO with logicallabel %s", el.Name, el.Name))
+		}
+		if el.Network != "" && !networks[el.Network] {
+			problems = append(problems, fmt.Sprintf("adapter %s: unknown network %s", el.Name, el.Network))
+		}
+	}
+	for _, el := range model.AdaptersForSwitches {
+		if len(model.PhysicalIOs) > 0 && !logicalLabels[el] {
+			problems = append(problems, fmt.Sprintf("adapter for switches %s: no physicalIO with logicallabel %s", el, el))
+		}
+	}
+	return problems
+}
+
+//networkID return id of network of model with name
+func (model *DevModelFile) networkID(name string) string {
+	for _, el := range model.Networks {
+		if el.Name == name {
+			if el.ID != "" {
+				return el.ID
+			}
+			return uuid.NewV5(uuid.NamespaceURL, fmt.Sprintf("eden://devmodel/%s/network/%s", model.Name, name)).String()
+		}
+	}
+	return ""
+}
+
+//DevModel converts device model from yaml into DevModel, model must be validated
+func (model *DevModelFile) DevModel() *DevModel {
+	var physicalIOs []*config.PhysicalIO
+	for _, el := range model.PhysicalIOs {
+		physicalIO := &config.PhysicalIO{
+			Ptype:        physicalIOTypes[el.Type],
+			Phylabel:     el.Phylabel,
+			Logicallabel: el.Logicallabel,
+			Assigngrp:    el.Assigngrp,
+			Phyaddrs:     el.Phyaddrs,
+			Usage:        physicalIOUsages[el.Usage],
+			UsagePolicy:  &config.PhyIOUsagePolicy{FreeUplink: el.FreeUplink, FallBackPriority: el.FallBackPriority},
+		}
+		if physicalIO.Logicallabel == "" {
+			physicalIO.Logicallabel = el.Phylabel
+		}
+		if physicalIO.Assigngrp == "" {
+			physicalIO.Assigngrp = physicalIO.Logicallabel
+		}
+		physicalIOs = append(physicalIOs, physicalIO)
+	}
+	var networks []*config.NetworkConfig
+	for _, el := range model.Networks {
+		network := &config.NetworkConfig{
+			Id:   model.networkID(el.Name),
+			Type: networkTypes[el.Type],
+			Ip: &config.Ipspec{
+				Dhcp:      dhcpTypes[el.DHCP],
+				Subnet:    el.Subnet,
+				Gateway:   el.Gateway,
+				Dns:       el.DNS,
+				Ntp:       el.NTP,
+				DhcpRange: &config.IpRange{},
+			},
+		}
+		if len(el.Wifi) > 0 {
+			network.Wireless = &config.WirelessConfig{Type: config.WirelessType_WiFi}
+			for _, wifi := range el.Wifi {
+				network.Wireless.WifiCfg = append(network.Wireless.WifiCfg, &config.WifiConfig{
+					WifiSSID:  wifi.SSID,
+					KeyScheme: wifiKeySchemes[wifi.KeyScheme],
+					Identity:  wifi.Identity,
+					Password:  wifi.Password,
+					Priority:  wifi.Priority,
+				})
+			}
+		}
+		if len(el.Cellular) > 0 {
+			network.Wireless = &config.WirelessConfig{Type: config.WirelessType_Cellular}
+			for _, apn := range el.Cellular {
+				network.Wireless.CellularCfg = append(network.Wireless.CellularCfg, &config.CellularConfig{APN: apn})
+			}
+		}
+		networks = append(networks, network)
+	}
+	var adapters []*config.SystemAdapter
+	for _, el := range model.Adapters {
+		adapters = append(adapters, &config.SystemAdapter{
+			Name:           el.Name,
+			FreeUplink:     el.FreeUplink,
+			Uplink:         el.Uplink,
+			NetworkUUID:    model.networkID(el.Network),
+			Addr:           el.Addr,
+			Alias:          el.Alias,
+			LowerLayerName: el.LowerLayerName,
+		})
+	}
+	return &DevModel{
+		physicalIOs:        physicalIOs,
+		networks:           networks,
+		adapters:           adapters,
+		adapterForSwitches: model.AdaptersForSwitches,
+		devModelType:       DevModelType(model.Name),
+	}
+}
+
+//DevModelsDir return directory with yaml files of device models from eden.models of config
+func DevModelsDir() string {
+	return utils.ResolveAbsPath(viper.GetString("eden.models"))
+}
+
+//ListDevModelFiles return built-in device models and models from yaml files of DevModelsDir sorted by name,
+//models from directory override built-in ones with the same name, invalid files are skipped with warning
+func ListDevModelFiles() ([]*DevModelFile, error) {
+	models := make(map[string]*DevModelFile)
+	for _, el := range builtInDevModels {
+		model, err := LoadDevModelFile([]byte(el))
+		if err != nil {
+			return nil, err
+		}
+		model.Source = "built-in"
+		models[model.Name] = model
+	}
+	if dir := DevModelsDir(); dir != "" {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, file := range files {
+			if file.IsDir() || (filepath.Ext(file.Name()) != ".yaml" && filepath.Ext(file.Name()) != ".yml") {
+				continue
+			}
+			model, err := ReadDevModelFile(filepath.Join(dir, file.Name()))
+			if err != nil {
+				log.Warnf("skip %s: %s", file.Name(), err)
+				continue
+			}
+			models[model.Name] = model
+		}
+	}
+	var result []*DevModelFile
+	for _, model := range models {
+		result = append(result, model)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+//ReadDevModelFile loads device model from yaml file
+func ReadDevModelFile(fileName string) (*DevModelFile, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	model, err := LoadDevModelFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	model.Source = fileName
+	return model, nil
+}
+
+//FindDevModelFile return device model with name or loads it from yaml file if name is path to it
+func FindDevModelFile(name string) (*DevModelFile, error) {
+	if ext := filepath.Ext(name); ext == ".yaml" || ext == ".yml" {
+		return ReadDevModelFile(name)
+	}
+	models, err := ListDevModelFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range models {
+		if model.Name == name {
+			return model, nil
+		}
+	}
+	return nil, fmt.Errorf("not implemented type: %s", name)
+}
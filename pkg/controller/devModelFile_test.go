@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/lf-edge/eve/api/go/evecommon"
+	"strings"
+	"testing"
+)
+
+const testDevModel = `
+name: Board
+physicalIOs:
+  - type: eth
+    phylabel: eth0
+    phyaddrs: {Ifname: eth0}
+    usage: mgmtAndApps
+  - type: wlan
+    phylabel: wlan0
+    phyaddrs: {Ifname: wlan0}
+    usage: mgmtAndApps
+  - type: wwan
+    phylabel: wwan0
+    phyaddrs: {Ifname: wwan0}
+    usage: mgmtOnly
+  - type: eth
+    phylabel: eth1
+    phyaddrs: {Ifname: eth1}
+    usage: shared
+networks:
+  - name: dhcp
+    dhcp: client
+  - name: wifi
+    dhcp: client
+    wifi: [{ssid: lab, keyScheme: wpaPsk, password: secret}]
+  - name: lte
+    dhcp: client
+    cellular: [internet]
+adapters:
+  - name: eth0
+    network: dhcp
+    uplink: true
+  - name: wlan0
+    network: wifi
+  - name: wwan0
+    network: lte
+adaptersForSwitches: [eth1]
+`
+
+//TestDevModelFile test built-in and yaml device models
+func TestDevModelFile(t *testing.T) {
+	qemu, err := (&CloudCtx{}).GetDevModel(DevModelTypeQemu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//built-in model must be the same as defined in go before
+	expectedIOs := []*config.PhysicalIO{{
+		Ptype:        evecommon.PhyIoType_PhyIoNetEth,
+		Phylabel:     "eth0",
+		Logicallabel: "eth0",
+		Assigngrp:    "eth0",
+		Phyaddrs:     map[string]string{"Ifname": "eth0"},
+		Usage:        evecommon.PhyIoMemberUsage_PhyIoUsageMgmtAndApps,
+		UsagePolicy:  &config.PhyIOUsagePolicy{FreeUplink: true},
+	}, {
+		Ptype:        evecommon.PhyIoType_PhyIoNetEth,
+		Phylabel:     "eth1",
+		Logicallabel: "eth1",
+		Assigngrp:    "eth1",
+		Phyaddrs:     map[string]string{"Ifname": "eth1"},
+		Usage:        evecommon.PhyIoMemberUsage_PhyIoUsageShared,
+		UsagePolicy:  &config.PhyIOUsagePolicy{FreeUplink: true},
+	}}
+	for i, el := range expectedIOs {
+		if !proto.Equal(el, qemu.physicalIOs[i]) {
+			t.Fatalf("wrong physicalIO %d: %v", i, qemu.physicalIOs[i])
+		}
+	}
+	expectedNetwork := &config.NetworkConfig{
+		Id:   defaults.NetDHCPID,
+		Type: config.NetworkType_V4,
+		Ip:   &config.Ipspec{Dhcp: config.DHCPType_Client, DhcpRange: &config.IpRange{}},
+	}
+	if !proto.Equal(expectedNetwork, qemu.networks[0]) {
+		t.Fatalf("wrong network: %v", qemu.networks[0])
+	}
+	expectedAdapter := &config.SystemAdapter{Name: "eth0", Uplink: true, NetworkUUID: defaults.NetDHCPID}
+	if !proto.Equal(expectedAdapter, qemu.adapters[0]) {
+		t.Fatalf("wrong adapter: %v", qemu.adapters[0])
+	}
+	if qemu.GetFirstAdapterForSwitches() != "eth1" {
+		t.Fatalf("wrong adapter for switches: %s", qemu.GetFirstAdapterForSwitches())
+	}
+	if _, err = (&CloudCtx{}).GetDevModel("Unknown"); err == nil {
+		t.Fatal("expected error for unknown model")
+	}
+
+	model, err := LoadDevModelFile([]byte(testDevModel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	devModel := model.DevModel()
+	if devModel.networks[1].GetWireless().GetWifiCfg()[0].GetKeyScheme() != config.WiFiKeyScheme_WPAPSK {
+		t.Fatalf("wrong wifi: %v", devModel.networks[1].GetWireless())
+	}
+	if devModel.networks[2].GetWireless().GetCellularCfg()[0].GetAPN() != "internet" {
+		t.Fatalf("wrong cellular: %v", devModel.networks[2].GetWireless())
+	}
+	if devModel.adapters[2].GetNetworkUUID() != devModel.networks[2].GetId() || devModel.networks[2].GetId() == "" {
+		t.Fatalf("wrong network of adapter: %s", devModel.adapters[2].GetNetworkUUID())
+	}
+
+	broken := strings.NewReplacer("network: lte", "network: unknown", "type: wwan", "type: modem", "[eth1]", "[eth2]").Replace(testDevModel)
+	_, err = LoadDevModelFile([]byte(broken))
+	if err == nil {
+		t.Fatal("expected error for broken model")
+	}
+	for _, problem := range []string{"unknown network unknown", "unknown type \"modem\"", "adapter for switches eth2"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected %q in %q", problem, err)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	uuid "github.com/satori/go.uuid"
 	"github.com/spf13/viper"
 	"strconv"
+	"strings"
 )
 
 //StateUpdate refresh state file
@@ -31,6 +32,11 @@ func (cloud *CloudCtx) StateUpdate(dev *device.Ctx) (err error) {
 		return err
 	}
 	if loaded {
+		//state of devices onboarded with other serials must not overwrite state of EVE from config
+		stateKey := viper.GetString("eve.uuid")
+		if dev.GetSerial() != "" && dev.GetSerial() != viper.GetString("eve.serial") {
+			stateKey = dev.GetID().String()
+		}
 		if err = utils.GenerateStateFile(edenDir, utils.StateObject{
 			EveConfig:  string(devConfig),
 			EveDir:     viper.GetString("eve.dist"),
@@ -38,6 +44,7 @@ func (cloud *CloudCtx) StateUpdate(dev *device.Ctx) (err error) {
 			EveUUID:    viper.GetString("eve.uuid"),
 			DeviceUUID: dev.GetID().String(),
 			QEMUConfig: viper.GetString("eve.qemu-config"),
+			StateKey:   stateKey,
 		}); err != nil {
 			return err
 		}
@@ -94,6 +101,7 @@ func (cloud *CloudCtx) ConfigParse(config *config.EdgeDevConfig) (device *device
 	}
 	version, _ := strconv.Atoi(config.Id.Version)
 	dev.SetConfigVersion(version)
+	dev.SetName(config.Name)
 	for _, el := range config.ConfigItems {
 		dev.SetConfigItem(el.GetKey(), el.GetValue())
 	}
@@ -176,9 +184,25 @@ func (cloud *CloudCtx) ConfigParse(config *config.EdgeDevConfig) (device *device
 	return dev, nil
 }
 
+//GetConfigValidated return config of dev checked with ConfigValidate
+func (cloud *CloudCtx) GetConfigValidated(dev *device.Ctx) ([]byte, error) {
+	devConfig, err := cloud.GetConfigBytes(dev, false)
+	if err != nil {
+		return nil, err
+	}
+	var deviceConfig config.EdgeDevConfig
+	if err = json.Unmarshal(devConfig, &deviceConfig); err != nil {
+		return nil, fmt.Errorf("unmarshal error: %s", err)
+	}
+	if problems := ConfigValidate(&deviceConfig, dev.GetAdaptersForSwitch()); len(problems) > 0 {
+		return nil, fmt.Errorf("config validation failed:\n%s", strings.Join(problems, "\n"))
+	}
+	return devConfig, nil
+}
+
 //ConfigSync set config for devID
 func (cloud *CloudCtx) ConfigSync(dev *device.Ctx) (err error) {
-	devConfig, err := cloud.GetConfigBytes(dev, false)
+	devConfig, err := cloud.GetConfigValidated(dev)
 	if err != nil {
 		return err
 	}
@@ -206,6 +230,39 @@ func (cloud *CloudCtx) GetDeviceFirst() (dev *device.Ctx, err error) {
 	return cloud.devices[0], nil
 }
 
+//ListDevices return all device objects
+func (cloud *CloudCtx) ListDevices() []*device.Ctx {
+	return cloud.devices
+}
+
+//GetDevice return device object by selector, which may be uuid, serial or name of device
+//empty selector returns first device
+func (cloud *CloudCtx) GetDevice(selector string) (dev *device.Ctx, err error) {
+	if selector == "" {
+		return cloud.GetDeviceFirst()
+	}
+	if devUUID, err := uuid.FromString(selector); err == nil {
+		return cloud.GetDeviceUUID(devUUID)
+	}
+	for _, match := range []func(*device.Ctx) string{(*device.Ctx).GetSerial, (*device.Ctx).GetName} {
+		var found []*device.Ctx
+		for _, el := range cloud.devices {
+			if match(el) == selector {
+				found = append(found, el)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		default:
+			return nil, fmt.Errorf("more than one device matches %s", selector)
+		}
+	}
+	return nil, fmt.Errorf("no device found for %s", selector)
+}
+
 //AddDevice add device with specified devUUID
 func (cloud *CloudCtx) AddDevice(devUUID uuid.UUID) (dev *device.Ctx, err error) {
 	for _, el := range cloud.devices {
@@ -388,7 +445,7 @@ func (cloud *CloudCtx) GetConfigBytes(dev *device.Ctx, pretty bool) ([]byte, err
 		ProductName:       "",
 		NetworkInstances:  networkInstanceConfigs,
 		Enterprise:        "",
-		Name:              "",
+		Name:              dev.GetName(),
 	}
 	if pretty {
 		return json.MarshalIndent(devConfig, "", "    ")
@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lf-edge/eve/api/go/config"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//ConfigChangeAction is type of change of object in config
+type ConfigChangeAction string
+
+//ConfigChangeAction values
+const (
+	ConfigAdded   ConfigChangeAction = "+"
+	ConfigRemoved ConfigChangeAction = "-"
+	ConfigChanged ConfigChangeAction = "~"
+)
+
+//ConfigChange is one semantic change of object between two configs of device
+type ConfigChange struct {
+	Action ConfigChangeAction
+	Kind   string   //kind of object: app, network instance, datastore, etc.
+	ID     string   //uuid or other key of object
+	Name   string   //human readable name of object
+	Fields []string //changed fields of object
+}
+
+//String return one-line representation of change
+func (change *ConfigChange) String() string {
+	s := fmt.Sprintf("%s %s %s", change.Action, change.Kind, change.ID)
+	if change.Name != "" {
+		s = fmt.Sprintf("%s (%s)", s, change.Name)
+	}
+	if len(change.Fields) > 0 {
+		s = fmt.Sprintf("%s: %s", s, strings.Join(change.Fields, ", "))
+	}
+	return s
+}
+
+//diffItem is object of config section with key and name to compare
+type diffItem struct {
+	id    string
+	name  string
+	value interface{}
+}
+
+//ConfigDiff return semantic changes of apps, network instances, networks, datastores,
+//volumes, config items, baseOS and device fields between old and new configs in json.
+//Version of config is ignored.
+func ConfigDiff(oldConfig, newConfig []byte) ([]*ConfigChange, error) {
+	var oldDevConfig, newDevConfig config.EdgeDevConfig
+	if err := json.Unmarshal(oldConfig, &oldDevConfig); err != nil {
+		return nil, fmt.Errorf("cannot parse old config: %s", err)
+	}
+	if err := json.Unmarshal(newConfig, &newDevConfig); err != nil {
+		return nil, fmt.Errorf("cannot parse new config: %s", err)
+	}
+	var changes []*ConfigChange
+	appItems := func(devConfig *config.EdgeDevConfig) (items []diffItem) {
+		for _, el := range devConfig.GetApps() {
+			items = append(items, diffItem{id: el.GetUuidandversion().GetUuid(), name: el.GetDisplayname(), value: el})
+		}
+		return
+	}
+	changes = append(changes, diffSection("app", appItems(&oldDevConfig), appItems(&newDevConfig))...)
+	niItems := func(devConfig *config.EdgeDevConfig) (items []diffItem) {
+		for _, el := range devConfig.GetNetworkInstances() {
+			items = append(items, diffItem{id: el.GetUuidandversion().GetUuid(), name: el.GetDisplayname(), value: el})
+		}
+		return
+	}
+	changes = append(changes, diffSection("network instance", niItems(&oldDevConfig), niItems(&newDevConfig))...)
+	networkItems := func(devConfig *config.EdgeDevConfig) (items []diffItem) {
+		for _, el := range devConfig.GetNetworks() {
+			items = append(items, diffItem{id: el.GetId(), value: el})
+		}
+		return
+	}
+	changes = append(changes, diffSection("network", networkItems(&oldDevConfig), networkItems(&newDevConfig))...)
+	dsItems := func(devConfig *config.EdgeDevConfig) (items []diffItem) {
+		for _, el := range devConfig.GetDatastores() {
+			items = append(items, diffItem{id: el.GetId(), name: el.GetFqdn(), value: el})
+		}
+		return
+	}
+	changes = append(changes, diffSection("datastore", dsItems(&oldDevConfig), dsItems(&newDevConfig))...)
+	volumeItems := func(devConfig *config.EdgeDevConfig) (items []diffItem) {
+		for _, el := range devConfig.GetVolumes() {
+			items = append(items, diffItem{id: el.GetUuid(), name: el.GetDisplayName(), value: el})
+		}
+		return
+	}
+	changes = append(changes, diffSection("volume", volumeItems(&oldDevConfig), volumeItems(&newDevConfig))...)
+	baseOSItems := func(devConfig *config.EdgeDevConfig) (items []diffItem) {
+		for _, el := range devConfig.GetBase() {
+			items = append(items, diffItem{id: el.GetUuidandversion().GetUuid(), name: el.GetBaseOSVersion(), value: el})
+		}
+		return
+	}
+	changes = append(changes, diffSection("baseOS", baseOSItems(&oldDevConfig), baseOSItems(&newDevConfig))...)
+	changes = append(changes, diffConfigItems(oldDevConfig.GetConfigItems(), newDevConfig.GetConfigItems())...)
+	//compare fields of device itself without sections compared above and version
+	devID := newDevConfig.GetId().GetUuid()
+	oldDevConfig.Id, newDevConfig.Id = nil, nil
+	for _, devConfig := range []*config.EdgeDevConfig{&oldDevConfig, &newDevConfig} {
+		devConfig.Apps, devConfig.NetworkInstances, devConfig.Networks = nil, nil, nil
+		devConfig.Datastores, devConfig.Volumes, devConfig.Base, devConfig.ConfigItems = nil, nil, nil, nil
+	}
+	if fields := changedFields(&oldDevConfig, &newDevConfig); len(fields) > 0 {
+		changes = append(changes, &ConfigChange{Action: ConfigChanged, Kind: "device", ID: devID, Fields: fields})
+	}
+	return changes, nil
+}
+
+//diffSection compares objects of one section of config by their keys
+func diffSection(kind string, oldItems, newItems []diffItem) (changes []*ConfigChange) {
+	oldMap := make(map[string]diffItem)
+	for _, el := range oldItems {
+		oldMap[el.id] = el
+	}
+	newMap := make(map[string]diffItem)
+	for _, el := range newItems {
+		newMap[el.id] = el
+	}
+	for _, el := range oldItems {
+		if _, ok := newMap[el.id]; !ok {
+			changes = append(changes, &ConfigChange{Action: ConfigRemoved, Kind: kind, ID: el.id, Name: el.name})
+		}
+	}
+	for _, el := range newItems {
+		old, ok := oldMap[el.id]
+		if !ok {
+			changes = append(changes, &ConfigChange{Action: ConfigAdded, Kind: kind, ID: el.id, Name: el.name})
+			continue
+		}
+		if fields := changedFields(old.value, el.value); len(fields) > 0 {
+			changes = append(changes, &ConfigChange{Action: ConfigChanged, Kind: kind, ID: el.id, Name: el.name, Fields: fields})
+		}
+	}
+	return
+}
+
+//diffConfigItems compares config items by keys and shows old and new values
+func diffConfigItems(oldItems, newItems []*config.ConfigItem) (changes []*ConfigChange) {
+	oldMap := make(map[string]string)
+	for _, el := range oldItems {
+		oldMap[el.GetKey()] = el.GetValue()
+	}
+	newMap := make(map[string]string)
+	for _, el := range newItems {
+		newMap[el.GetKey()] = el.GetValue()
+	}
+	for _, el := range oldItems {
+		if _, ok := newMap[el.GetKey()]; !ok {
+			changes = append(changes, &ConfigChange{Action: ConfigRemoved, Kind: "config item", ID: el.GetKey(), Fields: []string{el.GetValue()}})
+		}
+	}
+	for _, el := range newItems {
+		old, ok := oldMap[el.GetKey()]
+		if !ok {
+			changes = append(changes, &ConfigChange{Action: ConfigAdded, Kind: "config item", ID: el.GetKey(), Fields: []string{el.GetValue()}})
+			continue
+		}
+		if old != el.GetValue() {
+			changes = append(changes, &ConfigChange{Action: ConfigChanged, Kind: "config item", ID: el.GetKey(), Fields: []string{fmt.Sprintf("%q -> %q", old, el.GetValue())}})
+		}
+	}
+	return
+}
+
+//changedFields return sorted names of top-level json fields which differ between objects
+func changedFields(oldValue, newValue interface{}) (fields []string) {
+	toMap := func(value interface{}) map[string]interface{} {
+		result := make(map[string]interface{})
+		data, err := json.Marshal(value)
+		if err != nil {
+			return result
+		}
+		_ = json.Unmarshal(data, &result)
+		return result
+	}
+	oldMap, newMap := toMap(oldValue), toMap(newValue)
+	for k, v := range oldMap {
+		if !reflect.DeepEqual(v, newMap[k]) {
+			fields = append(fields, k)
+		}
+	}
+	for k := range newMap {
+		if _, ok := oldMap[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/lf-edge/eve/api/go/config"
+	"testing"
+)
+
+//TestConfigDiff test semantic diff between configs
+func TestConfigDiff(t *testing.T) {
+	oldConfig := &config.EdgeDevConfig{
+		Id: &config.UUIDandVersion{Uuid: "1b4a8cf4-6a2c-4e3b-9a3c-2f3a4e5b6c7d", Version: "4"},
+		Apps: []*config.AppInstanceConfig{
+			{Uuidandversion: &config.UUIDandVersion{Uuid: "app1", Version: "1"}, Displayname: "nginx"},
+			{Uuidandversion: &config.UUIDandVersion{Uuid: "app2", Version: "1"}, Displayname: "redis"},
+		},
+		Datastores:  []*config.DatastoreConfig{{Id: "ds1", Fqdn: "docker://docker.io"}},
+		ConfigItems: []*config.ConfigItem{{Key: "timer.config.interval", Value: "10"}, {Key: "debug.enable.ssh", Value: "key"}},
+	}
+	newConfig := &config.EdgeDevConfig{
+		Id: &config.UUIDandVersion{Uuid: "1b4a8cf4-6a2c-4e3b-9a3c-2f3a4e5b6c7d", Version: "5"},
+		Apps: []*config.AppInstanceConfig{
+			{Uuidandversion: &config.UUIDandVersion{Uuid: "app1", Version: "1"}, Displayname: "nginx", Activate: true},
+		},
+		Datastores:  []*config.DatastoreConfig{{Id: "ds1", Fqdn: "docker://docker.io"}},
+		Base:        []*config.BaseOSConfig{{Uuidandversion: &config.UUIDandVersion{Uuid: "base1", Version: "1"}, BaseOSVersion: "0.0.0-snapshot"}},
+		ConfigItems: []*config.ConfigItem{{Key: "timer.config.interval", Value: "5"}, {Key: "app.allow.vnc", Value: "true"}},
+		Name:        "lab-device",
+	}
+	oldData, err := json.Marshal(oldConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newData, err := json.Marshal(newConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := ConfigDiff(oldData, newData)
+	if err != nil {
+		t.Fatalf("ConfigDiff: %s", err)
+	}
+	expected := []string{
+		"- app app2 (redis)",
+		"~ app app1 (nginx): activate",
+		"+ baseOS base1 (0.0.0-snapshot)",
+		"- config item debug.enable.ssh: key",
+		`~ config item timer.config.interval: "10" -> "5"`,
+		"+ config item app.allow.vnc: true",
+		"~ device 1b4a8cf4-6a2c-4e3b-9a3c-2f3a4e5b6c7d: name",
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d: %v", len(expected), len(changes), changes)
+	}
+	for i, change := range changes {
+		if change.String() != expected[i] {
+			t.Errorf("change %d: expected %q, got %q", i, expected[i], change.String())
+		}
+	}
+	if changes, err = ConfigDiff(oldData, oldData); err != nil || len(changes) != 0 {
+		t.Fatalf("expected no changes for the same config, got %v (%v)", changes, err)
+	}
+}
@@ -0,0 +1,212 @@
+//Package eflowlog provides primitives for searching and processing data
+//in FlowLog files.
+package eflowlog
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/lf-edge/eden/pkg/controller/equery"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eve/api/go/flowlog"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+//HandlerFunc must process flowlog.FlowMessage and return true to exit
+//or false to continue
+type HandlerFunc func(fm *flowlog.FlowMessage, ds []*FlowMessageInterface, flowLogType FlowLogType) bool
+
+//QHandlerFunc must process flowlog.FlowMessage with compiled query
+//and return selected items
+type QHandlerFunc func(fm *flowlog.FlowMessage, query *equery.Query, flowLogType FlowLogType) []*FlowMessageInterface
+
+//FlowMessageInterface is an interface to pass between handlers
+type FlowMessageInterface interface{}
+
+type flowLogPacket struct {
+	name string
+	path *equery.Path
+}
+
+//FlowLogType is an parameter for obtain particular records from flow logs
+type FlowLogType *flowLogPacket
+
+//flowLogPath return FlowLogType with name for selection of records by path
+func flowLogPath(name, path string) FlowLogType {
+	p, err := equery.ParsePath(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &flowLogPacket{name: name, path: p}
+}
+
+var (
+	//FlowLogFlows can be used for filter GetFlows
+	FlowLogFlows = flowLogPath("Flows", "flows[*]")
+	//FlowLogDNS can be used for filter GetDnsReqs
+	FlowLogDNS = flowLogPath("DnsReqs", "dnsReqs[*]")
+	//FlowLogAll can be used for display whole messages
+	FlowLogAll = flowLogPath("", "")
+)
+
+//GetFlowLogType return FlowLogType by name
+func GetFlowLogType(name string) (FlowLogType, error) {
+	var flowLogType FlowLogType
+	switch name {
+	case "all":
+		flowLogType = FlowLogAll
+	case "flow":
+		flowLogType = FlowLogFlows
+	case "dns":
+		flowLogType = FlowLogDNS
+	default:
+		return nil, fmt.Errorf("not implemented: %s", name)
+	}
+	return flowLogType, nil
+}
+
+//ListFlowLogType return all implemented
+func ListFlowLogType() []string {
+	return []string{"all", "flow", "dns"}
+}
+
+//ParseFlowMessage unmarshal FlowMessage
+func ParseFlowMessage(data []byte) (*flowlog.FlowMessage, error) {
+	var fm flowlog.FlowMessage
+	if err := jsonpb.UnmarshalString(string(data), &fm); err != nil {
+		return nil, err
+	}
+	return &fm, nil
+}
+
+//FlowLogPrn print data from FlowMessage structure
+func FlowLogPrn(fm *flowlog.FlowMessage, ds []*FlowMessageInterface, flowLogType FlowLogType) {
+	fmt.Println("devId:", fm.GetDevId())
+	fmt.Println("scope:", fm.GetScope())
+	if flowLogType.name != "" {
+		fmt.Printf("%s:\n", flowLogType.name)
+	}
+	for i, d := range ds {
+		fmt.Printf("[%d]: %s\n", i, *d)
+	}
+	fmt.Println()
+}
+
+//HandleFirst runs once and interrupts the workflow of FlowLogWatch
+func HandleFirst(fm *flowlog.FlowMessage, ds []*FlowMessageInterface, flowLogType FlowLogType) bool {
+	FlowLogPrn(fm, ds, flowLogType)
+	return true
+}
+
+//HandleAll runs for all FlowLogs selected by FlowLogWatch
+func HandleAll(fm *flowlog.FlowMessage, ds []*FlowMessageInterface, flowLogType FlowLogType) bool {
+	FlowLogPrn(fm, ds, flowLogType)
+	return false
+}
+
+//FlowLogFind finds records selected by 'flowLogType' inside FlowMessage which match 'query'.
+//Nested fields are addressed with dots (e.g. 'flow.destPort')
+func FlowLogFind(fm *flowlog.FlowMessage, query *equery.Query, flowLogType FlowLogType) []*FlowMessageInterface {
+	var items []*FlowMessageInterface
+	for _, d := range flowLogType.path.Find(fm, query) {
+		var strValT FlowMessageInterface = d
+		items = append(items, &strValT)
+	}
+	return items
+}
+
+//FlowLogCheckerMode is FlowLogExist, FlowLogNew and FlowLogAny
+type FlowLogCheckerMode int
+
+// FlowLogChecker modes FlowLogExist, FlowLogNew and FlowLogAny.
+const (
+	FlowLogExist FlowLogCheckerMode = iota // just look to existing files
+	FlowLogNew                             // wait for new files
+	FlowLogAny                             // use both mechanisms
+)
+
+//flowLogProcess compiles 'query' once and return function which checks 'devId' and 'scope.*' fields of message
+//and process records selected by 'qhandler' with other fields of 'query'
+func flowLogProcess(query map[string]string, qhandler QHandlerFunc, handler HandlerFunc, flowLogType FlowLogType) loaders.ProcessFunction {
+	scopeFields := make(map[string]string)
+	itemFields := make(map[string]string)
+	for k, v := range query {
+		if strings.HasPrefix(k, "scope.") && flowLogType != FlowLogAll {
+			scopeFields[k] = v
+		} else {
+			itemFields[k] = v
+		}
+	}
+	devID := itemFields["devId"]
+	delete(itemFields, "devId")
+	scopeQuery, err := equery.FromMap(scopeFields)
+	if err != nil {
+		return func(bytes []byte) (bool, error) {
+			return false, err
+		}
+	}
+	itemQuery, err := equery.FromMap(itemFields)
+	if err != nil {
+		return func(bytes []byte) (bool, error) {
+			return false, err
+		}
+	}
+	return func(bytes []byte) (bool, error) {
+		fm, err := ParseFlowMessage(bytes)
+		if err != nil {
+			return true, nil
+		}
+		if devID != "" && devID != fm.DevId {
+			return true, nil
+		}
+		if !scopeQuery.Match(fm) {
+			return true, nil
+		}
+		ds := qhandler(fm, itemQuery, flowLogType)
+		if ds != nil {
+			if handler(fm, ds, flowLogType) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+//FlowLogLast search FlowLog files according to the 'query' parameters accepted by the 'qhandler' function and subsequent process using the 'handler' function.
+func FlowLogLast(loader loaders.Loader, query map[string]string, qhandler QHandlerFunc, handler HandlerFunc, flowLogType FlowLogType) error {
+	return loader.ProcessExisting(flowLogProcess(query, qhandler, handler, flowLogType), loaders.FlowLogType)
+}
+
+//FlowLogWatch monitors the change of FlowLog files according to the 'query' parameters accepted by the 'qhandler' function and subsequent processing using the 'handler' function with 'timeoutSeconds'.
+func FlowLogWatch(loader loaders.Loader, query map[string]string, qhandler QHandlerFunc, handler HandlerFunc, flowLogType FlowLogType, timeoutSeconds time.Duration) error {
+	return loader.ProcessStream(flowLogProcess(query, qhandler, handler, flowLogType), loaders.FlowLogType, timeoutSeconds)
+}
+
+//FlowLogChecker checks the flow logs in the regular expression pattern 'query' and processes the flowlog.FlowMessage found by the function 'handler' from existing files (mode=FlowLogExist), new files (mode=FlowLogNew) or any of them (mode=FlowLogAny) with timeout (0 for infinite).
+//With mode=FlowLogExist loaders.ErrNotFound is returned if no flow logs are found.
+func FlowLogChecker(loader loaders.Loader, devUUID uuid.UUID, query map[string]string, flowLogType FlowLogType, handler HandlerFunc, mode FlowLogCheckerMode, timeout time.Duration) error {
+	loader.SetUUID(devUUID)
+	var exist loaders.ExistFunc
+	var watch loaders.WatchFunc
+	// observe new files
+	if mode == FlowLogNew || mode == FlowLogAny {
+		watch = func() error {
+			return FlowLogWatch(loader.Clone(), query, FlowLogFind, handler, flowLogType, timeout)
+		}
+	}
+	// check flow logs by pattern in existing files
+	if mode == FlowLogExist || mode == FlowLogAny {
+		exist = func(found func()) error {
+			return FlowLogLast(loader.Clone(), query, FlowLogFind, func(fm *flowlog.FlowMessage, ds []*FlowMessageInterface, flowLogType FlowLogType) bool {
+				if handler(fm, ds, flowLogType) {
+					found()
+					return true
+				}
+				return false
+			}, flowLogType)
+		}
+	}
+	return loaders.Check(exist, watch)
+}
@@ -0,0 +1,174 @@
+//Package eformat provides output formats for messages obtained from EVE:
+//text (line per field), json, jsonl (json line per message), table and go template.
+package eformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"text/template"
+)
+
+//Kind is the kind of output format
+type Kind int
+
+//Kinds of output format
+const (
+	Text     Kind = iota // line per field
+	JSON                 // indented json
+	JSONL                // json line per message
+	Table                // columns with header
+	Template             // go template applied to json of message
+)
+
+const templatePrefix = "template="
+
+//Format prints messages in selected kind of output
+type Format struct {
+	Kind   Kind
+	tmpl   *template.Template
+	out    io.Writer
+	table  *tabwriter.Writer
+	header bool
+	stream bool
+	mu     sync.Mutex
+}
+
+//List return all supported formats
+func List() []string {
+	return []string{"text", "json", "jsonl", "table", templatePrefix + "<go-template>"}
+}
+
+//Parse return Format by name, empty name means text
+func Parse(name string) (*Format, error) {
+	f := &Format{out: os.Stdout}
+	switch {
+	case name == "" || name == "text":
+		f.Kind = Text
+	case name == "json":
+		f.Kind = JSON
+	case name == "jsonl":
+		f.Kind = JSONL
+	case name == "table":
+		f.Kind = Table
+	case strings.HasPrefix(name, templatePrefix):
+		tmpl, err := template.New("format").Option("missingkey=zero").Parse(strings.TrimPrefix(name, templatePrefix))
+		if err != nil {
+			return nil, fmt.Errorf("wrong template: %s", err)
+		}
+		f.Kind, f.tmpl = Template, tmpl
+	default:
+		return nil, fmt.Errorf("unknown format %s, use one of: %s", name, strings.Join(List(), ", "))
+	}
+	return f, nil
+}
+
+//SetOutput set writer for output (os.Stdout by default)
+func (f *Format) SetOutput(w io.Writer) {
+	f.out = w
+}
+
+//SetStream set flushing of table after every row for continuous output,
+//otherwise rows are aligned and printed with Flush
+func (f *Format) SetStream(stream bool) {
+	f.stream = stream
+}
+
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+//Marshal return json of obj, proto messages and slices of them are marshaled with jsonpb
+func Marshal(obj interface{}) ([]byte, error) {
+	if msg, ok := obj.(proto.Message); ok {
+		var buf bytes.Buffer
+		if err := (&jsonpb.Marshaler{}).Marshal(&buf, msg); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	if value := reflect.ValueOf(obj); value.Kind() == reflect.Slice && value.Type().Elem().Implements(protoMessageType) {
+		items := make([]json.RawMessage, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			item, err := Marshal(value.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return json.Marshal(items)
+	}
+	return json.Marshal(obj)
+}
+
+//Print print obj in json, jsonl or template format, template is applied to the decoded json of obj
+func (f *Format) Print(obj interface{}) error {
+	data, err := Marshal(obj)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch f.Kind {
+	case JSON:
+		var buf bytes.Buffer
+		if err = json.Indent(&buf, data, "", "  "); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(f.out, buf.String())
+	case Template:
+		var value interface{}
+		if err = json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err = f.tmpl.Execute(&buf, value); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(f.out, strings.TrimSuffix(buf.String(), "\n"))
+	default:
+		_, err = fmt.Fprintln(f.out, string(data))
+	}
+	return err
+}
+
+//Row print row of table with header printed before the first row
+func (f *Format) Row(header []string, columns ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.table == nil {
+		f.table = tabwriter.NewWriter(f.out, 1, 1, 1, ' ', 0)
+	}
+	if !f.header {
+		if _, err := fmt.Fprintln(f.table, strings.Join(header, "\t")); err != nil {
+			return err
+		}
+		f.header = true
+	}
+	for i, el := range columns {
+		columns[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(el)
+	}
+	if _, err := fmt.Fprintln(f.table, strings.Join(columns, "\t")); err != nil {
+		return err
+	}
+	if f.stream {
+		return f.table.Flush()
+	}
+	return nil
+}
+
+//Flush print buffered rows of table
+func (f *Format) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.table == nil {
+		return nil
+	}
+	return f.table.Flush()
+}
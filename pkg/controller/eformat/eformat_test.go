@@ -0,0 +1,65 @@
+package eformat
+
+import (
+	"bytes"
+	"github.com/lf-edge/eve/api/go/info"
+	"testing"
+)
+
+//TestFormat test printing of messages in all formats
+func TestFormat(t *testing.T) {
+	im := &info.ZInfoMsg{DevId: "dev", Ztype: info.ZInfoTypes_ZiDevice}
+	item := struct {
+		Source string
+		Msg    string
+	}{Source: "zedagent", Msg: "started"}
+	tests := []struct {
+		name     string
+		obj      interface{}
+		expected string
+	}{
+		{"json", im, "{\n  \"ztype\": \"ZiDevice\",\n  \"devId\": \"dev\"\n}\n"},
+		{"jsonl", im, "{\"ztype\":\"ZiDevice\",\"devId\":\"dev\"}\n"},
+		{"jsonl", item, "{\"Source\":\"zedagent\",\"Msg\":\"started\"}\n"},
+		{"template={{.devId}} {{.ztype}} {{.unknown}}", im, "dev ZiDevice <no value>\n"},
+		{"template={{.Source}}: {{.Msg}}\n", item, "zedagent: started\n"},
+	}
+	for _, test := range tests {
+		f, err := Parse(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		f.SetOutput(&buf)
+		if err = f.Print(test.obj); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, buf.String())
+		}
+	}
+
+	f, err := Parse("table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	f.SetOutput(&buf)
+	for _, row := range [][]string{{"zedagent", "started\tnow"}, {"nim", "up"}} {
+		if err = f.Row([]string{"SOURCE", "MSG"}, row...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "SOURCE   MSG\nzedagent started now\nnim      up\n"; buf.String() != expected {
+		t.Errorf("table: expected %q, got %q", expected, buf.String())
+	}
+
+	for _, name := range []string{"yaml", "template={{.devId"} {
+		if _, err = Parse(name); err == nil {
+			t.Errorf("expected error for %s", name)
+		}
+	}
+}
@@ -3,15 +3,17 @@
 package einfo
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/lf-edge/eden/pkg/controller/eformat"
+	"github.com/lf-edge/eden/pkg/controller/equery"
 	"github.com/lf-edge/eden/pkg/controller/loaders"
 	"github.com/lf-edge/eve/api/go/info"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
-	"reflect"
-	"regexp"
-	"strings"
+	"sort"
 	"time"
 )
 
@@ -19,36 +21,55 @@ import (
 //or false to continue
 type HandlerFunc func(im *info.ZInfoMsg, ds []*ZInfoMsgInterface, infoType ZInfoType) bool
 
-//QHandlerFunc must process info.ZInfoMsg with query parameters
-//and return true to exit or false to continue
-type QHandlerFunc func(im *info.ZInfoMsg, query map[string]string, infoType ZInfoType) []*ZInfoMsgInterface
+//QHandlerFunc must process info.ZInfoMsg with compiled query
+//and return selected objects
+type QHandlerFunc func(im *info.ZInfoMsg, query *equery.Query, infoType ZInfoType) []*ZInfoMsgInterface
 
 //ZInfoMsgInterface is an interface to pass between handlers
 type ZInfoMsgInterface interface{}
 
 type zInfoPacket struct {
-	upperType string
-	lowerType string
+	name string
+	path *equery.Path
 }
 
 //ZInfoType is an parameter for obtain particular info from files
 type ZInfoType *zInfoPacket
 
+//zInfoPath return ZInfoType with name for selection of objects by path
+func zInfoPath(name, path string) ZInfoType {
+	p, err := equery.ParsePath(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &zInfoPacket{name: name, path: p}
+}
+
 var (
-	//ZInfoDinfo can be used for filter GetNiinfo
-	ZInfoDinfo ZInfoType = &zInfoPacket{upperType: "GetDinfo"}
+	//ZInfoDinfo can be used for filter GetDinfo
+	ZInfoDinfo = zInfoPath("dinfo", "dinfo")
 	//ZInfoDevSW can be used for filter GetDinfo SwList
-	ZInfoDevSW ZInfoType = &zInfoPacket{upperType: "GetDinfo", lowerType: "SwList"}
+	ZInfoDevSW = zInfoPath("dinfo-swlist", "dinfo.swList[*]")
 	//ZInfoNetwork can be used for filter GetDinfo Network
-	ZInfoNetwork ZInfoType = &zInfoPacket{upperType: "GetDinfo", lowerType: "Network"}
+	ZInfoNetwork = zInfoPath("dinfo-network", "dinfo.network[*]")
 	//ZInfoNetworkInstance can be used for filter GetNiinfo
-	ZInfoNetworkInstance ZInfoType = &zInfoPacket{upperType: "GetNiinfo"}
+	ZInfoNetworkInstance = zInfoPath("niinfo", "niinfo")
 	//ZInfoAppInstance can be used for filter GetAinfo
-	ZInfoAppInstance ZInfoType = &zInfoPacket{upperType: "GetAinfo"}
+	ZInfoAppInstance = zInfoPath("ainfo", "ainfo")
 	//ZAll can be used for display all info items
-	ZAll ZInfoType = &zInfoPacket{}
+	ZAll = zInfoPath("all", "")
 )
 
+//ZInfoSelect return ZInfoType for selection of objects inside ZInfoMsg by path of fields separated by dots
+//with [*] for all elements and [N] for element N of lists, e.g. dinfo.storage[*] or ainfo.network[*].ipAddrs
+func ZInfoSelect(path string) (ZInfoType, error) {
+	p, err := equery.ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &zInfoPacket{name: path, path: p}, nil
+}
+
 //GetZInfoType return ZInfoType by name
 func GetZInfoType(name string) (ZInfoType, error) {
 	var zInfoType ZInfoType
@@ -102,12 +123,8 @@ func InfoPrn(im *info.ZInfoMsg) {
 func ZInfoPrn(im *info.ZInfoMsg, ds []*ZInfoMsgInterface, infoType ZInfoType) {
 	fmt.Println("ztype:", im.GetZtype())
 	fmt.Println("devId:", im.GetDevId())
-	if infoType.upperType != "" {
-		if infoType.lowerType != "" {
-			fmt.Printf("%s.%s:\n", infoType.upperType, infoType.lowerType)
-		} else {
-			fmt.Printf("%s:\n", infoType.upperType)
-		}
+	if infoType.path.String() != "" {
+		fmt.Printf("%s:\n", infoType.path)
 	}
 	for i, d := range ds {
 		fmt.Printf("[%d]: %s\n", i, *d)
@@ -116,6 +133,68 @@ func ZInfoPrn(im *info.ZInfoMsg, ds []*ZInfoMsgInterface, infoType ZInfoType) {
 	fmt.Println()
 }
 
+//ZInfoPrint print ZInfoMsg in format. For infoType other than ZAll only devId, ztype and atTimeStamp of ZInfoMsg
+//are printed with selected objects in items.
+func ZInfoPrint(im *info.ZInfoMsg, ds []*ZInfoMsgInterface, infoType ZInfoType, format *eformat.Format) error {
+	switch format.Kind {
+	case eformat.Text:
+		ZInfoPrn(im, ds, infoType)
+		return nil
+	case eformat.Table:
+		timestamp := ""
+		if im.GetAtTimeStamp() != nil {
+			timestamp = ptypes.TimestampString(im.GetAtTimeStamp())
+		}
+		for _, d := range ds {
+			item, err := eformat.Marshal(*d)
+			if err != nil {
+				return err
+			}
+			if err = format.Row([]string{"TIME", "ZTYPE", "TYPE", "ITEM"}, timestamp, im.GetZtype().String(), infoType.name, string(item)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if infoType == ZAll {
+		return format.Print(im)
+	}
+	data, err := eformat.Marshal(im)
+	if err != nil {
+		return err
+	}
+	var msg map[string]interface{}
+	if err = json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	selected := map[string]interface{}{"type": infoType.name}
+	for _, key := range []string{"devId", "ztype", "atTimeStamp"} {
+		if value, ok := msg[key]; ok {
+			selected[key] = value
+		}
+	}
+	items := make([]json.RawMessage, 0, len(ds))
+	for _, d := range ds {
+		item, err := eformat.Marshal(*d)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	selected["items"] = items
+	return format.Print(selected)
+}
+
+//HandleAllFormat return handler which prints all Info's selected by InfoWatch in format
+func HandleAllFormat(format *eformat.Format) HandlerFunc {
+	return func(im *info.ZInfoMsg, ds []*ZInfoMsgInterface, infoType ZInfoType) bool {
+		if err := ZInfoPrint(im, ds, infoType, format); err != nil {
+			log.Errorf("ZInfoPrint: %s", err)
+		}
+		return false
+	}
+}
+
 //HandleFirst runs once and interrupts the workflow of InfoWatch
 func HandleFirst(im *info.ZInfoMsg, ds []*ZInfoMsgInterface, infoType ZInfoType) bool {
 	//InfoPrn(im, ds)
@@ -130,90 +209,19 @@ func HandleAll(im *info.ZInfoMsg, ds []*ZInfoMsgInterface, infoType ZInfoType) b
 	return false
 }
 
-func processElem(value reflect.Value, query map[string]string) bool {
-
-	matched := true
-	var err error
-	for k, v := range query {
-		// Uppercase of filed's name first letter
-		n := strings.Title(k)
-		f := fmt.Sprint(reflect.Indirect(value).FieldByName(n))
-		matched, err = regexp.Match(v, []byte(f))
-		if err != nil {
-			log.Print(err)
-			return false
-		}
-		if matched == false {
-			break
-		}
-	}
-	return matched
-}
-
-//ZInfoFind finds ZInfoMsg records with 'devid' and ZInfoDevSWF structure fields
-//by reqexps in 'query'
-func ZInfoFind(im *info.ZInfoMsg, query map[string]string, infoType ZInfoType) []*ZInfoMsgInterface {
+//ZInfoFind finds objects selected by path of infoType inside ZInfoMsg which match 'query'
+func ZInfoFind(im *info.ZInfoMsg, query *equery.Query, infoType ZInfoType) []*ZInfoMsgInterface {
 	var dsws []*ZInfoMsgInterface
-
-	devid, ok := query["devId"]
-	if ok {
-		if devid != im.DevId {
-			return nil
-		}
-	}
-
-	delete(query, "devId")
-
-	if infoType.upperType != "" {
-		dInfo := reflect.ValueOf(im).MethodByName(infoType.upperType).Call([]reflect.Value{})
-		if len(dInfo) != 1 || dInfo[0].Interface() == nil {
-			return nil
-		}
-		if reflect.Indirect(reflect.ValueOf(dInfo[0].Interface())).Kind() == reflect.Invalid {
-			return nil
-		}
-		if infoType.lowerType != "" && infoType.upperType != "" {
-			dInfoField := reflect.Indirect(reflect.ValueOf(dInfo[0].Interface())).FieldByName(infoType.lowerType)
-			for i := 0; i < dInfoField.Len(); i++ {
-				d := dInfoField.Index(i)
-				if processElem(d, query) {
-					var strValT ZInfoMsgInterface = d.Interface()
-					dsws = append(dsws, &strValT)
-				}
-			}
-		} else if infoType.upperType != "" {
-			d := dInfo[0]
-			if processElem(d, query) {
-				var strValT ZInfoMsgInterface = d.Interface()
-				dsws = append(dsws, &strValT)
-			}
-		}
-	} else {
-		var strValT ZInfoMsgInterface = im
+	for _, d := range infoType.path.Find(im, query) {
+		var strValT ZInfoMsgInterface = d
 		dsws = append(dsws, &strValT)
 	}
 	return dsws
 }
 
-//InfoFind find ZInfoMsg records by reqexps in 'query' corresponded to devId and
-//ZInfoDevSW structure.
-func InfoFind(im *info.ZInfoMsg, query map[string]string) int {
-	matched := 1
-	for k, v := range query {
-		// Uppercase of filed's name first letter
-		n := strings.Title(k)
-		// Find field in structure by Titlized() name 'n'
-		r := reflect.ValueOf(im)
-		f := fmt.Sprint(reflect.Indirect(r).FieldByName(n))
-		matched, err := regexp.Match(v, []byte(f))
-		if err != nil {
-			return -1
-		}
-		if matched == false {
-			return 0
-		}
-	}
-	return matched
+//InfoFind return true if ZInfoMsg matches compiled 'query'
+func InfoFind(im *info.ZInfoMsg, query *equery.Query) bool {
+	return query.Match(im)
 }
 
 //InfoCheckerMode is InfoExist, InfoNew and InfoAny
@@ -226,13 +234,30 @@ const (
 	InfoAny                          // use both mechanisms
 )
 
+//infoProcess compiles 'query' once and return function which checks 'devId' of message
+//and process objects selected by 'qhandler' with other fields of 'query'
 func infoProcess(query map[string]string, qhandler QHandlerFunc, handler HandlerFunc, infoType ZInfoType) loaders.ProcessFunction {
+	fields := make(map[string]string, len(query))
+	for k, v := range query {
+		fields[k] = v
+	}
+	devID := fields["devId"]
+	delete(fields, "devId")
+	q, err := equery.FromMap(fields)
+	if err != nil {
+		return func(bytes []byte) (bool, error) {
+			return false, err
+		}
+	}
 	return func(bytes []byte) (bool, error) {
 		im, err := ParseZInfoMsg(bytes)
 		if err != nil {
 			return true, nil
 		}
-		ds := qhandler(&im, query, infoType)
+		if devID != "" && devID != im.DevId {
+			return true, nil
+		}
+		ds := qhandler(&im, q, infoType)
 		if ds != nil {
 			if handler(&im, ds, infoType) {
 				return false, nil
@@ -242,40 +267,98 @@ func infoProcess(query map[string]string, qhandler QHandlerFunc, handler Handler
 	}
 }
 
+//infoTime return AtTimeStamp of ZInfoMsg or zero time if it is not set
+func infoTime(im *info.ZInfoMsg) time.Time {
+	t, err := ptypes.Timestamp(im.GetAtTimeStamp())
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
 //InfoLast search Info files in the 'filepath' directory according to the 'query' parameters accepted by the 'qhandler' function and subsequent process using the 'handler' function.
+//Range of info (loaders.SinceKey, loaders.UntilKey and loaders.TailKey of 'query') is passed to the loader,
+//with loaders.TailKey only the last found info are processed from oldest to newest.
 func InfoLast(loader loaders.Loader, query map[string]string, qhandler QHandlerFunc, handler HandlerFunc, infoType ZInfoType) error {
-	return loader.ProcessExisting(infoProcess(query, qhandler, handler, infoType), loaders.InfoType)
+	query, r, err := loaders.RangeFromQuery(query)
+	if err != nil {
+		return err
+	}
+	if r.IsEmpty() {
+		return loader.ProcessExisting(infoProcess(query, qhandler, handler, infoType), loaders.InfoType)
+	}
+	loaderRange := r
+	if len(query) != 0 || infoType != ZAll {
+		// messages with found objects are unknown before processing
+		loaderRange.Tail = 0
+	}
+	loader.SetRange(loaderRange)
+	type found struct {
+		im *info.ZInfoMsg
+		ds []*ZInfoMsgInterface
+	}
+	var items []found
+	err = loader.ProcessExisting(infoProcess(query, qhandler, func(im *info.ZInfoMsg, ds []*ZInfoMsgInterface, infoType ZInfoType) bool {
+		if r.Tail == 0 {
+			return handler(im, ds, infoType)
+		}
+		items = append(items, found{im: im, ds: ds})
+		return false
+	}, infoType), loaders.InfoType)
+	if err != nil || r.Tail == 0 {
+		return err
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return infoTime(items[i].im).Before(infoTime(items[j].im))
+	})
+	if len(items) > r.Tail {
+		items = items[len(items)-r.Tail:]
+	}
+	for _, el := range items {
+		if handler(el.im, el.ds, infoType) {
+			break
+		}
+	}
+	return nil
 }
 
 //InfoWatch monitors the change of Info files in the 'filepath' directory according to the 'query' parameters accepted by the 'qhandler' function and subsequent processing using the 'handler' function with 'timeoutSeconds'.
+//With loaders.ConsumerKey of 'query' info is processed from the last processed one of the watcher.
 func InfoWatch(loader loaders.Loader, query map[string]string, qhandler QHandlerFunc, handler HandlerFunc, infoType ZInfoType, timeoutSeconds time.Duration) error {
+	//consumer must be taken before RangeFromQuery, which drops loaders.ConsumerKey
+	query, err := loaders.ConsumerFromQuery(loader, query)
+	if err != nil {
+		return err
+	}
+	if query, _, err = loaders.RangeFromQuery(query); err != nil {
+		return err
+	}
 	return loader.ProcessStream(infoProcess(query, qhandler, handler, infoType), loaders.InfoType, timeoutSeconds)
 }
 
 //InfoChecker checks the information in the regular expression pattern 'query' and processes the info.ZInfoMsg found by the function 'handler' from existing files (mode=InfoExist), new files (mode=InfoNew) or any of them (mode=InfoAny) with timeout (0 for infinite).
-func InfoChecker(loader loaders.Loader, devUUID uuid.UUID, query map[string]string, infoType ZInfoType, handler HandlerFunc, mode InfoCheckerMode, timeout time.Duration) (err error) {
+//With mode=InfoExist loaders.ErrNotFound is returned if no info is found.
+func InfoChecker(loader loaders.Loader, devUUID uuid.UUID, query map[string]string, infoType ZInfoType, handler HandlerFunc, mode InfoCheckerMode, timeout time.Duration) error {
 	loader.SetUUID(devUUID)
-	done := make(chan error)
-
+	var exist loaders.ExistFunc
+	var watch loaders.WatchFunc
 	// observe new files
 	if mode == InfoNew || mode == InfoAny {
-		go func() {
-			done <- InfoWatch(loader.Clone(), query, ZInfoFind, handler, infoType, timeout)
-		}()
+		watch = func() error {
+			return InfoWatch(loader.Clone(), query, ZInfoFind, handler, infoType, timeout)
+		}
 	}
 	// check info by pattern in existing files
 	if mode == InfoExist || mode == InfoAny {
-		go func() {
-			handler := func(im *info.ZInfoMsg, ds []*ZInfoMsgInterface, infoType ZInfoType) (result bool) {
-				if result = handler(im, ds, infoType); result {
-					done <- nil
+		exist = func(found func()) error {
+			return InfoLast(loader.Clone(), query, ZInfoFind, func(im *info.ZInfoMsg, ds []*ZInfoMsgInterface, infoType ZInfoType) bool {
+				if handler(im, ds, infoType) {
+					found()
+					return true
 				}
-				return
-			}
-			if err = InfoLast(loader.Clone(), query, ZInfoFind, handler, infoType); err != nil {
-				done <- err
-			}
-		}()
+				return false
+			}, infoType)
+		}
 	}
-	return <-done
+	return loaders.Check(exist, watch)
 }
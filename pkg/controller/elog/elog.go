@@ -6,13 +6,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/golang/protobuf/jsonpb"
+	"github.com/lf-edge/eden/pkg/controller/eformat"
+	"github.com/lf-edge/eden/pkg/controller/equery"
 	"github.com/lf-edge/eden/pkg/controller/loaders"
 	"github.com/lf-edge/eve/api/go/logs"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
-	"reflect"
 	"regexp"
-	"strings"
+	"sort"
 	"time"
 )
 
@@ -45,15 +46,16 @@ func ParseLogBundle(data []byte) (logBundle logs.LogBundle, err error) {
 	return lb, err
 }
 
+var logItemRe = regexp.MustCompile(`(?P<time>[^{]*):\s*(?P<json>{.*})`)
+
 //ParseLogItem apply regexp on logItem
 func ParseLogItem(data string) (logItem LogItem, err error) {
-	pattern := `(?P<time>[^{]*):\s*(?P<json>{.*})`
-	re := regexp.MustCompile(pattern)
+	re := logItemRe
 	parts := re.SubexpNames()
 	result := re.FindAllStringSubmatch(data, -1)
 	m := map[string]string{}
 	if len(result) == 0 {
-		log.Debugf("error in FindAllStringSubmatch for %s and string %s. Will use new api", pattern, data)
+		log.Debugf("error in FindAllStringSubmatch for %s and string %s. Will use new api", re, data)
 		var le LogItem
 		err = json.Unmarshal([]byte(data), &le)
 		return le, err
@@ -67,24 +69,9 @@ func ParseLogItem(data string) (logItem LogItem, err error) {
 	return le, err
 }
 
-//LogItemFind find LogItem records by reqexps in 'query' corresponded to LogItem structure.
-func LogItemFind(le LogItem, query map[string]string) int {
-	matched := 1
-	for k, v := range query {
-		// Uppercase of filed's name first letter
-		n := strings.Title(k)
-		// Find field in structure by Titlized() name 'n'
-		r := reflect.ValueOf(le)
-		f := fmt.Sprint(reflect.Indirect(r).FieldByName(n))
-		matched, err := regexp.Match(v, []byte(f))
-		if err != nil {
-			return -1
-		}
-		if matched == false {
-			return 0
-		}
-	}
-	return matched
+//LogItemFind return true if LogItem matches compiled 'query'
+func LogItemFind(le LogItem, query *equery.Query) bool {
+	return query.Match(&le)
 }
 
 //HandleFirst runs once and interrupts the workflow of LogWatch
@@ -112,6 +99,33 @@ func LogPrn(le *LogItem) {
 	fmt.Println()
 }
 
+//LogItemPrint print LogItem in format
+func LogItemPrint(le *LogItem, format *eformat.Format) error {
+	switch format.Kind {
+	case eformat.Text:
+		LogPrn(le)
+		return nil
+	case eformat.Table:
+		pid := ""
+		if le.Pid != nil {
+			pid = fmt.Sprint(le.Pid)
+		}
+		return format.Row([]string{"TIME", "SOURCE", "LEVEL", "PID", "MSG"}, le.Time, le.Source, le.Level, pid, le.Msg)
+	default:
+		return format.Print(le)
+	}
+}
+
+//HandleAllFormat return handler which prints all Logs selected by LogWatch in format
+func HandleAllFormat(format *eformat.Format) HandlerFunc {
+	return func(le *LogItem) bool {
+		if err := LogItemPrint(le, format); err != nil {
+			log.Errorf("LogItemPrint: %s", err)
+		}
+		return false
+	}
+}
+
 //HandlerFunc must process LogItem and return true to exit
 //or false to continue
 type HandlerFunc func(*LogItem) bool
@@ -125,6 +139,12 @@ func logProcess(query map[string]string, handler HandlerFunc) loaders.ProcessFun
 	if ok {
 		delete(query, "eveVersion")
 	}
+	q, err := equery.FromMap(query)
+	if err != nil {
+		return func(bytes []byte) (bool, error) {
+			return false, err
+		}
+	}
 	return func(bytes []byte) (bool, error) {
 		lb, err := ParseLogBundle(bytes)
 		if err != nil {
@@ -143,7 +163,7 @@ func logProcess(query map[string]string, handler HandlerFunc) loaders.ProcessFun
 				log.Debugf("logProcess: %s", err)
 				continue
 			}
-			if LogItemFind(le, query) == 1 {
+			if q.Match(&le) {
 				if handler(&le) {
 					return false, nil
 				}
@@ -155,41 +175,124 @@ func logProcess(query map[string]string, handler HandlerFunc) loaders.ProcessFun
 
 //LogWatch monitors the change of Log files in the 'filepath' directory
 //according to the 'query' reqexps and processing using the 'handler' function.
+//With loaders.ConsumerKey of 'query' logs are processed from the last processed one of the watcher.
 func LogWatch(loader loaders.Loader, query map[string]string, handler HandlerFunc, timeoutSeconds time.Duration) error {
+	//consumer must be taken before RangeFromQuery, which drops loaders.ConsumerKey
+	query, err := loaders.ConsumerFromQuery(loader, query)
+	if err != nil {
+		return err
+	}
+	if query, _, err = loaders.RangeFromQuery(query); err != nil {
+		return err
+	}
 	return loader.ProcessStream(logProcess(query, handler), loaders.LogsType, timeoutSeconds)
 }
 
+//logBundleDelay is the maximum expected delay between log item and timestamp of its bundle
+const logBundleDelay = time.Minute
+
+//logItemTime return time of LogItem or zero time if it cannot be parsed
+func logItemTime(le *LogItem) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, le.Time)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
 //LogLast function process Log files in the 'filepath' directory
-//according to the 'query' reqexps and return last founded item
+//according to the 'query' reqexps and return last founded item.
+//Range of logs (loaders.SinceKey, loaders.UntilKey and loaders.TailKey of 'query') is passed to the loader,
+//with loaders.TailKey only the last items are processed from oldest to newest.
 func LogLast(loader loaders.Loader, query map[string]string, handler HandlerFunc) error {
-	return loader.ProcessExisting(logProcess(query, handler), loaders.LogsType)
+	query, r, err := loaders.RangeFromQuery(query)
+	if err != nil {
+		return err
+	}
+	if r.IsEmpty() {
+		return loader.ProcessExisting(logProcess(query, handler), loaders.LogsType)
+	}
+	loaderRange := r
+	if !loaderRange.Until.IsZero() {
+		loaderRange.Until = loaderRange.Until.Add(logBundleDelay)
+	}
+	if len(query) != 0 {
+		// bundles with matched items are unknown before processing
+		loaderRange.Tail = 0
+	}
+	loader.SetRange(loaderRange)
+	var items []*LogItem
+	err = loader.ProcessExisting(logProcess(query, func(le *LogItem) bool {
+		if !r.Contains(logItemTime(le)) {
+			return false
+		}
+		if r.Tail == 0 {
+			return handler(le)
+		}
+		items = append(items, le)
+		return false
+	}), loaders.LogsType)
+	if err != nil || r.Tail == 0 {
+		return err
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return logItemTime(items[i]).Before(logItemTime(items[j]))
+	})
+	if len(items) > r.Tail {
+		items = items[len(items)-r.Tail:]
+	}
+	for _, le := range items {
+		if handler(le) {
+			break
+		}
+	}
+	return nil
+}
+
+//BundleHandlerFunc must process LogBundle and return true to exit
+//or false to continue
+type BundleHandlerFunc func(*logs.LogBundle) bool
+
+//LogBundleLast process LogBundles without parsing of items with range
+//(loaders.SinceKey, loaders.UntilKey and loaders.TailKey of 'query') using the 'handler' function
+func LogBundleLast(loader loaders.Loader, query map[string]string, handler BundleHandlerFunc) error {
+	_, r, err := loaders.RangeFromQuery(query)
+	if err != nil {
+		return err
+	}
+	loader.SetRange(r)
+	return loader.ProcessExisting(func(bytes []byte) (bool, error) {
+		var lb logs.LogBundle
+		if err := jsonpb.UnmarshalString(string(bytes), &lb); err != nil {
+			return true, nil
+		}
+		return !handler(&lb), nil
+	}, loaders.LogsType)
 }
 
 //LogChecker check logs by pattern from existence files with LogLast and use LogWatchWithTimeout with timeout for observe new files
-func LogChecker(loader loaders.Loader, devUUID uuid.UUID, q map[string]string, handler HandlerFunc, mode LogCheckerMode, timeout time.Duration) (err error) {
+//With mode=LogExist loaders.ErrNotFound is returned if no logs are found.
+func LogChecker(loader loaders.Loader, devUUID uuid.UUID, q map[string]string, handler HandlerFunc, mode LogCheckerMode, timeout time.Duration) error {
 	loader.SetUUID(devUUID)
-	done := make(chan error)
-
+	var exist loaders.ExistFunc
+	var watch loaders.WatchFunc
 	// observe new files
 	if mode == LogNew || mode == LogAny {
-		go func() {
-			done <- LogWatch(loader.Clone(), q, handler, timeout)
-		}()
+		watch = func() error {
+			return LogWatch(loader.Clone(), q, handler, timeout)
+		}
 	}
 	// check info by pattern in existing files
 	if mode == LogExist || mode == LogAny {
-		go func() {
-			handler := func(item *LogItem) (result bool) {
-				if result = handler(item); result {
-					done <- nil
+		exist = func(found func()) error {
+			return LogLast(loader.Clone(), q, func(item *LogItem) bool {
+				if handler(item) {
+					found()
+					return true
 				}
-				return
-			}
-			err := LogLast(loader.Clone(), q, handler)
-			if err != nil {
-				done <- err
-			}
-		}()
+				return false
+			})
+		}
 	}
-	return <-done
+	return loaders.Check(exist, watch)
 }
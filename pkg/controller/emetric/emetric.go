@@ -0,0 +1,205 @@
+//Package emetric provides primitives for searching and processing data
+//in Metric files.
+package emetric
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/lf-edge/eden/pkg/controller/equery"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eve/api/go/metrics"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+//HandlerFunc must process metrics.ZMetricMsg and return true to exit
+//or false to continue
+type HandlerFunc func(mm *metrics.ZMetricMsg, ds []*ZMetricMsgInterface, metricType ZMetricType) bool
+
+//QHandlerFunc must process metrics.ZMetricMsg with compiled query
+//and return selected items
+type QHandlerFunc func(mm *metrics.ZMetricMsg, query *equery.Query, metricType ZMetricType) []*ZMetricMsgInterface
+
+//ZMetricMsgInterface is an interface to pass between handlers
+type ZMetricMsgInterface interface{}
+
+type zMetricPacket struct {
+	name string
+	path *equery.Path
+}
+
+//ZMetricType is an parameter for obtain particular metrics from files
+type ZMetricType *zMetricPacket
+
+//zMetricPath return ZMetricType with name for selection of objects by path
+func zMetricPath(name, path string) ZMetricType {
+	p, err := equery.ParsePath(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &zMetricPacket{name: name, path: p}
+}
+
+var (
+	//ZMetricDevice can be used for filter GetDm
+	ZMetricDevice = zMetricPath("Dm", "dm")
+	//ZMetricApp can be used for filter GetAm
+	ZMetricApp = zMetricPath("Am", "am[*]")
+	//ZMetricNetworkInstance can be used for filter GetNm
+	ZMetricNetworkInstance = zMetricPath("Nm", "nm[*]")
+	//ZMetricVolume can be used for filter GetVm
+	ZMetricVolume = zMetricPath("Vm", "vm[*]")
+	//ZAll can be used for display all metrics
+	ZAll = zMetricPath("", "")
+)
+
+//GetZMetricType return ZMetricType by name
+func GetZMetricType(name string) (ZMetricType, error) {
+	var zMetricType ZMetricType
+	switch name {
+	case "all":
+		zMetricType = ZAll
+	case "dm":
+		zMetricType = ZMetricDevice
+	case "am":
+		zMetricType = ZMetricApp
+	case "nm":
+		zMetricType = ZMetricNetworkInstance
+	case "vm":
+		zMetricType = ZMetricVolume
+	default:
+		return nil, fmt.Errorf("not implemented: %s", name)
+	}
+	return zMetricType, nil
+}
+
+//ListZMetricType return all implemented
+func ListZMetricType() []string {
+	return []string{"all", "dm", "am", "nm", "vm"}
+}
+
+//ParseZMetricMsg unmarshal ZMetricMsg
+func ParseZMetricMsg(data []byte) (*metrics.ZMetricMsg, error) {
+	var zm metrics.ZMetricMsg
+	if err := jsonpb.UnmarshalString(string(data), &zm); err != nil {
+		return nil, err
+	}
+	return &zm, nil
+}
+
+//ZMetricPrn print data from ZMetricMsg structure
+func ZMetricPrn(mm *metrics.ZMetricMsg, ds []*ZMetricMsgInterface, metricType ZMetricType) {
+	fmt.Println("devId:", mm.GetDevID())
+	if metricType.name != "" {
+		fmt.Printf("%s:\n", metricType.name)
+	}
+	for i, d := range ds {
+		fmt.Printf("[%d]: %s\n", i, *d)
+	}
+	fmt.Println("atTimeStamp:", mm.GetAtTimeStamp())
+	fmt.Println()
+}
+
+//HandleFirst runs once and interrupts the workflow of MetricWatch
+func HandleFirst(mm *metrics.ZMetricMsg, ds []*ZMetricMsgInterface, metricType ZMetricType) bool {
+	ZMetricPrn(mm, ds, metricType)
+	return true
+}
+
+//HandleAll runs for all Metrics selected by MetricWatch
+func HandleAll(mm *metrics.ZMetricMsg, ds []*ZMetricMsgInterface, metricType ZMetricType) bool {
+	ZMetricPrn(mm, ds, metricType)
+	return false
+}
+
+//ZMetricFind finds items selected by 'metricType' inside ZMetricMsg which match 'query'.
+//Nested fields are addressed with dots (e.g. 'memory.usedMem')
+func ZMetricFind(mm *metrics.ZMetricMsg, query *equery.Query, metricType ZMetricType) []*ZMetricMsgInterface {
+	var items []*ZMetricMsgInterface
+	for _, d := range metricType.path.Find(mm, query) {
+		var strValT ZMetricMsgInterface = d
+		items = append(items, &strValT)
+	}
+	return items
+}
+
+//MetricCheckerMode is MetricExist, MetricNew and MetricAny
+type MetricCheckerMode int
+
+// MetricChecker modes MetricExist, MetricNew and MetricAny.
+const (
+	MetricExist MetricCheckerMode = iota // just look to existing files
+	MetricNew                            // wait for new files
+	MetricAny                            // use both mechanisms
+)
+
+//metricProcess compiles 'query' once and return function which checks 'devId' of message
+//and process items selected by 'qhandler'
+func metricProcess(query map[string]string, qhandler QHandlerFunc, handler HandlerFunc, metricType ZMetricType) loaders.ProcessFunction {
+	fields := make(map[string]string, len(query))
+	for k, v := range query {
+		fields[k] = v
+	}
+	devID := fields["devId"]
+	delete(fields, "devId")
+	q, err := equery.FromMap(fields)
+	if err != nil {
+		return func(bytes []byte) (bool, error) {
+			return false, err
+		}
+	}
+	return func(bytes []byte) (bool, error) {
+		mm, err := ParseZMetricMsg(bytes)
+		if err != nil {
+			return true, nil
+		}
+		if devID != "" && devID != mm.DevID {
+			return true, nil
+		}
+		ds := qhandler(mm, q, metricType)
+		if ds != nil {
+			if handler(mm, ds, metricType) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+//MetricLast search Metric files according to the 'query' parameters accepted by the 'qhandler' function and subsequent process using the 'handler' function.
+func MetricLast(loader loaders.Loader, query map[string]string, qhandler QHandlerFunc, handler HandlerFunc, metricType ZMetricType) error {
+	return loader.ProcessExisting(metricProcess(query, qhandler, handler, metricType), loaders.MetricsType)
+}
+
+//MetricWatch monitors the change of Metric files according to the 'query' parameters accepted by the 'qhandler' function and subsequent processing using the 'handler' function with 'timeoutSeconds'.
+func MetricWatch(loader loaders.Loader, query map[string]string, qhandler QHandlerFunc, handler HandlerFunc, metricType ZMetricType, timeoutSeconds time.Duration) error {
+	return loader.ProcessStream(metricProcess(query, qhandler, handler, metricType), loaders.MetricsType, timeoutSeconds)
+}
+
+//MetricChecker checks the metrics in the regular expression pattern 'query' and processes the metrics.ZMetricMsg found by the function 'handler' from existing files (mode=MetricExist), new files (mode=MetricNew) or any of them (mode=MetricAny) with timeout (0 for infinite).
+//With mode=MetricExist loaders.ErrNotFound is returned if no metrics are found.
+func MetricChecker(loader loaders.Loader, devUUID uuid.UUID, query map[string]string, metricType ZMetricType, handler HandlerFunc, mode MetricCheckerMode, timeout time.Duration) error {
+	loader.SetUUID(devUUID)
+	var exist loaders.ExistFunc
+	var watch loaders.WatchFunc
+	// observe new files
+	if mode == MetricNew || mode == MetricAny {
+		watch = func() error {
+			return MetricWatch(loader.Clone(), query, ZMetricFind, handler, metricType, timeout)
+		}
+	}
+	// check metrics by pattern in existing files
+	if mode == MetricExist || mode == MetricAny {
+		exist = func(found func()) error {
+			return MetricLast(loader.Clone(), query, ZMetricFind, func(mm *metrics.ZMetricMsg, ds []*ZMetricMsgInterface, metricType ZMetricType) bool {
+				if handler(mm, ds, metricType) {
+					found()
+					return true
+				}
+				return false
+			}, metricType)
+		}
+	}
+	return loaders.Check(exist, watch)
+}
@@ -0,0 +1,712 @@
+//Package equery provides expression language for searching in logs and info of EVE.
+//
+//Expression consists of comparisons of fields with values joined by and, or, not and parentheses:
+//	level in (error, fatal) and (source = zedagent or source = zedmanager) and not msg ~ lisp
+//	time > "2020-05-13T10:00:00Z"
+//	dinfo.network.IPAddrs ~ "^192\.168\."
+//Fields are paths separated by dots, slices match if any of elements matches.
+//Operators are = != < <= > >= ~ (regexp) !~ in (...) and not in (...).
+//Values are compared as numbers or times (RFC3339, "2006-01-02 15:04:05" or "2006-01-02" in local time zone)
+//if both sides are numbers or times, strings are compared without case.
+//Expression is compiled once and may be matched against many objects.
+//
+//Path selects objects inside object, [*] selects all elements of slice and [N] selects element N:
+//	dinfo.network[*].IPAddrs
+package equery
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ExprKey is key of map query with expression, other keys of map are field:regexp pairs
+const ExprKey = "@expr"
+
+//Query is compiled expression
+type Query struct {
+	src  string
+	root node
+}
+
+type node interface {
+	match(value reflect.Value) bool
+}
+
+type andNode []node
+
+func (n andNode) match(value reflect.Value) bool {
+	for _, el := range n {
+		if !el.match(value) {
+			return false
+		}
+	}
+	return true
+}
+
+type orNode []node
+
+func (n orNode) match(value reflect.Value) bool {
+	for _, el := range n {
+		if el.match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+type notNode struct {
+	node node
+}
+
+func (n notNode) match(value reflect.Value) bool {
+	return !n.node.match(value)
+}
+
+//literal is value of expression with number and time parsed on compile
+type literal struct {
+	str    string
+	lower  string
+	num    float64
+	isNum  bool
+	tm     time.Time
+	isTime bool
+}
+
+type pathElem struct {
+	name    string
+	title   string
+	indexed bool
+	all     bool
+	index   int
+}
+
+type cmpNode struct {
+	path   []pathElem
+	op     string
+	negate bool
+	values []*literal
+	re     *regexp.Regexp
+}
+
+func (n *cmpNode) match(value reflect.Value) bool {
+	matched := false
+	for _, v := range resolve(value, n.path, nil) {
+		if n.matchValue(v) {
+			matched = true
+			break
+		}
+	}
+	return matched != n.negate
+}
+
+func (n *cmpNode) matchValue(v reflect.Value) bool {
+	switch n.op {
+	case "~":
+		return n.re.MatchString(toString(v))
+	case "in":
+		for _, el := range n.values {
+			if compare(v, el) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	res := compare(v, n.values[0])
+	switch n.op {
+	case "=":
+		return res == 0
+	case "<":
+		return res < 0
+	case "<=":
+		return res <= 0
+	case ">":
+		return res > 0
+	case ">=":
+		return res >= 0
+	}
+	return false
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+//parseTime parses time in local time zone if zone is not set, as --since and --until are parsed
+func parseTime(s string) (time.Time, bool) {
+	//fast check to not try layouts for all strings
+	if len(s) < 10 || s[4] != '-' || s[7] != '-' {
+		return time.Time{}, false
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func newLiteral(s string) *literal {
+	lit := &literal{str: s, lower: strings.ToLower(s)}
+	if num, err := strconv.ParseFloat(s, 64); err == nil {
+		lit.num, lit.isNum = num, true
+	}
+	lit.tm, lit.isTime = parseTime(s)
+	return lit
+}
+
+//timestamper is implemented by protobuf Timestamp
+type timestamper interface {
+	GetSeconds() int64
+	GetNanos() int32
+}
+
+func asTimestamp(v reflect.Value) (timestamper, bool) {
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Type().Elem().Name() != "Timestamp" || !v.CanInterface() {
+		return nil, false
+	}
+	ts, ok := v.Interface().(timestamper)
+	return ts, ok
+}
+
+func toNumber(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		num, err := strconv.ParseFloat(v.String(), 64)
+		return num, err == nil
+	}
+	return 0, false
+}
+
+func toTime(v reflect.Value) (time.Time, bool) {
+	if ts, ok := asTimestamp(v); ok {
+		return time.Unix(ts.GetSeconds(), int64(ts.GetNanos())).UTC(), true
+	}
+	if v.Kind() == reflect.String {
+		return parseTime(v.String())
+	}
+	if v.CanInterface() {
+		if t, ok := v.Interface().(time.Time); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func toString(v reflect.Value) string {
+	if t, ok := asTimestamp(v); ok {
+		return time.Unix(t.GetSeconds(), int64(t.GetNanos())).UTC().Format(time.RFC3339Nano)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	if !v.CanInterface() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+//compare return -1, 0 or 1 comparing v with lit as numbers, times or strings without case
+func compare(v reflect.Value, lit *literal) int {
+	if lit.isNum {
+		if num, ok := toNumber(v); ok {
+			switch {
+			case num < lit.num:
+				return -1
+			case num > lit.num:
+				return 1
+			}
+			return 0
+		}
+	}
+	if lit.isTime {
+		if t, ok := toTime(v); ok {
+			switch {
+			case t.Before(lit.tm):
+				return -1
+			case t.After(lit.tm):
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(toString(v)), lit.lower)
+}
+
+//field return field of struct, value of map or result of getter (for oneof fields of protobuf) by name
+func field(value reflect.Value, el pathElem) reflect.Value {
+	if value.Kind() == reflect.Map && value.Type().Key().Kind() == reflect.String {
+		return value.MapIndex(reflect.ValueOf(el.name).Convert(value.Type().Key()))
+	}
+	if v := reflect.Indirect(value); v.Kind() == reflect.Struct {
+		if f := v.FieldByName(el.title); f.IsValid() {
+			return f
+		}
+		if f := v.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, el.name) }); f.IsValid() {
+			return f
+		}
+	}
+	if m := value.MethodByName("Get" + el.title); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+		return m.Call(nil)[0]
+	}
+	return reflect.Value{}
+}
+
+//resolve appends values of path inside value to result, all elements of slices are used
+func resolve(value reflect.Value, path []pathElem, result []reflect.Value) []reflect.Value {
+	for value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return result
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() || ((value.Kind() == reflect.Ptr || value.Kind() == reflect.Map || value.Kind() == reflect.Slice) && value.IsNil()) {
+		return result
+	}
+	if isList(value) {
+		for i := 0; i < value.Len(); i++ {
+			result = resolve(value.Index(i), path, result)
+		}
+		return result
+	}
+	if len(path) == 0 {
+		return append(result, value)
+	}
+	return resolve(field(value, path[0]), path[1:], result)
+}
+
+//Path is compiled path for selection of objects inside object, e.g. dinfo.network[*].IPAddrs
+type Path struct {
+	src   string
+	elems []pathElem
+}
+
+var pathElemRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(?:\[(\*|\d+)\])?$`)
+
+//ParsePath compiles path of fields separated by dots, [*] after field selects all elements of slice
+//and [N] selects element N of slice. Empty path selects object itself.
+func ParsePath(src string) (*Path, error) {
+	p := &Path{src: src}
+	if strings.TrimSpace(src) == "" {
+		return p, nil
+	}
+	for _, part := range strings.Split(src, ".") {
+		m := pathElemRe.FindStringSubmatch(strings.TrimSpace(part))
+		if m == nil {
+			return nil, fmt.Errorf("wrong element %q of path %s", part, src)
+		}
+		el := pathElem{name: m[1], title: strings.Title(m[1])}
+		switch m[2] {
+		case "":
+		case "*":
+			el.indexed, el.all = true, true
+		default:
+			index, err := strconv.Atoi(m[2])
+			if err != nil {
+				return nil, fmt.Errorf("wrong index of %q in path %s: %s", part, src, err)
+			}
+			el.indexed, el.index = true, index
+		}
+		p.elems = append(p.elems, el)
+	}
+	return p, nil
+}
+
+//Select return objects selected by path inside obj. Slices without index are walked through
+//if path continues and returned as is at the end of path.
+func (p *Path) Select(obj interface{}) []interface{} {
+	var result []interface{}
+	for _, v := range selectPath(reflect.ValueOf(obj), p.elems, nil) {
+		if v.CanInterface() {
+			result = append(result, v.Interface())
+		}
+	}
+	return result
+}
+
+//Find return objects selected by path inside obj which match query
+func (p *Path) Find(obj interface{}, q *Query) []interface{} {
+	var result []interface{}
+	for _, el := range p.Select(obj) {
+		if q.Match(el) {
+			result = append(result, el)
+		}
+	}
+	return result
+}
+
+//String return source of path
+func (p *Path) String() string {
+	return p.src
+}
+
+//isList checks if value is slice (not of bytes) or array
+func isList(value reflect.Value) bool {
+	return (value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8) || value.Kind() == reflect.Array
+}
+
+//selectPath appends values of path inside value to result
+func selectPath(value reflect.Value, path []pathElem, result []reflect.Value) []reflect.Value {
+	for value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return result
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() || ((value.Kind() == reflect.Ptr || value.Kind() == reflect.Map || value.Kind() == reflect.Slice) && value.IsNil()) {
+		return result
+	}
+	if len(path) == 0 {
+		return append(result, value)
+	}
+	if isList(value) {
+		for i := 0; i < value.Len(); i++ {
+			result = selectPath(value.Index(i), path, result)
+		}
+		return result
+	}
+	el := path[0]
+	v := field(value, el)
+	if !el.indexed {
+		return selectPath(v, path[1:], result)
+	}
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || !isList(v) {
+		return result
+	}
+	if el.all {
+		for i := 0; i < v.Len(); i++ {
+			result = selectPath(v.Index(i), path[1:], result)
+		}
+	} else if el.index < v.Len() {
+		result = selectPath(v.Index(el.index), path[1:], result)
+	}
+	return result
+}
+
+//Match return true if obj matches query, empty query matches everything
+func (q *Query) Match(obj interface{}) bool {
+	if q == nil || q.root == nil {
+		return true
+	}
+	return q.root.match(reflect.ValueOf(obj))
+}
+
+//String return source of query
+func (q *Query) String() string {
+	return q.src
+}
+
+type tokenType int
+
+const (
+	tokenWord tokenType = iota
+	tokenString
+	tokenOp
+	tokenEnd
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+const opChars = "=!<>~()|&,"
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' && j+1 < len(src) && (src[j+1] == c || src[j+1] == '\\') {
+					j++
+				}
+				sb.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{typ: tokenString, val: sb.String(), pos: i})
+			i = j + 1
+		case strings.IndexByte(opChars, c) >= 0:
+			op := string(c)
+			if i+1 < len(src) {
+				switch two := src[i : i+2]; two {
+				case "==", "!=", "<=", ">=", "=~", "!~", "&&", "||":
+					op = two
+				}
+			}
+			tokens = append(tokens, token{typ: tokenOp, val: op, pos: i})
+			i += len(op)
+		default:
+			j := i
+			for ; j < len(src) && strings.IndexByte(opChars+" \t\n\r\"'", src[j]) < 0; j++ {
+			}
+			tokens = append(tokens, token{typ: tokenWord, val: src[i:j], pos: i})
+			i = j
+		}
+	}
+	return append(tokens, token{typ: tokenEnd, pos: len(src)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+//keyword checks if next token is word kw (in any case) or one of ops
+func (p *parser) keyword(kw string, ops ...string) bool {
+	t := p.peek()
+	if t.typ == tokenWord && strings.EqualFold(t.val, kw) {
+		return true
+	}
+	if t.typ == tokenOp {
+		for _, op := range ops {
+			if t.val == op {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	var nodes orNode
+	for {
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if !p.keyword("or", "||") {
+			break
+		}
+		p.next()
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	var nodes andNode
+	for {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if !p.keyword("and", "&&") {
+			break
+		}
+		p.next()
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.keyword("not", "!") {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node: n}, nil
+	}
+	if t := p.peek(); t.typ == tokenOp && t.val == "(" {
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.typ != tokenOp || t.val != ")" {
+			return nil, fmt.Errorf("expected ) at %d", t.pos)
+		}
+		return n, nil
+	}
+	return p.parseCmp()
+}
+
+func (p *parser) parseValue() (*literal, error) {
+	t := p.next()
+	if t.typ != tokenWord && t.typ != tokenString {
+		return nil, fmt.Errorf("expected value at %d", t.pos)
+	}
+	return newLiteral(t.val), nil
+}
+
+func (p *parser) parseCmp() (node, error) {
+	t := p.next()
+	if t.typ != tokenWord {
+		return nil, fmt.Errorf("expected field at %d", t.pos)
+	}
+	n := &cmpNode{}
+	for _, name := range strings.Split(t.val, ".") {
+		if name == "" {
+			return nil, fmt.Errorf("wrong field %s at %d", t.val, t.pos)
+		}
+		n.path = append(n.path, pathElem{name: name, title: strings.Title(name)})
+	}
+	if p.keyword("not") {
+		p.next()
+		n.negate = true
+		if !p.keyword("in") {
+			return nil, fmt.Errorf("expected in at %d", p.peek().pos)
+		}
+	}
+	op := p.next()
+	switch {
+	case op.typ == tokenWord && strings.EqualFold(op.val, "in"):
+		n.op = "in"
+		if t := p.next(); t.typ != tokenOp || t.val != "(" {
+			return nil, fmt.Errorf("expected ( at %d", t.pos)
+		}
+		for {
+			lit, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, lit)
+			t := p.next()
+			if t.typ == tokenOp && t.val == ")" {
+				break
+			}
+			if t.typ != tokenOp || t.val != "," {
+				return nil, fmt.Errorf("expected , or ) at %d", t.pos)
+			}
+		}
+		return n, nil
+	case op.typ == tokenOp:
+		switch op.val {
+		case "=", "==":
+			n.op = "="
+		case "!=":
+			n.op, n.negate = "=", true
+		case "~", "=~":
+			n.op = "~"
+		case "!~":
+			n.op, n.negate = "~", true
+		case "<", "<=", ">", ">=":
+			n.op = op.val
+		}
+	}
+	if n.op == "" {
+		return nil, fmt.Errorf("expected operator after %s at %d", t.val, op.pos)
+	}
+	lit, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	n.values = []*literal{lit}
+	if n.op == "~" {
+		if n.re, err = regexp.Compile(lit.str); err != nil {
+			return nil, fmt.Errorf("wrong regexp %q at %d: %s", lit.str, op.pos, err)
+		}
+	}
+	return n, nil
+}
+
+//Parse compiles expression, empty expression matches everything
+func Parse(expr string) (*Query, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, fmt.Errorf("query %q: %s", expr, err)
+	}
+	q := &Query{src: expr}
+	if len(tokens) == 1 {
+		return q, nil
+	}
+	p := &parser{tokens: tokens}
+	if q.root, err = p.parseOr(); err != nil {
+		return nil, fmt.Errorf("query %q: %s", expr, err)
+	}
+	if t := p.peek(); t.typ != tokenEnd {
+		return nil, fmt.Errorf("query %q: unexpected %s at %d", expr, t.val, t.pos)
+	}
+	return q, nil
+}
+
+//FromMap compiles query from map of field:regexp pairs joined with and and expression in ExprKey.
+//It should be called once and the compiled query should be matched against every message.
+func FromMap(query map[string]string) (*Query, error) {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var nodes andNode
+	var src []string
+	for _, k := range keys {
+		if k == ExprKey {
+			continue
+		}
+		n := &cmpNode{op: "~", values: []*literal{newLiteral(query[k])}}
+		for _, name := range strings.Split(k, ".") {
+			n.path = append(n.path, pathElem{name: name, title: strings.Title(name)})
+		}
+		re, err := regexp.Compile(query[k])
+		if err != nil {
+			return nil, fmt.Errorf("wrong regexp for %s: %s", k, err)
+		}
+		n.re = re
+		nodes = append(nodes, n)
+		src = append(src, fmt.Sprintf("%s ~ %q", k, query[k]))
+	}
+	if expr, ok := query[ExprKey]; ok {
+		q, err := Parse(expr)
+		if err != nil {
+			return nil, err
+		}
+		if q.root != nil {
+			nodes = append(nodes, q.root)
+			src = append(src, fmt.Sprintf("(%s)", expr))
+		}
+	}
+	q := &Query{src: strings.Join(src, " and ")}
+	switch len(nodes) {
+	case 0:
+	case 1:
+		q.root = nodes[0]
+	default:
+		q.root = nodes
+	}
+	return q, nil
+}
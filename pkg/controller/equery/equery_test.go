@@ -0,0 +1,166 @@
+package equery
+
+import (
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/lf-edge/eve/api/go/info"
+	"testing"
+	"time"
+)
+
+type testItem struct {
+	Source string
+	Level  string
+	Msg    string
+	Time   string
+	Pid    interface{}
+}
+
+//TestQuery test parsing and matching of expressions
+func TestQuery(t *testing.T) {
+	items := []*testItem{
+		{Source: "zedagent", Level: "error", Msg: "cannot connect to lisp", Time: "2020-05-13T10:00:00.5Z", Pid: float64(10)},
+		{Source: "zedmanager", Level: "fatal", Msg: "no memory", Time: "2020-05-13T11:00:00Z", Pid: float64(200)},
+		{Source: "zedagent", Level: "info", Msg: "started", Time: "2020-05-12T11:00:00Z"},
+	}
+	tests := []struct {
+		expr    string
+		matched []int
+	}{
+		{"", []int{0, 1, 2}},
+		{"level in (error, fatal)", []int{0, 1}},
+		{"level not in (ERROR, fatal)", []int{2}},
+		{"(source = zedagent or source = zedmanager) and level != info and not msg ~ lisp", []int{1}},
+		{`time > "2020-05-13"`, []int{0, 1}},
+		{"time <= 2020-05-13T10:00:00.5Z", []int{0, 2}},
+		{"pid > 20", []int{1}},
+		{"pid >= 10 && msg !~ memory", []int{0}},
+		{`msg =~ "^no " || !level=info`, []int{0, 1}},
+		{"unknown = 1", nil},
+		{"unknown != 1", []int{0, 1, 2}},
+	}
+	for _, test := range tests {
+		q, err := Parse(test.expr)
+		if err != nil {
+			t.Fatalf("Parse(%s): %s", test.expr, err)
+		}
+		var matched []int
+		for i, item := range items {
+			if q.Match(item) {
+				matched = append(matched, i)
+			}
+		}
+		if len(matched) != len(test.matched) {
+			t.Fatalf("%s: expected %v, got %v", test.expr, test.matched, matched)
+		}
+		for i := range matched {
+			if matched[i] != test.matched[i] {
+				t.Fatalf("%s: expected %v, got %v", test.expr, test.matched, matched)
+			}
+		}
+	}
+	for _, expr := range []string{"level", "level =", "level in (error", "(level = error", "msg ~ \"[\"", "level = error extra", "= error", `msg = "open`} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("expected error for %s", expr)
+		}
+	}
+}
+
+//TestQueryLocalTime test that times without zone are in local time zone
+func TestQueryLocalTime(t *testing.T) {
+	local := time.Local
+	defer func() { time.Local = local }()
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+	item := &testItem{Time: "2020-05-13T10:00:00Z"}
+	for expr, expected := range map[string]bool{
+		`time > "2020-05-13 14:00:00"`:       true,
+		`time > "2020-05-13 16:00:00"`:       false,
+		`time > "2020-05-13T14:00:00Z"`:      false,
+		`time = "2020-05-13T15:00:00+05:00"`: true,
+	} {
+		q, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("Parse(%s): %s", expr, err)
+		}
+		if q.Match(item) != expected {
+			t.Errorf("%s: expected %t", expr, expected)
+		}
+	}
+}
+
+//TestQueryInfo test nested fields, slices, oneof getters and timestamps of protobuf
+func TestQueryInfo(t *testing.T) {
+	im := &info.ZInfoMsg{
+		DevId:       "dev",
+		AtTimeStamp: &timestamp.Timestamp{Seconds: 1589364000},
+		InfoContent: &info.ZInfoMsg_Dinfo{Dinfo: &info.ZInfoDevice{
+			Network: []*info.ZInfoNetwork{{DevName: "eth0", IPAddrs: []string{"10.0.0.2/24"}}, {DevName: "eth1", IPAddrs: []string{"192.168.1.5/24"}}},
+		}},
+	}
+	for expr, expected := range map[string]bool{
+		`dinfo.network.IPAddrs ~ "^192\.168\."`:        true,
+		"dinfo.network.devName = eth2":                 false,
+		"dinfo.network.devName not in (eth0)":          false,
+		"atTimeStamp >= 2020-05-13T10:00:00Z":          true,
+		"atTimeStamp < 2020-05-13":                     false,
+		"ainfo.state = RUNNING":                        false,
+		"devId = DEV and dinfo.network.devName = eth1": true,
+	} {
+		q, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("Parse(%s): %s", expr, err)
+		}
+		if q.Match(im) != expected {
+			t.Errorf("%s: expected %t", expr, expected)
+		}
+	}
+	q, err := FromMap(map[string]string{"devId": "^de", ExprKey: "dinfo.network.devName = eth0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Match(im) {
+		t.Errorf("%s: expected match", q)
+	}
+}
+
+//TestPath test selection of objects by path
+func TestPath(t *testing.T) {
+	im := &info.ZInfoMsg{
+		DevId: "dev",
+		InfoContent: &info.ZInfoMsg_Dinfo{Dinfo: &info.ZInfoDevice{
+			Network: []*info.ZInfoNetwork{{DevName: "eth0", IPAddrs: []string{"10.0.0.2/24"}}, {DevName: "eth1", IPAddrs: []string{"192.168.1.5/24", "fe80::1/64"}}},
+		}},
+	}
+	for path, expected := range map[string]int{
+		"":                            1,
+		"dinfo":                       1,
+		"ainfo":                       0,
+		"dinfo.network":               1,
+		"dinfo.network[*]":            2,
+		"dinfo.network[1]":            1,
+		"dinfo.network[2]":            0,
+		"dinfo.network.devName":       2,
+		"dinfo.network[*].ipAddrs":    2,
+		"dinfo.network[*].ipAddrs[*]": 3,
+		"dinfo.network[0].unknown":    0,
+	} {
+		p, err := ParsePath(path)
+		if err != nil {
+			t.Fatalf("ParsePath(%s): %s", path, err)
+		}
+		if selected := p.Select(im); len(selected) != expected {
+			t.Errorf("%s: expected %d objects, got %d: %v", path, expected, len(selected), selected)
+		}
+	}
+	p, err := ParsePath("dinfo.network[1].IPAddrs[0]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selected := p.Select(im); len(selected) != 1 || selected[0] != "192.168.1.5/24" {
+		t.Errorf("%s: wrong selection %v", p, selected)
+	}
+	for _, path := range []string{"dinfo..network", "dinfo.network[x]", "dinfo.network[*", "dinfo-network"} {
+		if _, err = ParsePath(path); err == nil {
+			t.Errorf("expected error for %s", path)
+		}
+	}
+}
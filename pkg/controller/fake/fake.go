@@ -0,0 +1,265 @@
+//Package fake provides in-memory implementation of controller
+//for use in tests without running Adam.
+package fake
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/lf-edge/eden/pkg/controller/cachers"
+	"github.com/lf-edge/eden/pkg/controller/eflowlog"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/controller/elog"
+	"github.com/lf-edge/eden/pkg/controller/emetric"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/flowlog"
+	"github.com/lf-edge/eve/api/go/info"
+	"github.com/lf-edge/eve/api/go/logs"
+	"github.com/lf-edge/eve/api/go/metrics"
+	uuid "github.com/satori/go.uuid"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Ctx is in-memory controller
+//zero value is ready to use
+type Ctx struct {
+	dir           string
+	mu            sync.Mutex
+	onboard       []string
+	devices       []uuid.UUID
+	serials       map[uuid.UUID]string
+	configs       map[uuid.UUID]string
+	logsBuffer    loaders.MemoryBuffer
+	infoBuffer    loaders.MemoryBuffer
+	metricsBuffer loaders.MemoryBuffer
+	flowLogBuffer loaders.MemoryBuffer
+	NoAutoBoard   bool //do not register device on Register call, use AddDevice instead
+}
+
+func (ctx *Ctx) getLoader() loaders.Loader {
+	return loaders.MemoryLoader(&ctx.logsBuffer, &ctx.infoBuffer, &ctx.metricsBuffer, &ctx.flowLogBuffer)
+}
+
+//InitWithVars use variables from viper for init controller
+func (ctx *Ctx) InitWithVars(vars *utils.ConfigVars) error {
+	if vars != nil {
+		ctx.dir = vars.AdamDir
+	}
+	return nil
+}
+
+//GetDir return dir
+func (ctx *Ctx) GetDir() (dir string) {
+	return ctx.dir
+}
+
+//Register add serial into onboard list and register device as EVE do
+//if NoAutoBoard is not set
+//eveSerial may contain comma-separated serials, device is registered for every new one
+func (ctx *Ctx) Register(eveCert string, eveSerial string) error {
+	ctx.mu.Lock()
+	ctx.onboard = append(ctx.onboard, eveSerial)
+	ctx.mu.Unlock()
+	if ctx.NoAutoBoard {
+		return nil
+	}
+	for _, serial := range strings.Split(eveSerial, ",") {
+		devUUID := uuid.NewV5(uuid.NamespaceOID, serial)
+		if _, err := ctx.DeviceGetSerial(devUUID); err == nil {
+			continue
+		}
+		if err := ctx.AddDevice(devUUID); err != nil {
+			return err
+		}
+		ctx.mu.Lock()
+		if ctx.serials == nil {
+			ctx.serials = make(map[uuid.UUID]string)
+		}
+		ctx.serials[devUUID] = serial
+		ctx.mu.Unlock()
+	}
+	return nil
+}
+
+//AddDevice add device with devUUID into device list
+func (ctx *Ctx) AddDevice(devUUID uuid.UUID) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	for _, el := range ctx.devices {
+		if uuid.Equal(el, devUUID) {
+			return fmt.Errorf("device %s already exists", devUUID)
+		}
+	}
+	ctx.devices = append(ctx.devices, devUUID)
+	return nil
+}
+
+//OnBoardList return onboard list
+func (ctx *Ctx) OnBoardList() (out []string, err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return append([]string{}, ctx.onboard...), nil
+}
+
+//DeviceList return device list
+func (ctx *Ctx) DeviceList() (out []string, err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	for _, el := range ctx.devices {
+		out = append(out, el.String())
+	}
+	return out, nil
+}
+
+//DeviceGetSerial return serial used by device for onboarding
+//devices added with AddDevice have empty serial
+func (ctx *Ctx) DeviceGetSerial(devUUID uuid.UUID) (serial string, err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	for _, el := range ctx.devices {
+		if uuid.Equal(el, devUUID) {
+			return ctx.serials[devUUID], nil
+		}
+	}
+	return "", fmt.Errorf("device %s not found", devUUID)
+}
+
+//ConfigSet set config for devID
+func (ctx *Ctx) ConfigSet(devUUID uuid.UUID, devConfig []byte) (err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if ctx.configs == nil {
+		ctx.configs = make(map[uuid.UUID]string)
+	}
+	ctx.configs[devUUID] = string(devConfig)
+	return nil
+}
+
+//ConfigGet get config for devID
+func (ctx *Ctx) ConfigGet(devUUID uuid.UUID) (out string, err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	out, ok := ctx.configs[devUUID]
+	if !ok {
+		return "", fmt.Errorf("no config for device %s", devUUID)
+	}
+	return out, nil
+}
+
+func marshal(msg proto.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	mler := jsonpb.Marshaler{}
+	if err := mler.Marshal(&buf, msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+//AddInfo inject info.ZInfoMsg for devUUID as if it was sent by EVE
+func (ctx *Ctx) AddInfo(devUUID uuid.UUID, im *info.ZInfoMsg) error {
+	data, err := marshal(im)
+	if err != nil {
+		return err
+	}
+	ctx.infoBuffer.Append(devUUID, data)
+	return nil
+}
+
+//AddLogBundle inject logs.LogBundle for devUUID as if it was sent by EVE
+func (ctx *Ctx) AddLogBundle(devUUID uuid.UUID, lb *logs.LogBundle) error {
+	data, err := marshal(lb)
+	if err != nil {
+		return err
+	}
+	ctx.logsBuffer.Append(devUUID, data)
+	return nil
+}
+
+//AddMetric inject metrics.ZMetricMsg for devUUID as if it was sent by EVE
+func (ctx *Ctx) AddMetric(devUUID uuid.UUID, mm *metrics.ZMetricMsg) error {
+	data, err := marshal(mm)
+	if err != nil {
+		return err
+	}
+	ctx.metricsBuffer.Append(devUUID, data)
+	return nil
+}
+
+//AddFlowMessage inject flowlog.FlowMessage for devUUID as if it was sent by EVE
+func (ctx *Ctx) AddFlowMessage(devUUID uuid.UUID, fm *flowlog.FlowMessage) error {
+	data, err := marshal(fm)
+	if err != nil {
+		return err
+	}
+	ctx.flowLogBuffer.Append(devUUID, data)
+	return nil
+}
+
+//LogChecker check logs by pattern from existing objects with LogLast and use LogWatch with timeout for observe new objects
+func (ctx *Ctx) LogChecker(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc, mode elog.LogCheckerMode, timeout time.Duration) (err error) {
+	return elog.LogChecker(ctx.getLoader(), devUUID, q, handler, mode, timeout)
+}
+
+//LogLastCallback check logs by pattern from existing objects with callback
+func (ctx *Ctx) LogLastCallback(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return elog.LogLast(loader, q, handler)
+}
+
+//LogBundleLastCallback process log bundles from existing objects with range from query with callback
+func (ctx *Ctx) LogBundleLastCallback(devUUID uuid.UUID, q map[string]string, handler elog.BundleHandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return elog.LogBundleLast(loader, q, handler)
+}
+
+//CacheStats is not supported by fake controller
+func (ctx *Ctx) CacheStats(devUUID uuid.UUID) (stats []cachers.Stats, err error) {
+	return nil, fmt.Errorf("caching is not supported by fake controller")
+}
+
+//CachePrune is not supported by fake controller
+func (ctx *Ctx) CachePrune(devUUID uuid.UUID, r cachers.Retention) (removed []cachers.Stats, err error) {
+	return nil, fmt.Errorf("caching is not supported by fake controller")
+}
+
+//InfoChecker checks the information in the regular expression pattern 'query' and processes the info.ZInfoMsg found by the function 'handler' from existing objects (mode=einfo.InfoExist), new objects (mode=einfo.InfoNew) or any of them (mode=einfo.InfoAny) with timeout.
+func (ctx *Ctx) InfoChecker(devUUID uuid.UUID, q map[string]string, infoType einfo.ZInfoType, handler einfo.HandlerFunc, mode einfo.InfoCheckerMode, timeout time.Duration) (err error) {
+	return einfo.InfoChecker(ctx.getLoader(), devUUID, q, infoType, handler, mode, timeout)
+}
+
+//InfoLastCallback check info by pattern from existing objects with callback
+func (ctx *Ctx) InfoLastCallback(devUUID uuid.UUID, q map[string]string, infoType einfo.ZInfoType, handler einfo.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return einfo.InfoLast(loader, q, einfo.ZInfoFind, handler, infoType)
+}
+
+//MetricChecker checks the metrics in the regular expression pattern 'query' and processes the metrics.ZMetricMsg found by the function 'handler' from existing objects (mode=emetric.MetricExist), new objects (mode=emetric.MetricNew) or any of them (mode=emetric.MetricAny) with timeout.
+func (ctx *Ctx) MetricChecker(devUUID uuid.UUID, q map[string]string, metricType emetric.ZMetricType, handler emetric.HandlerFunc, mode emetric.MetricCheckerMode, timeout time.Duration) (err error) {
+	return emetric.MetricChecker(ctx.getLoader(), devUUID, q, metricType, handler, mode, timeout)
+}
+
+//MetricLastCallback check metrics by pattern from existence objects with callback
+func (ctx *Ctx) MetricLastCallback(devUUID uuid.UUID, q map[string]string, metricType emetric.ZMetricType, handler emetric.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return emetric.MetricLast(loader, q, emetric.ZMetricFind, handler, metricType)
+}
+
+//FlowLogChecker checks the flow logs in the regular expression pattern 'query' and processes the flowlog.FlowMessage found by the function 'handler' from existing objects (mode=eflowlog.FlowLogExist), new objects (mode=eflowlog.FlowLogNew) or any of them (mode=eflowlog.FlowLogAny) with timeout.
+func (ctx *Ctx) FlowLogChecker(devUUID uuid.UUID, q map[string]string, flowLogType eflowlog.FlowLogType, handler eflowlog.HandlerFunc, mode eflowlog.FlowLogCheckerMode, timeout time.Duration) (err error) {
+	return eflowlog.FlowLogChecker(ctx.getLoader(), devUUID, q, flowLogType, handler, mode, timeout)
+}
+
+//FlowLogLastCallback check flow logs by pattern from existence objects with callback
+func (ctx *Ctx) FlowLogLastCallback(devUUID uuid.UUID, q map[string]string, flowLogType eflowlog.FlowLogType, handler eflowlog.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return eflowlog.FlowLogLast(loader, q, eflowlog.FlowLogFind, handler, flowLogType)
+}
@@ -0,0 +1,422 @@
+package fake_test
+
+import (
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/lf-edge/eden/pkg/controller"
+	"github.com/lf-edge/eden/pkg/controller/eflowlog"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/controller/elog"
+	"github.com/lf-edge/eden/pkg/controller/emetric"
+	"github.com/lf-edge/eden/pkg/controller/fake"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/lf-edge/eve/api/go/flowlog"
+	"github.com/lf-edge/eve/api/go/info"
+	"github.com/lf-edge/eve/api/go/logs"
+	"github.com/lf-edge/eve/api/go/metrics"
+	uuid "github.com/satori/go.uuid"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+//consumerLoader is loader which keeps consumer set from query
+type consumerLoader struct {
+	loaders.Loader
+	group    string
+	consumer string
+}
+
+//SetConsumer remembers group and consumer
+func (loader *consumerLoader) SetConsumer(group string, consumer string) {
+	loader.group, loader.consumer = group, consumer
+}
+
+//appendLater add msg into buffer after watcher is started
+func appendLater(t *testing.T, buffer *loaders.MemoryBuffer, msg proto.Message) {
+	data, err := (&jsonpb.Marshaler{}).MarshalToString(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		buffer.Append(uuid.Nil, []byte(data))
+	}()
+}
+
+func prepareCloud(t *testing.T) (*fake.Ctx, controller.Cloud) {
+	fakeCtrl := &fake.Ctx{}
+	ctx, err := controller.CloudPrepareWithController(fakeCtrl, &utils.ConfigVars{DevModel: string(controller.DevModelTypeQemu), EveSerial: "31415926"})
+	if err != nil {
+		t.Fatalf("CloudPrepareWithController: %s", err)
+	}
+	if err = ctx.OnBoard(); err != nil {
+		t.Fatalf("OnBoard: %s", err)
+	}
+	return fakeCtrl, ctx
+}
+
+//TestFakeConfig test config set and get with fake controller
+func TestFakeConfig(t *testing.T) {
+	_, ctx := prepareCloud(t)
+	dev, err := ctx.GetDeviceFirst()
+	if err != nil {
+		t.Fatalf("GetDeviceFirst: %s", err)
+	}
+	dev.SetConfigItem("timer.config.interval", "10")
+	devConfig, err := ctx.GetConfigBytes(dev, false)
+	if err != nil {
+		t.Fatalf("GetConfigBytes: %s", err)
+	}
+	if err = ctx.ConfigSet(dev.GetID(), devConfig); err != nil {
+		t.Fatalf("ConfigSet: %s", err)
+	}
+	out, err := ctx.ConfigGet(dev.GetID())
+	if err != nil {
+		t.Fatalf("ConfigGet: %s", err)
+	}
+	if out != string(devConfig) {
+		t.Fatalf("config mismatch: %s != %s", out, devConfig)
+	}
+}
+
+//TestFakeInfo test InfoChecker for existing and new info
+func TestFakeInfo(t *testing.T) {
+	fakeCtrl, ctx := prepareCloud(t)
+	dev, err := ctx.GetDeviceFirst()
+	if err != nil {
+		t.Fatalf("GetDeviceFirst: %s", err)
+	}
+	devID := dev.GetID()
+	if err = fakeCtrl.AddInfo(devID, &info.ZInfoMsg{Ztype: info.ZInfoTypes_ZiDevice, DevId: devID.String()}); err != nil {
+		t.Fatalf("AddInfo: %s", err)
+	}
+	found := false
+	handler := func(im *info.ZInfoMsg, ds []*einfo.ZInfoMsgInterface, infoType einfo.ZInfoType) bool {
+		found = true
+		return true
+	}
+	if err = ctx.InfoLastCallback(devID, map[string]string{"devId": devID.String()}, einfo.ZAll, handler); err != nil {
+		t.Fatalf("InfoLastCallback: %s", err)
+	}
+	if !found {
+		t.Fatal("info not found")
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = fakeCtrl.AddInfo(devID, &info.ZInfoMsg{Ztype: info.ZInfoTypes_ZiApp, DevId: devID.String(), InfoContent: &info.ZInfoMsg_Ainfo{Ainfo: &info.ZInfoApp{AppName: "test"}}})
+	}()
+	if err = ctx.InfoChecker(devID, map[string]string{"appName": "test"}, einfo.ZInfoAppInstance, einfo.HandleFirst, einfo.InfoNew, 5); err != nil {
+		t.Fatalf("InfoChecker: %s", err)
+	}
+}
+
+//TestFakeLogs test LogChecker with fake controller
+func TestFakeLogs(t *testing.T) {
+	fakeCtrl, ctx := prepareCloud(t)
+	dev, err := ctx.GetDeviceFirst()
+	if err != nil {
+		t.Fatalf("GetDeviceFirst: %s", err)
+	}
+	devID := dev.GetID()
+	lb := &logs.LogBundle{DevID: devID.String(), Log: []*logs.LogEntry{{Content: `{"source":"zedagent","level":"info","msg":"hello"}`}}}
+	if err = fakeCtrl.AddLogBundle(devID, lb); err != nil {
+		t.Fatalf("AddLogBundle: %s", err)
+	}
+	if err = ctx.LogChecker(devID, map[string]string{"source": "zedagent"}, elog.HandleFirst, elog.LogExist, 5); err != nil {
+		t.Fatalf("LogChecker: %s", err)
+	}
+}
+
+//TestWatchConsumer test name of watcher passed with loaders.ConsumerKey to loader by LogWatch and InfoWatch
+func TestWatchConsumer(t *testing.T) {
+	var logsBuffer, infoBuffer loaders.MemoryBuffer
+	memoryLoader := loaders.MemoryLoader(&logsBuffer, &infoBuffer, &loaders.MemoryBuffer{}, &loaders.MemoryBuffer{})
+
+	query := map[string]string{"source": "zedagent", loaders.ConsumerKey: "group:worker"}
+	if err := elog.LogWatch(memoryLoader, query, elog.HandleFirst, 1); err == nil {
+		t.Fatal("expected error for loader without consumer support")
+	}
+	loader := &consumerLoader{Loader: memoryLoader}
+	appendLater(t, &logsBuffer, &logs.LogBundle{Log: []*logs.LogEntry{{Content: `{"source":"zedagent","level":"info","msg":"hello"}`}}})
+	found := false
+	if err := elog.LogWatch(loader, query, func(item *elog.LogItem) bool {
+		found = true
+		return true
+	}, 5); err != nil {
+		t.Fatalf("LogWatch: %s", err)
+	}
+	if !found || loader.group != "group" || loader.consumer != "worker" {
+		t.Fatalf("LogWatch: found %t, consumer %s:%s", found, loader.group, loader.consumer)
+	}
+
+	query = map[string]string{"appName": "test", loaders.ConsumerKey: "infogroup"}
+	if err := einfo.InfoWatch(memoryLoader, query, einfo.ZInfoFind, einfo.HandleFirst, einfo.ZInfoAppInstance, 1); err == nil {
+		t.Fatal("expected error for loader without consumer support")
+	}
+	loader = &consumerLoader{Loader: memoryLoader}
+	appendLater(t, &infoBuffer, &info.ZInfoMsg{Ztype: info.ZInfoTypes_ZiApp, InfoContent: &info.ZInfoMsg_Ainfo{Ainfo: &info.ZInfoApp{AppName: "test"}}})
+	found = false
+	if err := einfo.InfoWatch(loader, query, einfo.ZInfoFind, func(im *info.ZInfoMsg, ds []*einfo.ZInfoMsgInterface, infoType einfo.ZInfoType) bool {
+		found = true
+		return true
+	}, einfo.ZInfoAppInstance, 5); err != nil {
+		t.Fatalf("InfoWatch: %s", err)
+	}
+	if !found || loader.group != "infogroup" || loader.consumer != "infogroup" {
+		t.Fatalf("InfoWatch: found %t, consumer %s:%s", found, loader.group, loader.consumer)
+	}
+}
+
+//TestFakeMetrics test MetricChecker with nested fields of app metrics
+func TestFakeMetrics(t *testing.T) {
+	fakeCtrl, ctx := prepareCloud(t)
+	dev, err := ctx.GetDeviceFirst()
+	if err != nil {
+		t.Fatalf("GetDeviceFirst: %s", err)
+	}
+	devID := dev.GetID()
+	mm := &metrics.ZMetricMsg{
+		DevID: devID.String(),
+		Am: []*metrics.AppMetric{
+			{AppName: "nginx", Memory: &metrics.MemoryMetric{UsedMem: 256}},
+			{AppName: "redis", Memory: &metrics.MemoryMetric{UsedMem: 1024}},
+		},
+		MetricContent: &metrics.ZMetricMsg_Dm{Dm: &metrics.DeviceMetric{Memory: &metrics.MemoryMetric{UsedMem: 2048}}},
+	}
+	if err = fakeCtrl.AddMetric(devID, mm); err != nil {
+		t.Fatalf("AddMetric: %s", err)
+	}
+	var used uint32
+	handler := func(mm *metrics.ZMetricMsg, ds []*emetric.ZMetricMsgInterface, metricType emetric.ZMetricType) bool {
+		if len(ds) != 1 {
+			t.Fatalf("expected one app metric, got %d", len(ds))
+		}
+		used = (*ds[0]).(*metrics.AppMetric).Memory.UsedMem
+		return true
+	}
+	if err = ctx.MetricChecker(devID, map[string]string{"appName": "redis"}, emetric.ZMetricApp, handler, emetric.MetricExist, 5); err != nil {
+		t.Fatalf("MetricChecker: %s", err)
+	}
+	if used != 1024 {
+		t.Fatalf("expected used memory 1024, got %d", used)
+	}
+	err = ctx.MetricChecker(devID, map[string]string{"appName": "mysql"}, emetric.ZMetricApp, handler, emetric.MetricExist, 5)
+	if err != loaders.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for absent app metric, got %v", err)
+	}
+	found := false
+	handler = func(mm *metrics.ZMetricMsg, ds []*emetric.ZMetricMsgInterface, metricType emetric.ZMetricType) bool {
+		found = true
+		return true
+	}
+	if err = ctx.MetricLastCallback(devID, map[string]string{"memory.usedMem": "^2048$"}, emetric.ZMetricDevice, handler); err != nil {
+		t.Fatalf("MetricLastCallback: %s", err)
+	}
+	if !found {
+		t.Fatal("device metric not found")
+	}
+}
+
+//TestFakeFlowLog test FlowLogChecker with scope and flow fields
+func TestFakeFlowLog(t *testing.T) {
+	fakeCtrl, ctx := prepareCloud(t)
+	dev, err := ctx.GetDeviceFirst()
+	if err != nil {
+		t.Fatalf("GetDeviceFirst: %s", err)
+	}
+	devID := dev.GetID()
+	fm := &flowlog.FlowMessage{
+		DevId: devID.String(),
+		Scope: &flowlog.ScopeInfo{NetInstUUID: "ni1"},
+		Flows: []*flowlog.FlowRecord{
+			{Flow: &flowlog.IpFlow{Dest: "10.0.0.1", DestPort: 80}, AclName: "allow-http"},
+			{Flow: &flowlog.IpFlow{Dest: "10.0.0.1", DestPort: 22}, AclName: "drop"},
+		},
+	}
+	if err = fakeCtrl.AddFlowMessage(devID, fm); err != nil {
+		t.Fatalf("AddFlowMessage: %s", err)
+	}
+	var aclName string
+	handler := func(fm *flowlog.FlowMessage, ds []*eflowlog.FlowMessageInterface, flowLogType eflowlog.FlowLogType) bool {
+		if len(ds) != 1 {
+			t.Fatalf("expected one flow, got %d", len(ds))
+		}
+		aclName = (*ds[0]).(*flowlog.FlowRecord).AclName
+		return true
+	}
+	q := map[string]string{"scope.netInstUUID": "^ni1$", "flow.destPort": "^22$"}
+	if err = ctx.FlowLogChecker(devID, q, eflowlog.FlowLogFlows, handler, eflowlog.FlowLogExist, 5); err != nil {
+		t.Fatalf("FlowLogChecker: %s", err)
+	}
+	if aclName != "drop" {
+		t.Fatalf("expected flow dropped by acl, got %q", aclName)
+	}
+}
+
+//TestFakeHistory test saving of pushed configs into history
+func TestFakeHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eden-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx, err := controller.CloudPrepareWithController(&fake.Ctx{}, &utils.ConfigVars{DevModel: string(controller.DevModelTypeQemu), EveSerial: "31415926", AdamDir: dir})
+	if err != nil {
+		t.Fatalf("CloudPrepareWithController: %s", err)
+	}
+	if err = ctx.OnBoard(); err != nil {
+		t.Fatalf("OnBoard: %s", err)
+	}
+	dev, err := ctx.GetDeviceFirst()
+	if err != nil {
+		t.Fatalf("GetDeviceFirst: %s", err)
+	}
+	for _, version := range []int{9, 10} {
+		dev.SetConfigVersion(version)
+		dev.SetConfigItem("timer.config.interval", strconv.Itoa(version))
+		devConfig, err := ctx.GetConfigBytes(dev, false)
+		if err != nil {
+			t.Fatalf("GetConfigBytes: %s", err)
+		}
+		if err = ctx.ConfigSet(dev.GetID(), devConfig); err != nil {
+			t.Fatalf("ConfigSet: %s", err)
+		}
+	}
+	snapshots, err := ctx.ConfigHistory(dev.GetID())
+	if err != nil {
+		t.Fatalf("ConfigHistory: %s", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Version != "9" || snapshots[1].Version != "10" {
+		t.Fatalf("unexpected history: %v", snapshots)
+	}
+	snapshot, err := ctx.ConfigHistoryGet(dev.GetID(), "9")
+	if err != nil {
+		t.Fatalf("ConfigHistoryGet: %s", err)
+	}
+	if !strings.Contains(string(snapshot.Config), `"value":"9"`) {
+		t.Fatalf("unexpected config in snapshot: %s", snapshot.Config)
+	}
+}
+
+//TestFakeRollback test push of config from history with bumped version
+func TestFakeRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eden-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx, err := controller.CloudPrepareWithController(&fake.Ctx{}, &utils.ConfigVars{DevModel: string(controller.DevModelTypeQemu), EveSerial: "31415926", AdamDir: dir})
+	if err != nil {
+		t.Fatalf("CloudPrepareWithController: %s", err)
+	}
+	if err = ctx.OnBoard(); err != nil {
+		t.Fatalf("OnBoard: %s", err)
+	}
+	dev, err := ctx.GetDeviceFirst()
+	if err != nil {
+		t.Fatalf("GetDeviceFirst: %s", err)
+	}
+	for _, version := range []int{9, 10} {
+		dev.SetConfigVersion(version)
+		dev.SetConfigItem("timer.config.interval", strconv.Itoa(version))
+		devConfig, err := ctx.GetConfigBytes(dev, false)
+		if err != nil {
+			t.Fatalf("GetConfigBytes: %s", err)
+		}
+		if err = ctx.ConfigSet(dev.GetID(), devConfig); err != nil {
+			t.Fatalf("ConfigSet: %s", err)
+		}
+	}
+	if err = ctx.ConfigRollback(dev, "8"); err == nil {
+		t.Fatal("expected error for version not in history")
+	}
+	if err = ctx.ConfigRollback(dev, "9"); err != nil {
+		t.Fatalf("ConfigRollback: %s", err)
+	}
+	devConfig, err := ctx.ConfigGet(dev.GetID())
+	if err != nil {
+		t.Fatalf("ConfigGet: %s", err)
+	}
+	if !strings.Contains(devConfig, `"version":"11"`) || !strings.Contains(devConfig, `"value":"9"`) {
+		t.Fatalf("unexpected config after rollback: %s", devConfig)
+	}
+	snapshots, err := ctx.ConfigHistory(dev.GetID())
+	if err != nil {
+		t.Fatalf("ConfigHistory: %s", err)
+	}
+	if len(snapshots) != 3 || snapshots[2].Version != "11" {
+		t.Fatalf("rolled back config not saved into history: %v", snapshots)
+	}
+}
+
+//TestFakeConfigValidated test validation of config before pushing
+func TestFakeConfigValidated(t *testing.T) {
+	_, ctx := prepareCloud(t)
+	dev, err := ctx.GetDeviceFirst()
+	if err != nil {
+		t.Fatalf("GetDeviceFirst: %s", err)
+	}
+	if _, err = ctx.GetConfigValidated(dev); err != nil {
+		t.Fatalf("GetConfigValidated: %s", err)
+	}
+	niID := "0b2c3d4e-5f60-4a1b-8c2d-3e4f5a6b7c8d"
+	ni := &config.NetworkInstanceConfig{
+		Uuidandversion: &config.UUIDandVersion{Uuid: niID, Version: "1"},
+		Displayname:    "switch",
+		InstType:       config.ZNetworkInstType_ZnetInstSwitch,
+		Port:           &config.Adapter{Name: "wrong"},
+	}
+	if err = ctx.AddNetworkInstanceConfig(ni); err != nil {
+		t.Fatalf("AddNetworkInstanceConfig: %s", err)
+	}
+	dev.SetNetworkInstanceConfig([]string{niID})
+	if _, err = ctx.GetConfigValidated(dev); err == nil || !strings.Contains(err.Error(), "not in adapters for switch") {
+		t.Fatalf("expected error for wrong adapter of switch, got %v", err)
+	}
+}
+
+//TestFakeDeviceSelect test onboarding of second serial and selection of devices
+func TestFakeDeviceSelect(t *testing.T) {
+	fakeCtrl, _ := prepareCloud(t)
+	ctx, err := controller.CloudPrepareWithController(fakeCtrl, &utils.ConfigVars{DevModel: string(controller.DevModelTypeQemu), EveSerial: "27182818"})
+	if err != nil {
+		t.Fatalf("CloudPrepareWithController: %s", err)
+	}
+	if err = ctx.OnBoard(); err != nil {
+		t.Fatalf("OnBoard: %s", err)
+	}
+	if len(ctx.ListDevices()) != 2 {
+		t.Fatalf("expected 2 devices, got %d", len(ctx.ListDevices()))
+	}
+	first, err := ctx.GetDevice("31415926")
+	if err != nil {
+		t.Fatalf("GetDevice by serial: %s", err)
+	}
+	second, err := ctx.GetDevice("27182818")
+	if err != nil {
+		t.Fatalf("GetDevice by serial: %s", err)
+	}
+	if first == second {
+		t.Fatal("different serials must select different devices")
+	}
+	if dev, err := ctx.GetDevice(second.GetID().String()); err != nil || dev != second {
+		t.Fatalf("GetDevice by uuid: %v %s", dev, err)
+	}
+	second.SetName("edge")
+	if dev, err := ctx.GetDevice("edge"); err != nil || dev != second {
+		t.Fatalf("GetDevice by name: %v %s", dev, err)
+	}
+	first.SetName("edge")
+	if _, err = ctx.GetDevice("edge"); err == nil {
+		t.Fatal("ambiguous name must fail")
+	}
+	if _, err = ctx.GetDevice("unknown"); err == nil {
+		t.Fatal("unknown selector must fail")
+	}
+}
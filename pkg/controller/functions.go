@@ -3,7 +3,9 @@ package controller
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/golang/protobuf/proto"
 	"github.com/lf-edge/eden/pkg/controller/adam"
+	"github.com/lf-edge/eden/pkg/controller/zedcloud"
 	"github.com/lf-edge/eden/pkg/utils"
 	"github.com/lf-edge/eve/api/go/config"
 	uuid "github.com/satori/go.uuid"
@@ -11,16 +13,26 @@ import (
 	"io/ioutil"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 //CloudPrepare is for init controller connection and obtain device list
+//it uses zedcloud-compatible controller if zedcloud.enabled is set and adam otherwise
 func CloudPrepare() (Cloud, error) {
 	vars, err := utils.InitVars()
 	if err != nil {
 		return nil, fmt.Errorf("utils.InitVars: %s", err)
 	}
-	ctx := &CloudCtx{vars: vars, Controller: &adam.Ctx{}}
+	if vars.ZedcloudEnabled {
+		return CloudPrepareWithController(&zedcloud.Ctx{}, vars)
+	}
+	return CloudPrepareWithController(&adam.Ctx{}, vars)
+}
+
+//CloudPrepareWithController is for init provided controller with vars and obtain device list
+func CloudPrepareWithController(ctrl Controller, vars *utils.ConfigVars) (Cloud, error) {
+	ctx := &CloudCtx{vars: vars, Controller: ctrl}
 	if err := ctx.InitWithVars(vars); err != nil {
 		return nil, fmt.Errorf("cloud.InitWithVars: %s", err)
 	}
@@ -56,6 +68,18 @@ func (cloud *CloudCtx) devInit(devID string) error {
 			return fmt.Errorf("cloud.AddDevice(%s): %s", devUUID, err)
 		}
 	}
+	if serial, err := cloud.DeviceGetSerial(devUUID); err != nil {
+		log.Debugf("cannot obtain serial of device %s: %s", devUUID, err)
+	} else {
+		dev.SetSerial(serial)
+	}
+	//name is stored only inside config, so restore it from there
+	if devConfig, err := cloud.ConfigGet(devUUID); err == nil {
+		var deviceConfig config.EdgeDevConfig
+		if err = json.Unmarshal([]byte(devConfig), &deviceConfig); err == nil {
+			dev.SetName(deviceConfig.Name)
+		}
+	}
 	if cloud.vars.SshKey != "" {
 		b, err := ioutil.ReadFile(cloud.vars.SshKey)
 		switch {
@@ -74,42 +98,65 @@ func (cloud *CloudCtx) devInit(devID string) error {
 }
 
 //OnBoard in controller
+//it registers serial of EVE from config if there is no device with this serial
+//serials of already onboarded devices are kept to allow several EVEs to use the same controller
 func (cloud *CloudCtx) OnBoard() error {
-	devUUID, err := cloud.GetDeviceFirst()
-	if devUUID == nil {
-		log.Info("Try to add onboarding")
-		err = cloud.Register(cloud.vars.EveCert, cloud.vars.EveSerial)
-		if err != nil {
-			return fmt.Errorf("ctx.register: %s", err)
+	serials := []string{cloud.vars.EveSerial}
+	for _, dev := range cloud.devices {
+		switch dev.GetSerial() {
+		case cloud.vars.EveSerial:
+			return nil
+		case "":
+			//controller does not report serials, so keep old behavior with single device
+			return nil
 		}
-		res, err := cloud.OnBoardList()
-		if err != nil {
-			return fmt.Errorf("ctx.OnBoardList: %s", err)
-		}
-		if len(res) == 0 {
-			return fmt.Errorf("no onboard in list")
+		if _, found := utils.FindEleInSlice(serials, dev.GetSerial()); !found {
+			serials = append(serials, dev.GetSerial())
 		}
-		log.Info(res)
+	}
+	log.Info("Try to add onboarding")
+	if err := cloud.Register(cloud.vars.EveCert, strings.Join(serials, ",")); err != nil {
+		return fmt.Errorf("ctx.register: %s", err)
+	}
+	res, err := cloud.OnBoardList()
+	if err != nil {
+		return fmt.Errorf("ctx.OnBoardList: %s", err)
+	}
+	if len(res) == 0 {
+		return fmt.Errorf("no onboard in list")
+	}
+	log.Info(res)
 
-		maxRepeat := 20
-		delayTime := 20 * time.Second
+	maxRepeat := 20
+	delayTime := 20 * time.Second
 
-		for i := 0; i < maxRepeat; i++ {
-			cmdOut, err := cloud.DeviceList()
+	for i := 0; i < maxRepeat; i++ {
+		cmdOut, err := cloud.DeviceList()
+		if err != nil {
+			return fmt.Errorf("ctx.DeviceList: %s", err)
+		}
+		for _, devID := range cmdOut {
+			devUUID, err := uuid.FromString(devID)
 			if err != nil {
-				return fmt.Errorf("ctx.DeviceList: %s", err)
+				return fmt.Errorf("uuid.FromString(%s): %s", devID, err)
+			}
+			if _, err = cloud.GetDeviceUUID(devUUID); err == nil {
+				continue
 			}
-			if len(cmdOut) > 0 {
+			if err = cloud.devInit(devID); err != nil {
+				return err
+			}
+			dev, _ := cloud.GetDeviceUUID(devUUID)
+			if dev.GetSerial() == "" || dev.GetSerial() == cloud.vars.EveSerial {
 				log.Info("Done onboarding in adam!")
-				log.Infof("Device uuid: %s", cmdOut[0])
-				return cloud.devInit(cmdOut[0])
+				log.Infof("Device uuid: %s", devID)
+				return nil
 			}
-			log.Infof("Attempt to list devices (%d) of (%d)", i, maxRepeat)
-			time.Sleep(delayTime)
 		}
-		return fmt.Errorf("onboarding timeout. You may try to run this onboard command again in several minutes. If not successful see logs of adam/eve")
+		log.Infof("Attempt to list devices (%d) of (%d)", i, maxRepeat)
+		time.Sleep(delayTime)
 	}
-	return nil
+	return fmt.Errorf("onboarding timeout. You may try to run this onboard command again in several minutes. If not successful see logs of adam/eve")
 }
 
 //VersionIncrement use []byte with config.EdgeDevConfig and increment config version
@@ -146,3 +193,21 @@ func VersionIncrement(configOld []byte) ([]byte, error) {
 	log.Debugf("VersionIncrement %d->%s", oldVersion, deviceConfig.Id.Version)
 	return json.Marshal(&deviceConfig)
 }
+
+//ConfigJSONToProto converts config.EdgeDevConfig from json into binary protobuf form
+func ConfigJSONToProto(data []byte) ([]byte, error) {
+	var deviceConfig config.EdgeDevConfig
+	if err := json.Unmarshal(data, &deviceConfig); err != nil {
+		return nil, fmt.Errorf("unmarshal error: %s", err)
+	}
+	return proto.Marshal(&deviceConfig)
+}
+
+//ConfigProtoToJSON converts config.EdgeDevConfig from binary protobuf into json form
+func ConfigProtoToJSON(data []byte) ([]byte, error) {
+	var deviceConfig config.EdgeDevConfig
+	if err := proto.Unmarshal(data, &deviceConfig); err != nil {
+		return nil, fmt.Errorf("unmarshal error: %s", err)
+	}
+	return json.Marshal(&deviceConfig)
+}
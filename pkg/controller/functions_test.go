@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/lf-edge/eve/api/go/config"
+	"testing"
+)
+
+//TestConfigProtoConversion test conversion of config between json and binary protobuf
+func TestConfigProtoConversion(t *testing.T) {
+	deviceConfig := &config.EdgeDevConfig{
+		Id:          &config.UUIDandVersion{Uuid: "1b4a8cf4-6a2c-4e3b-9a3c-2f3a4e5b6c7d", Version: "4"},
+		ConfigItems: []*config.ConfigItem{{Key: "timer.config.interval", Value: "10"}},
+	}
+	data, err := json.Marshal(deviceConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	protoData, err := ConfigJSONToProto(data)
+	if err != nil {
+		t.Fatalf("ConfigJSONToProto: %s", err)
+	}
+	jsonData, err := ConfigProtoToJSON(protoData)
+	if err != nil {
+		t.Fatalf("ConfigProtoToJSON: %s", err)
+	}
+	if string(jsonData) != string(data) {
+		t.Fatalf("config mismatch after conversion: %s != %s", jsonData, data)
+	}
+	if _, err = ConfigProtoToJSON([]byte("not a proto")); err == nil {
+		t.Fatal("expected error for broken proto")
+	}
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lf-edge/eden/pkg/device"
+	"github.com/lf-edge/eve/api/go/config"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const historyDir = "history"
+
+//ConfigSnapshot is config of device pushed into controller with time and command, which pushed it
+type ConfigSnapshot struct {
+	Version   string          `json:"version"`
+	Timestamp time.Time       `json:"timestamp"`
+	Command   string          `json:"command"`
+	Config    json.RawMessage `json:"config"`
+}
+
+//getHistoryDir return directory with config snapshots of devUUID inside directory of controller
+func (cloud *CloudCtx) getHistoryDir(devUUID uuid.UUID) (string, error) {
+	if cloud.GetDir() == "" {
+		return "", fmt.Errorf("controller has no directory to keep history")
+	}
+	return filepath.Join(cloud.GetDir(), historyDir, devUUID.String()), nil
+}
+
+//ConfigSet set config for devUUID and save snapshot of it into history
+func (cloud *CloudCtx) ConfigSet(devUUID uuid.UUID, devConfig []byte) (err error) {
+	if err = cloud.Controller.ConfigSet(devUUID, devConfig); err != nil {
+		return err
+	}
+	//history is not kept for controllers without directory
+	if cloud.GetDir() == "" {
+		return nil
+	}
+	if err = cloud.saveSnapshot(devUUID, devConfig); err != nil {
+		log.Warnf("cannot save config into history: %s", err)
+	}
+	return nil
+}
+
+//saveSnapshot saves config keyed by its version, snapshot with the same version is overwritten
+func (cloud *CloudCtx) saveSnapshot(devUUID uuid.UUID, devConfig []byte) error {
+	dir, err := cloud.getHistoryDir(devUUID)
+	if err != nil {
+		return err
+	}
+	var deviceConfig config.EdgeDevConfig
+	if err = json.Unmarshal(devConfig, &deviceConfig); err != nil {
+		return fmt.Errorf("unmarshal error: %s", err)
+	}
+	version := deviceConfig.GetId().GetVersion()
+	if version == "" || strings.ContainsAny(version, `/\`) {
+		return fmt.Errorf("wrong version of config: %q", version)
+	}
+	data, err := json.Marshal(ConfigSnapshot{
+		Version:   version,
+		Timestamp: time.Now(),
+		Command:   strings.Join(append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...), " "),
+		Config:    devConfig,
+	})
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, version+".json"), data, 0644)
+}
+
+//ConfigHistory return snapshots of configs pushed for devUUID sorted by version
+func (cloud *CloudCtx) ConfigHistory(devUUID uuid.UUID) (snapshots []*ConfigSnapshot, err error) {
+	dir, err := cloud.getHistoryDir(devUUID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		snapshot, err := cloud.ConfigHistoryGet(devUUID, strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return versionLess(snapshots[i].Version, snapshots[j].Version)
+	})
+	return snapshots, nil
+}
+
+//versionLess compares versions as numbers if possible
+func versionLess(a, b string) bool {
+	an, errA := strconv.Atoi(a)
+	bn, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return an < bn
+	}
+	return a < b
+}
+
+//ConfigHistoryGet return snapshot of config with version pushed for devUUID
+func (cloud *CloudCtx) ConfigHistoryGet(devUUID uuid.UUID, version string) (*ConfigSnapshot, error) {
+	dir, err := cloud.getHistoryDir(devUUID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(version, `/\`) {
+		return nil, fmt.Errorf("wrong version of config: %q", version)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, version+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no config with version %s in history of device %s", version, devUUID)
+		}
+		return nil, err
+	}
+	var snapshot ConfigSnapshot
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("cannot parse snapshot %s: %s", version, err)
+	}
+	return &snapshot, nil
+}
+
+//ConfigRollback pushes config with version from history of dev with bumped version of current config
+//through ConfigSync, so it is validated and state file is refreshed with it
+func (cloud *CloudCtx) ConfigRollback(dev *device.Ctx, version string) error {
+	snapshot, err := cloud.ConfigHistoryGet(dev.GetID(), version)
+	if err != nil {
+		return err
+	}
+	var deviceConfig config.EdgeDevConfig
+	if err = json.Unmarshal(snapshot.Config, &deviceConfig); err != nil {
+		return fmt.Errorf("unmarshal error: %s", err)
+	}
+	//parse snapshot into clean context to not mix objects with current ones
+	rolled := &CloudCtx{Controller: cloud.Controller, vars: cloud.vars}
+	rolledDev, err := rolled.ConfigParse(&deviceConfig)
+	if err != nil {
+		return fmt.Errorf("configParse error: %s", err)
+	}
+	rolledDev.SetSerial(dev.GetSerial())
+	rolledDev.SetConfigVersion(dev.GetConfigVersion() + 1)
+	return rolled.ConfigSync(rolledDev)
+}
@@ -12,6 +12,7 @@ type Loader interface {
 	ProcessStream(process ProcessFunction, typeToProcess infoOrLogs, timeoutSeconds time.Duration) error
 	ProcessExisting(process ProcessFunction, typeToProcess infoOrLogs) error
 	SetRemoteCache(cache cachers.Cacher)
+	SetRange(r Range)
 	Clone() Loader
 }
 
@@ -23,5 +24,11 @@ var LogsType infoOrLogs = 1
 //InfoType for observe info
 var InfoType infoOrLogs = 2
 
+//MetricsType for observe metrics
+var MetricsType infoOrLogs = 3
+
+//FlowLogType for observe flow logs
+var FlowLogType infoOrLogs = 4
+
 //ProcessFunction is prototype of processing function
 type ProcessFunction func(bytes []byte) (bool, error)
@@ -0,0 +1,349 @@
+package loaders
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/lf-edge/eden/pkg/controller/cachers"
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/supportbundle"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//bundleItem is object loaded from bundle
+type bundleItem struct {
+	devUUID uuid.UUID //uuid.Nil if device is unknown
+	time    time.Time
+	data    []byte
+}
+
+//ReplayLoader is Loader which can replay objects with their original timing
+type ReplayLoader interface {
+	Loader
+	//SetReplay sets speed of replay of objects in stream (0 to not wait between objects)
+	SetReplay(speed float64)
+}
+
+type bundleLoader struct {
+	devUUID uuid.UUID
+	items   map[infoOrLogs][]bundleItem //sorted from oldest to newest
+	cache   cachers.Cacher
+	rng     Range
+	replay  float64
+}
+
+//BundleLoader return loader of logs, info, metrics and flow logs exported from previous run:
+//support bundle (see 'eden support-bundle') or Adam's device directory as directory, tar or tar.gz file in src.
+//Objects are recognized by the name of directory (logs, info, metrics and flowlog) and by the streams of redis,
+//identical objects are loaded once.
+func BundleLoader(src string) (ReplayLoader, error) {
+	log.Debugf("BundleLoader init")
+	loader := &bundleLoader{items: make(map[infoOrLogs][]bundleItem)}
+	reader := &bundleReader{seen: make(map[string]bool)}
+	fi, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		err = reader.readDir(src)
+	} else {
+		err = reader.readArchive(src)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot load bundle %s: %s", src, err)
+	}
+	for _, item := range reader.pending {
+		if item.devUUID == uuid.Nil {
+			item.devUUID = reader.device
+		}
+		loader.items[item.typeToProcess] = append(loader.items[item.typeToProcess], item.bundleItem)
+	}
+	for _, items := range loader.items {
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].time.Before(items[j].time)
+		})
+	}
+	return loader, nil
+}
+
+//SetRemoteCache add cache layer
+func (loader *bundleLoader) SetRemoteCache(cache cachers.Cacher) {
+	loader.cache = cache
+}
+
+//SetRange set range of objects for ProcessExisting and ProcessStream
+func (loader *bundleLoader) SetRange(r Range) {
+	loader.rng = r
+}
+
+//SetReplay set speed of replay with original timing of objects in ProcessStream,
+//e.g. 1 for original timing and 10 for ten times faster, 0 to process objects without delays
+func (loader *bundleLoader) SetReplay(speed float64) {
+	loader.replay = speed
+}
+
+//Clone create copy
+func (loader *bundleLoader) Clone() Loader {
+	return &bundleLoader{items: loader.items, devUUID: loader.devUUID, cache: loader.cache, rng: loader.rng, replay: loader.replay}
+}
+
+//SetUUID set device UUID, objects of all devices are processed for uuid.Nil
+func (loader *bundleLoader) SetUUID(devUUID uuid.UUID) {
+	loader.devUUID = devUUID
+}
+
+//getItems return objects of device in range from oldest to newest
+func (loader *bundleLoader) getItems(typeToProcess infoOrLogs) [][]byte {
+	var items []bundleItem
+	for _, item := range loader.items[typeToProcess] {
+		if loader.devUUID != uuid.Nil && item.devUUID != uuid.Nil && item.devUUID != loader.devUUID {
+			continue
+		}
+		if loader.rng.Contains(item.time) {
+			items = append(items, item)
+		}
+	}
+	if loader.rng.Tail > 0 && len(items) > loader.rng.Tail {
+		items = items[len(items)-loader.rng.Tail:]
+	}
+	result := make([][]byte, 0, len(items))
+	for _, item := range items {
+		result = append(result, item.data)
+	}
+	return result
+}
+
+func (loader *bundleLoader) processItem(process ProcessFunction, typeToProcess infoOrLogs, data []byte) (bool, error) {
+	if loader.cache != nil {
+		if err := loader.cache.CheckAndSave(loader.devUUID, int(typeToProcess), data); err != nil {
+			log.Errorf("error in cache: %s", err)
+		}
+	}
+	return process(data)
+}
+
+//ProcessExisting for observe objects of bundle from newest to oldest
+//or Tail newest objects from oldest to newest if range has Tail
+func (loader *bundleLoader) ProcessExisting(process ProcessFunction, typeToProcess infoOrLogs) error {
+	items := loader.getItems(typeToProcess)
+	for i := range items {
+		data := items[len(items)-1-i]
+		if loader.rng.Tail > 0 {
+			data = items[i]
+		}
+		doContinue, err := loader.processItem(process, typeToProcess, data)
+		if err != nil {
+			return err
+		}
+		if !doContinue {
+			return nil
+		}
+	}
+	return nil
+}
+
+//ProcessStream for replay objects of bundle from oldest to newest with speed from SetReplay,
+//returns nil after the last object
+func (loader *bundleLoader) ProcessStream(process ProcessFunction, typeToProcess infoOrLogs, timeoutSeconds time.Duration) error {
+	var timeout <-chan time.Time
+	if timeoutSeconds != 0 {
+		timeout = time.After(timeoutSeconds * time.Second)
+	}
+	var last time.Time
+	for _, data := range loader.getItems(typeToProcess) {
+		if t := ObjectTime(data); loader.replay > 0 && !t.IsZero() {
+			if !last.IsZero() && t.After(last) {
+				select {
+				case <-time.After(time.Duration(float64(t.Sub(last)) / loader.replay)):
+				case <-timeout:
+					return fmt.Errorf("timeout")
+				}
+			}
+			last = t
+		}
+		select {
+		case <-timeout:
+			return fmt.Errorf("timeout")
+		default:
+		}
+		doContinue, err := loader.processItem(process, typeToProcess, data)
+		if err != nil {
+			return err
+		}
+		if !doContinue {
+			return nil
+		}
+	}
+	return nil
+}
+
+//bundlePendingItem is object of bundle with type before sorting
+type bundlePendingItem struct {
+	bundleItem
+	typeToProcess infoOrLogs
+}
+
+//bundleReader reads files of bundle
+type bundleReader struct {
+	device  uuid.UUID       //device from manifest of support bundle
+	seen    map[string]bool //objects already loaded
+	pending []bundlePendingItem
+}
+
+//bundleDirTypes are types of objects by name of directory in Adam's device directory and support bundle
+var bundleDirTypes = map[string]infoOrLogs{
+	"logs":    LogsType,
+	"info":    InfoType,
+	"metrics": MetricsType,
+	"flowlog": FlowLogType,
+}
+
+//bundleStreamTypes are types of objects by prefix of redis stream
+var bundleStreamTypes = map[string]infoOrLogs{
+	defaults.DefaultLogsRedisPrefix:    LogsType,
+	defaults.DefaultInfoRedisPrefix:    InfoType,
+	defaults.DefaultMetricsRedisPrefix: MetricsType,
+	defaults.DefaultFlowLogRedisPrefix: FlowLogType,
+}
+
+//bundleDevice return uuid of device from path of Adam's device directory or uuid.Nil
+func bundleDevice(name string) uuid.UUID {
+	parts := strings.Split(name, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == "device" {
+			if devUUID, err := uuid.FromString(parts[i+1]); err == nil {
+				return devUUID
+			}
+		}
+	}
+	return uuid.Nil
+}
+
+func (reader *bundleReader) add(typeToProcess infoOrLogs, devUUID uuid.UUID, data []byte, modTime time.Time) {
+	data = bytes.TrimSpace(data)
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil || !bytes.HasPrefix(data, []byte("{")) {
+		return
+	}
+	key := fmt.Sprintf("%d:%s", typeToProcess, compact.String())
+	if reader.seen[key] {
+		return
+	}
+	reader.seen[key] = true
+	t := ObjectTime(data)
+	if t.IsZero() {
+		t = modTime
+	}
+	reader.pending = append(reader.pending, bundlePendingItem{
+		bundleItem:    bundleItem{devUUID: devUUID, time: t, data: data},
+		typeToProcess: typeToProcess,
+	})
+}
+
+//addFile add objects from file with name (slash separated) of bundle
+func (reader *bundleReader) addFile(name string, data []byte, modTime time.Time) {
+	if path.Base(name) == supportbundle.ManifestFile {
+		var manifest supportbundle.Manifest
+		if err := json.Unmarshal(data, &manifest); err == nil {
+			if devUUID, err := uuid.FromString(manifest.Device); err == nil {
+				reader.device = devUUID
+			}
+		}
+		return
+	}
+	if typeToProcess, ok := bundleDirTypes[path.Base(path.Dir(name))]; ok {
+		reader.add(typeToProcess, bundleDevice(name), data, modTime)
+		return
+	}
+	if path.Ext(name) != ".jsonl" {
+		return
+	}
+	stream := strings.TrimSuffix(path.Base(name), ".jsonl")
+	for prefix, typeToProcess := range bundleStreamTypes {
+		if !strings.HasPrefix(stream, prefix) {
+			continue
+		}
+		devUUID, _ := uuid.FromString(strings.TrimPrefix(stream, prefix))
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+		for scanner.Scan() {
+			var entry struct {
+				Values struct {
+					Object string `json:"object"`
+				} `json:"values"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil && entry.Values.Object != "" {
+				reader.add(typeToProcess, devUUID, []byte(entry.Values.Object), modTime)
+			}
+		}
+	}
+}
+
+func (reader *bundleReader) readDir(src string) error {
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		reader.addFile(filepath.ToSlash(p), data, info.ModTime())
+		return nil
+	})
+}
+
+func (reader *bundleReader) readArchive(src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		reader.addFile(path.Clean("/"+hdr.Name), data, hdr.ModTime)
+	}
+}
@@ -0,0 +1,104 @@
+package loaders
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/supportbundle"
+	uuid "github.com/satori/go.uuid"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//TestBundleLoader test loading of objects from support bundle and their replay
+func TestBundleLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eden-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	devUUID, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherUUID, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2020, 5, 13, 10, 0, 0, 0, time.UTC)
+	src := filepath.Join(dir, "bundle.tar.gz")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := supportbundle.NewWriter(f, devUUID.String())
+	var objects []string
+	for i := 0; i < 3; i++ {
+		obj := string(testObject(now.Add(time.Duration(i) * time.Second)))
+		objects = append(objects, obj)
+		//the same object in different layouts must be loaded once
+		if err = w.Add(fmt.Sprintf("%s/%04d.json", supportbundle.InfoDir, i), []byte(obj)); err != nil {
+			t.Fatal(err)
+		}
+		if err = w.Add(fmt.Sprintf("adam/device/%s/info/%d.json", devUUID, i), []byte(obj+"\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	line, err := json.Marshal(map[string]interface{}{"id": "1-0", "values": map[string]string{"object": `{"devId":"other"}`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Add(fmt.Sprintf("redis/%s%s.jsonl", defaults.DefaultInfoRedisPrefix, otherUUID), line); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loader, err := BundleLoader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collect := func(l Loader, stream bool) (result []string) {
+		process := func(data []byte) (bool, error) {
+			result = append(result, string(data))
+			return true, nil
+		}
+		if stream {
+			err = l.ProcessStream(process, InfoType, 0)
+		} else {
+			err = l.ProcessExisting(process, InfoType)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if result := collect(loader, false); len(result) != 4 {
+		t.Fatalf("expected 4 objects of all devices, got %v", result)
+	}
+	loader.SetUUID(devUUID)
+	if result := collect(loader, false); !reflect.DeepEqual(result, []string{objects[2], objects[1], objects[0]}) {
+		t.Fatalf("expected objects from newest to oldest, got %v", result)
+	}
+	loader.SetRange(Range{Tail: 2})
+	if result := collect(loader.Clone(), false); !reflect.DeepEqual(result, objects[1:]) {
+		t.Fatalf("expected tail from oldest to newest, got %v", result)
+	}
+	loader.SetRange(Range{})
+	loader.SetReplay(10)
+	start := time.Now()
+	if result := collect(loader, true); !reflect.DeepEqual(result, objects) {
+		t.Fatalf("expected replay from oldest to newest, got %v", result)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("replay with original timing is too fast: %s", elapsed)
+	}
+}
@@ -0,0 +1,42 @@
+package loaders
+
+import (
+	"errors"
+)
+
+//ErrNotFound is returned by Check if no existing objects are found and new objects are not watched
+var ErrNotFound = errors.New("no matching objects found")
+
+//ExistFunc must process existing objects and call found on the object which stops processing
+type ExistFunc func(found func()) error
+
+//WatchFunc must process new objects and return nil on the object which stops processing
+type WatchFunc func() error
+
+//Check runs exist and watch (nil to skip any of them) concurrently and returns
+//nil when one of them found object or the first error. If exist ends without found object,
+//result of watch is awaited or ErrNotFound is returned if watch is nil.
+func Check(exist ExistFunc, watch WatchFunc) error {
+	//buffered for both functions to not block the one which ends after result is returned
+	done := make(chan error, 2)
+	if watch != nil {
+		go func() {
+			done <- watch()
+		}()
+	}
+	if exist != nil {
+		go func() {
+			found := false
+			err := exist(func() { found = true })
+			switch {
+			case err != nil:
+				done <- err
+			case found:
+				done <- nil
+			case watch == nil:
+				done <- ErrNotFound
+			}
+		}()
+	}
+	return <-done
+}
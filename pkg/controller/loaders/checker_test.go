@@ -0,0 +1,36 @@
+package loaders
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+//TestCheck test results of existing and new objects processing
+func TestCheck(t *testing.T) {
+	exist := func(found bool) ExistFunc {
+		return func(foundFunc func()) error {
+			if found {
+				foundFunc()
+			}
+			return nil
+		}
+	}
+	if err := Check(exist(true), nil); err != nil {
+		t.Fatalf("found existing object: %s", err)
+	}
+	if err := Check(exist(false), nil); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound without watch, got %v", err)
+	}
+	watchErr := errors.New("timeout")
+	watch := func() error {
+		time.Sleep(10 * time.Millisecond)
+		return watchErr
+	}
+	if err := Check(exist(false), watch); err != watchErr {
+		t.Fatalf("expected result of watch if existing object not found, got %v", err)
+	}
+	if err := Check(exist(true), watch); err != nil {
+		t.Fatalf("existing object must be returned before watch ends: %s", err)
+	}
+}
@@ -0,0 +1,53 @@
+package loaders
+
+import (
+	"fmt"
+	"strings"
+)
+
+//ConsumerKey is key of query with name of watcher ("group" or "group:consumer") for ProcessStream
+//to resume from the last processed object of watcher, it is removed from query by ConsumerFromQuery and RangeFromQuery
+const ConsumerKey = "@consumer"
+
+//ConsumerLoader is implemented by loaders which process new objects as consumer of group:
+//the last processed object of group is kept between runs and objects are shared between consumers of group
+type ConsumerLoader interface {
+	SetConsumer(group string, consumer string)
+}
+
+//ParseConsumer return group and consumer from name of watcher, consumer is equal to group if not set
+func ParseConsumer(name string) (group string, consumer string, err error) {
+	parts := strings.SplitN(name, ":", 2)
+	group, consumer = parts[0], parts[0]
+	if len(parts) == 2 {
+		consumer = parts[1]
+	}
+	if group == "" || consumer == "" {
+		return "", "", fmt.Errorf("wrong name of watcher %q, use group or group:consumer", name)
+	}
+	return group, consumer, nil
+}
+
+//ConsumerFromQuery return copy of query without ConsumerKey and set consumer of loader from it
+func ConsumerFromQuery(loader Loader, query map[string]string) (map[string]string, error) {
+	name, ok := query[ConsumerKey]
+	if !ok {
+		return query, nil
+	}
+	result := make(map[string]string, len(query))
+	for k, v := range query {
+		if k != ConsumerKey {
+			result[k] = v
+		}
+	}
+	group, consumer, err := ParseConsumer(name)
+	if err != nil {
+		return nil, err
+	}
+	consumerLoader, ok := loader.(ConsumerLoader)
+	if !ok {
+		return nil, fmt.Errorf("resumable watch of %s is supported only with redis of adam", name)
+	}
+	consumerLoader.SetConsumer(group, consumer)
+	return result, nil
+}
@@ -0,0 +1,41 @@
+package loaders
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testConsumerLoader struct {
+	memoryLoader
+	group    string
+	consumer string
+}
+
+func (loader *testConsumerLoader) SetConsumer(group string, consumer string) {
+	loader.group = group
+	loader.consumer = consumer
+}
+
+//TestConsumerFromQuery test name of watcher in query
+func TestConsumerFromQuery(t *testing.T) {
+	loader := &testConsumerLoader{}
+	q, err := ConsumerFromQuery(loader, map[string]string{"devId": "dev", ConsumerKey: "ci:runner1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q, map[string]string{"devId": "dev"}) || loader.group != "ci" || loader.consumer != "runner1" {
+		t.Fatalf("wrong query %v or consumer %s:%s", q, loader.group, loader.consumer)
+	}
+	if _, err = ConsumerFromQuery(loader, map[string]string{ConsumerKey: "ci"}); err != nil || loader.consumer != "ci" {
+		t.Fatalf("consumer must be equal to group: %s %v", loader.consumer, err)
+	}
+	if _, err = ConsumerFromQuery(loader, map[string]string{ConsumerKey: ":runner1"}); err == nil {
+		t.Fatal("expected error for empty group")
+	}
+	if _, err = ConsumerFromQuery(&memoryLoader{}, map[string]string{ConsumerKey: "ci"}); err == nil {
+		t.Fatal("expected error for loader without consumer groups")
+	}
+	if q, _, err = RangeFromQuery(map[string]string{"devId": "dev", ConsumerKey: "ci"}); err != nil || !reflect.DeepEqual(q, map[string]string{"devId": "dev"}) {
+		t.Fatalf("ConsumerKey must be removed by RangeFromQuery: %v %v", q, err)
+	}
+}
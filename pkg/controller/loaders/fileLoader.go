@@ -15,16 +15,19 @@ import (
 type getDir = func(devUUID uuid.UUID) (dir string)
 
 type fileLoader struct {
-	devUUID    uuid.UUID
-	logsGetter getDir
-	infoGetter getDir
-	cache      cachers.Cacher
+	devUUID       uuid.UUID
+	logsGetter    getDir
+	infoGetter    getDir
+	metricsGetter getDir
+	flowLogGetter getDir
+	cache         cachers.Cacher
+	rng           Range
 }
 
 //FileLoader return loader from files
-func FileLoader(logsGetter getDir, infoGetter getDir) *fileLoader {
+func FileLoader(logsGetter getDir, infoGetter getDir, metricsGetter getDir, flowLogGetter getDir) *fileLoader {
 	log.Debugf("FileLoader init")
-	return &fileLoader{logsGetter: logsGetter, infoGetter: infoGetter}
+	return &fileLoader{logsGetter: logsGetter, infoGetter: infoGetter, metricsGetter: metricsGetter, flowLogGetter: flowLogGetter}
 }
 
 //SetRemoteCache add cache layer
@@ -32,9 +35,14 @@ func (loader *fileLoader) SetRemoteCache(cache cachers.Cacher) {
 	loader.cache = cache
 }
 
+//SetRange set range of objects for ProcessExisting
+func (loader *fileLoader) SetRange(r Range) {
+	loader.rng = r
+}
+
 //Clone create copy
 func (loader *fileLoader) Clone() Loader {
-	return &fileLoader{logsGetter: loader.logsGetter, infoGetter: loader.infoGetter, devUUID: loader.devUUID, cache: loader.cache}
+	return &fileLoader{logsGetter: loader.logsGetter, infoGetter: loader.infoGetter, metricsGetter: loader.metricsGetter, flowLogGetter: loader.flowLogGetter, devUUID: loader.devUUID, cache: loader.cache, rng: loader.rng}
 }
 
 func (loader *fileLoader) getFilePath(typeToProcess infoOrLogs) string {
@@ -43,6 +51,10 @@ func (loader *fileLoader) getFilePath(typeToProcess infoOrLogs) string {
 		return loader.logsGetter(loader.devUUID)
 	case InfoType:
 		return loader.infoGetter(loader.devUUID)
+	case MetricsType:
+		return loader.metricsGetter(loader.devUUID)
+	case FlowLogType:
+		return loader.flowLogGetter(loader.devUUID)
 	default:
 		return ""
 	}
@@ -53,7 +65,8 @@ func (loader *fileLoader) SetUUID(devUUID uuid.UUID) {
 	loader.devUUID = devUUID
 }
 
-//ProcessExisting for observe existing files
+//ProcessExisting for observe existing files from newest to oldest
+//or Tail newest files from oldest to newest if range has Tail
 func (loader *fileLoader) ProcessExisting(process ProcessFunction, typeToProcess infoOrLogs) error {
 	files, err := ioutil.ReadDir(loader.getFilePath(typeToProcess))
 	if err != nil {
@@ -63,10 +76,22 @@ func (loader *fileLoader) ProcessExisting(process ProcessFunction, typeToProcess
 		return files[i].ModTime().Unix() > files[j].ModTime().Unix()
 	})
 	time.Sleep(1 * time.Second) // wait for write ends
+	processData := func(data []byte) (bool, error) {
+		if loader.cache != nil {
+			if err = loader.cache.CheckAndSave(loader.devUUID, int(typeToProcess), data); err != nil {
+				log.Errorf("error in cache: %s", err)
+			}
+		}
+		return process(data)
+	}
+	var tail [][]byte
 	for _, file := range files {
 		if file.IsDir() {
 			continue
 		}
+		if !loader.rng.Since.IsZero() && file.ModTime().Before(loader.rng.Since) {
+			break // files are written after timestamps of objects inside, so older files are out of range
+		}
 		fileFullPath := path.Join(loader.getFilePath(typeToProcess), file.Name())
 		log.Debugf("local controller parse %s", fileFullPath)
 		data, err := ioutil.ReadFile(fileFullPath)
@@ -74,12 +99,25 @@ func (loader *fileLoader) ProcessExisting(process ProcessFunction, typeToProcess
 			log.Error("Can't open ", fileFullPath)
 			continue
 		}
-		if loader.cache != nil {
-			if err = loader.cache.CheckAndSave(loader.devUUID, int(typeToProcess), data); err != nil {
-				log.Errorf("error in cache: %s", err)
+		if !loader.rng.IsEmpty() && !loader.rng.Contains(ObjectTime(data)) {
+			continue
+		}
+		if loader.rng.Tail > 0 {
+			if tail = append(tail, data); len(tail) == loader.rng.Tail {
+				break
 			}
+			continue
 		}
-		doContinue, err := process(data)
+		doContinue, err := processData(data)
+		if err != nil {
+			return err
+		}
+		if !doContinue {
+			return nil
+		}
+	}
+	for i := len(tail) - 1; i >= 0; i-- {
+		doContinue, err := processData(tail[i])
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,191 @@
+package loaders
+
+import (
+	"fmt"
+	"github.com/lf-edge/eden/pkg/controller/cachers"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+//MemoryBuffer keeps objects of one type for devices in memory and notifies watchers about new ones
+type MemoryBuffer struct {
+	mu       sync.Mutex
+	items    map[uuid.UUID][][]byte
+	watchers map[uuid.UUID][]chan []byte
+}
+
+//Append add object for devUUID into buffer
+func (buffer *MemoryBuffer) Append(devUUID uuid.UUID, data []byte) {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	if buffer.items == nil {
+		buffer.items = make(map[uuid.UUID][][]byte)
+	}
+	buffer.items[devUUID] = append(buffer.items[devUUID], data)
+	for _, ch := range buffer.watchers[devUUID] {
+		select {
+		case ch <- data:
+		default:
+			log.Warning("MemoryBuffer: watcher is not ready, object skipped")
+		}
+	}
+}
+
+//Get return copy of objects for devUUID in order of addition
+func (buffer *MemoryBuffer) Get(devUUID uuid.UUID) [][]byte {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return append([][]byte{}, buffer.items[devUUID]...)
+}
+
+func (buffer *MemoryBuffer) watch(devUUID uuid.UUID) chan []byte {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	if buffer.watchers == nil {
+		buffer.watchers = make(map[uuid.UUID][]chan []byte)
+	}
+	ch := make(chan []byte, 100)
+	buffer.watchers[devUUID] = append(buffer.watchers[devUUID], ch)
+	return ch
+}
+
+func (buffer *MemoryBuffer) unwatch(devUUID uuid.UUID, ch chan []byte) {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	for i, el := range buffer.watchers[devUUID] {
+		if el == ch {
+			buffer.watchers[devUUID] = append(buffer.watchers[devUUID][:i], buffer.watchers[devUUID][i+1:]...)
+			return
+		}
+	}
+}
+
+type memoryLoader struct {
+	devUUID       uuid.UUID
+	logsBuffer    *MemoryBuffer
+	infoBuffer    *MemoryBuffer
+	metricsBuffer *MemoryBuffer
+	flowLogBuffer *MemoryBuffer
+	cache         cachers.Cacher
+	rng           Range
+}
+
+//MemoryLoader return loader from memory buffers
+func MemoryLoader(logsBuffer *MemoryBuffer, infoBuffer *MemoryBuffer, metricsBuffer *MemoryBuffer, flowLogBuffer *MemoryBuffer) *memoryLoader {
+	log.Debugf("MemoryLoader init")
+	return &memoryLoader{logsBuffer: logsBuffer, infoBuffer: infoBuffer, metricsBuffer: metricsBuffer, flowLogBuffer: flowLogBuffer}
+}
+
+//SetRemoteCache add cache layer
+func (loader *memoryLoader) SetRemoteCache(cache cachers.Cacher) {
+	loader.cache = cache
+}
+
+//SetRange set range of objects for ProcessExisting
+func (loader *memoryLoader) SetRange(r Range) {
+	loader.rng = r
+}
+
+//Clone create copy
+func (loader *memoryLoader) Clone() Loader {
+	return &memoryLoader{logsBuffer: loader.logsBuffer, infoBuffer: loader.infoBuffer, metricsBuffer: loader.metricsBuffer, flowLogBuffer: loader.flowLogBuffer, devUUID: loader.devUUID, cache: loader.cache, rng: loader.rng}
+}
+
+func (loader *memoryLoader) getBuffer(typeToProcess infoOrLogs) *MemoryBuffer {
+	switch typeToProcess {
+	case LogsType:
+		return loader.logsBuffer
+	case InfoType:
+		return loader.infoBuffer
+	case MetricsType:
+		return loader.metricsBuffer
+	case FlowLogType:
+		return loader.flowLogBuffer
+	default:
+		return nil
+	}
+}
+
+//SetUUID set device UUID
+func (loader *memoryLoader) SetUUID(devUUID uuid.UUID) {
+	loader.devUUID = devUUID
+}
+
+func (loader *memoryLoader) processItem(process ProcessFunction, typeToProcess infoOrLogs, data []byte) (bool, error) {
+	if loader.cache != nil {
+		if err := loader.cache.CheckAndSave(loader.devUUID, int(typeToProcess), data); err != nil {
+			log.Errorf("error in cache: %s", err)
+		}
+	}
+	return process(data)
+}
+
+//ProcessExisting for observe existing objects from newest to oldest
+//or Tail newest objects from oldest to newest if range has Tail
+func (loader *memoryLoader) ProcessExisting(process ProcessFunction, typeToProcess infoOrLogs) error {
+	buffer := loader.getBuffer(typeToProcess)
+	if buffer == nil {
+		return fmt.Errorf("not implemented type %d", typeToProcess)
+	}
+	var items [][]byte
+	for _, data := range buffer.Get(loader.devUUID) {
+		if loader.rng.IsEmpty() || loader.rng.Contains(ObjectTime(data)) {
+			items = append(items, data)
+		}
+	}
+	if loader.rng.Tail > 0 {
+		if len(items) > loader.rng.Tail {
+			items = items[len(items)-loader.rng.Tail:]
+		}
+		for _, data := range items {
+			doContinue, err := loader.processItem(process, typeToProcess, data)
+			if err != nil {
+				return err
+			}
+			if !doContinue {
+				return nil
+			}
+		}
+		return nil
+	}
+	for i := len(items) - 1; i >= 0; i-- {
+		doContinue, err := loader.processItem(process, typeToProcess, items[i])
+		if err != nil {
+			return err
+		}
+		if !doContinue {
+			return nil
+		}
+	}
+	return nil
+}
+
+//ProcessStream for observe new objects
+func (loader *memoryLoader) ProcessStream(process ProcessFunction, typeToProcess infoOrLogs, timeoutSeconds time.Duration) error {
+	buffer := loader.getBuffer(typeToProcess)
+	if buffer == nil {
+		return fmt.Errorf("not implemented type %d", typeToProcess)
+	}
+	ch := buffer.watch(loader.devUUID)
+	defer buffer.unwatch(loader.devUUID, ch)
+	var timeout <-chan time.Time
+	if timeoutSeconds != 0 {
+		timeout = time.After(timeoutSeconds * time.Second)
+	}
+	for {
+		select {
+		case data := <-ch:
+			doContinue, err := loader.processItem(process, typeToProcess, data)
+			if err != nil {
+				return err
+			}
+			if !doContinue {
+				return nil
+			}
+		case <-timeout:
+			return fmt.Errorf("timeout")
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package loaders
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+//Keys of query with range of objects, they are removed from query by RangeFromQuery
+const (
+	SinceKey = "@since"
+	UntilKey = "@until"
+	TailKey  = "@tail"
+)
+
+//Range limits objects processed by ProcessExisting of Loader
+type Range struct {
+	Since time.Time //objects with timestamp before Since are skipped if not zero
+	Until time.Time //objects with timestamp after Until are skipped if not zero
+	Tail  int       //only Tail newest objects are processed from oldest to newest if not zero
+}
+
+//IsEmpty checks if range does not limit objects
+func (r Range) IsEmpty() bool {
+	return r.Since.IsZero() && r.Until.IsZero() && r.Tail == 0
+}
+
+//Contains checks if t is inside of range, zero t is inside of any range
+func (r Range) Contains(t time.Time) bool {
+	if t.IsZero() {
+		return true
+	}
+	if !r.Since.IsZero() && t.Before(r.Since) {
+		return false
+	}
+	if !r.Until.IsZero() && t.After(r.Until) {
+		return false
+	}
+	return true
+}
+
+//ToQuery put range into query
+func (r Range) ToQuery(query map[string]string) {
+	if !r.Since.IsZero() {
+		query[SinceKey] = r.Since.Format(time.RFC3339Nano)
+	}
+	if !r.Until.IsZero() {
+		query[UntilKey] = r.Until.Format(time.RFC3339Nano)
+	}
+	if r.Tail != 0 {
+		query[TailKey] = strconv.Itoa(r.Tail)
+	}
+}
+
+//RangeFromQuery return copy of query without keys of range and range from them
+func RangeFromQuery(query map[string]string) (map[string]string, Range, error) {
+	var r Range
+	result := make(map[string]string, len(query))
+	for k, v := range query {
+		var err error
+		switch k {
+		case SinceKey:
+			r.Since, err = time.Parse(time.RFC3339Nano, v)
+		case UntilKey:
+			r.Until, err = time.Parse(time.RFC3339Nano, v)
+		case TailKey:
+			r.Tail, err = strconv.Atoi(v)
+		case ConsumerKey:
+			// used only by ConsumerFromQuery for ProcessStream
+		default:
+			result[k] = v
+		}
+		if err != nil {
+			return nil, r, fmt.Errorf("wrong %s: %s", k, err)
+		}
+	}
+	return result, r, nil
+}
+
+//ParseTime return time from RFC3339, "2006-01-02 15:04:05" or "2006-01-02" formats
+//or duration before now, e.g. 5m for five minutes ago
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("wrong time %s, use duration (e.g. 5m), RFC3339 or \"2006-01-02 15:04:05\"", s)
+}
+
+//ObjectTime return timestamp of object (timestamp of logs and atTimeStamp of info and metrics)
+//or zero time if object has no timestamp
+func ObjectTime(data []byte) time.Time {
+	var obj struct {
+		Timestamp   *time.Time `json:"timestamp"`
+		AtTimeStamp *time.Time `json:"atTimeStamp"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return time.Time{}
+	}
+	if obj.AtTimeStamp != nil {
+		return *obj.AtTimeStamp
+	}
+	if obj.Timestamp != nil {
+		return *obj.Timestamp
+	}
+	return time.Time{}
+}
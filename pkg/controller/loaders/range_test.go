@@ -0,0 +1,97 @@
+package loaders
+
+import (
+	"fmt"
+	uuid "github.com/satori/go.uuid"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testObject(t time.Time) []byte {
+	return []byte(fmt.Sprintf(`{"devId":"dev","atTimeStamp":"%s"}`, t.Format(time.RFC3339Nano)))
+}
+
+//TestRange test range of objects in query and in loaders
+func TestRange(t *testing.T) {
+	now := time.Date(2020, 5, 13, 10, 0, 0, 0, time.UTC)
+	r := Range{Since: now.Add(-5 * time.Minute), Until: now, Tail: 2}
+	q := map[string]string{"devId": "dev"}
+	r.ToQuery(q)
+	q, parsed, err := RangeFromQuery(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q, map[string]string{"devId": "dev"}) || !parsed.Since.Equal(r.Since) || !parsed.Until.Equal(r.Until) || parsed.Tail != 2 {
+		t.Fatalf("wrong range %v from query %v", parsed, q)
+	}
+	if since, err := ParseTime("5m", now); err != nil || !since.Equal(r.Since) {
+		t.Fatalf("wrong time: %s %v", since, err)
+	}
+	if _, err = ParseTime("yesterday", now); err == nil {
+		t.Fatal("expected error for wrong time")
+	}
+
+	dir, err := ioutil.TempDir("", "eden-range")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	devUUID, err := uuid.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := &MemoryBuffer{}
+	var expected [][]byte
+	for i := 10; i >= 0; i-- {
+		ts := now.Add(time.Duration(-i) * time.Minute)
+		data := testObject(ts)
+		buffer.Append(devUUID, data)
+		file := filepath.Join(dir, fmt.Sprintf("%d.json", i))
+		if err = ioutil.WriteFile(file, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Chtimes(file, ts, ts); err != nil {
+			t.Fatal(err)
+		}
+		if i >= 1 && i <= 5 {
+			expected = append(expected, data)
+		}
+	}
+	getDir := func(uuid.UUID) string { return dir }
+	for name, loader := range map[string]Loader{
+		"memory": MemoryLoader(nil, buffer, nil, nil),
+		"file":   FileLoader(nil, getDir, nil, nil),
+	} {
+		loader.SetUUID(devUUID)
+		for _, test := range []struct {
+			r        Range
+			expected [][]byte
+		}{
+			{Range{Since: now.Add(-5 * time.Minute), Until: now.Add(-time.Minute)}, expected},
+			{Range{Since: now.Add(-5 * time.Minute), Until: now.Add(-time.Minute), Tail: 2}, expected[3:]},
+			{Range{Tail: 1}, [][]byte{testObject(now)}},
+		} {
+			var processed [][]byte
+			loader.SetRange(test.r)
+			if err = loader.ProcessExisting(func(data []byte) (bool, error) {
+				processed = append(processed, data)
+				return true, nil
+			}, InfoType); err != nil {
+				t.Fatal(err)
+			}
+			if test.r.Tail == 0 {
+				// objects are processed from newest to oldest without tail
+				for i, j := 0, len(processed)-1; i < j; i, j = i+1, j-1 {
+					processed[i], processed[j] = processed[j], processed[i]
+				}
+			}
+			if !reflect.DeepEqual(processed, test.expected) {
+				t.Errorf("%s %+v: expected %s, got %s", name, test.r, test.expected, processed)
+			}
+		}
+	}
+}
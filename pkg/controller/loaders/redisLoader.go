@@ -7,6 +7,7 @@ import (
 	"github.com/lf-edge/eden/pkg/defaults"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -14,27 +15,41 @@ import (
 
 type getStream = func(devUUID uuid.UUID) (stream string)
 
+//groupClient is part of redis client used to read stream as consumer of group
+type groupClient interface {
+	XGroupCreateMkStream(stream, group, start string) *redis.StatusCmd
+	XReadGroup(a *redis.XReadGroupArgs) *redis.XStreamSliceCmd
+	XAck(stream, group string, ids ...string) *redis.IntCmd
+}
+
 type redisLoader struct {
-	lastID     string
-	addr       string
-	password   string
-	databaseID int
-	streamLogs getStream
-	streamInfo getStream
-	client     *redis.Client
-	cache      cachers.Cacher
-	devUUID    uuid.UUID
+	lastID        string
+	addr          string
+	password      string
+	databaseID    int
+	streamLogs    getStream
+	streamInfo    getStream
+	streamMetrics getStream
+	streamFlowLog getStream
+	client        *redis.Client
+	cache         cachers.Cacher
+	devUUID       uuid.UUID
+	rng           Range
+	group         string
+	consumer      string
 }
 
 //RedisLoader return loader from redis
-func RedisLoader(addr string, password string, databaseID int, streamLogs getStream, streamInfo getStream) *redisLoader {
+func RedisLoader(addr string, password string, databaseID int, streamLogs getStream, streamInfo getStream, streamMetrics getStream, streamFlowLog getStream) *redisLoader {
 	log.Debugf("RedisLoader init")
 	return &redisLoader{
-		addr:       addr,
-		password:   password,
-		databaseID: databaseID,
-		streamLogs: streamLogs,
-		streamInfo: streamInfo,
+		addr:          addr,
+		password:      password,
+		databaseID:    databaseID,
+		streamLogs:    streamLogs,
+		streamInfo:    streamInfo,
+		streamMetrics: streamMetrics,
+		streamFlowLog: streamFlowLog,
 	}
 }
 
@@ -46,23 +61,44 @@ func (loader *redisLoader) SetRemoteCache(cache cachers.Cacher) {
 //Clone create copy
 func (loader *redisLoader) Clone() Loader {
 	return &redisLoader{
-		addr:       loader.addr,
-		password:   loader.password,
-		databaseID: loader.databaseID,
-		streamLogs: loader.streamLogs,
-		streamInfo: loader.streamInfo,
-		lastID:     "",
-		cache:      loader.cache,
-		devUUID:    loader.devUUID,
+		addr:          loader.addr,
+		password:      loader.password,
+		databaseID:    loader.databaseID,
+		streamLogs:    loader.streamLogs,
+		streamInfo:    loader.streamInfo,
+		streamMetrics: loader.streamMetrics,
+		streamFlowLog: loader.streamFlowLog,
+		lastID:        "",
+		cache:         loader.cache,
+		devUUID:       loader.devUUID,
+		rng:           loader.rng,
+		group:         loader.group,
+		consumer:      loader.consumer,
 	}
 }
 
+//SetRange set range of objects for ProcessExisting
+func (loader *redisLoader) SetRange(r Range) {
+	loader.rng = r
+}
+
+//SetConsumer set consumer group for ProcessStream: new objects are read as consumer of group
+//from the last object acknowledged by group, objects are acknowledged after processing
+func (loader *redisLoader) SetConsumer(group string, consumer string) {
+	loader.group = group
+	loader.consumer = consumer
+}
+
 func (loader *redisLoader) getStream(typeToProcess infoOrLogs) string {
 	switch typeToProcess {
 	case LogsType:
 		return loader.streamLogs(loader.devUUID)
 	case InfoType:
 		return loader.streamInfo(loader.devUUID)
+	case MetricsType:
+		return loader.streamMetrics(loader.devUUID)
+	case FlowLogType:
+		return loader.streamFlowLog(loader.devUUID)
 	default:
 		return ""
 	}
@@ -77,6 +113,13 @@ func (loader *redisLoader) process(process ProcessFunction, typeToProcess infoOr
 	OrderStream := loader.getStream(typeToProcess)
 	if !stream {
 		start := "-"
+		if !loader.rng.Since.IsZero() {
+			// objects are added into stream after their timestamps, so IDs of objects in range are not less
+			start = fmt.Sprintf("%d-0", loader.rng.Since.UnixNano()/int64(time.Millisecond))
+		}
+		if loader.rng.Tail > 0 {
+			return loader.processTail(process, typeToProcess, OrderStream, start)
+		}
 		for {
 			rr, err := loader.client.XRangeN(OrderStream, start, "+", 10).Result()
 			if err != nil {
@@ -91,6 +134,9 @@ func (loader *redisLoader) process(process ProcessFunction, typeToProcess infoOr
 				loader.lastID = r.ID
 				log.Debugf("lastID: %s", loader.lastID)
 				data := []byte(r.Values["object"].(string))
+				if !loader.rng.IsEmpty() && !loader.rng.Contains(ObjectTime(data)) {
+					continue
+				}
 				tocontinue, err := process(data)
 				if err != nil {
 					return false, false, fmt.Errorf("process: %s", err)
@@ -108,6 +154,8 @@ func (loader *redisLoader) process(process ProcessFunction, typeToProcess infoOr
 			counter, _ := strconv.Atoi(splitted[1])
 			start = fmt.Sprintf("%s-%v", splitted[0], counter+1)
 		}
+	} else if loader.group != "" {
+		return loader.processGroup(loader.client, process, typeToProcess, OrderStream)
 	} else {
 		log.Debugf("XRead from %s", OrderStream)
 		start := "$"
@@ -170,6 +218,126 @@ func (loader *redisLoader) process(process ProcessFunction, typeToProcess infoOr
 	}
 }
 
+//processGroup process objects of stream as consumer of group: pending objects of consumer
+//(read but not acknowledged before) are processed first, then new objects of group
+func (loader *redisLoader) processGroup(client groupClient, process ProcessFunction, typeToProcess infoOrLogs, stream string) (processed, found bool, err error) {
+	// new group starts from the end of stream, existing group continues from its last object
+	if err = client.XGroupCreateMkStream(stream, loader.group, "$").Err(); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return false, false, fmt.Errorf("XGroupCreate error: %s", err)
+	}
+	start := "0"
+	for {
+		block := time.Duration(-1)
+		if start == ">" {
+			block = 0
+		}
+		log.Debugf("XReadGroup %s from %s as %s:%s", start, stream, loader.group, loader.consumer)
+		rr, err := client.XReadGroup(&redis.XReadGroupArgs{
+			Group:    loader.group,
+			Consumer: loader.consumer,
+			Streams:  []string{stream, start},
+			Count:    10,
+			Block:    block,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return false, false, fmt.Errorf("XReadGroup error: %s", err)
+		}
+		if len(rr) == 0 || len(rr[0].Messages) == 0 {
+			// no more pending objects
+			start = ">"
+			continue
+		}
+		for _, r := range rr[0].Messages {
+			loader.lastID = r.ID
+			log.Debugf("XReadGroup lastID: %s", loader.lastID)
+			if start != ">" {
+				start = r.ID
+			}
+			object, ok := r.Values["object"].(string)
+			if !ok {
+				// object was trimmed from stream
+				if err = client.XAck(stream, loader.group, r.ID).Err(); err != nil {
+					return false, false, fmt.Errorf("XAck error: %s", err)
+				}
+				continue
+			}
+			data := []byte(object)
+			tocontinue, err := process(data)
+			if err != nil {
+				return false, false, fmt.Errorf("process: %s", err)
+			}
+			if loader.cache != nil {
+				if err = loader.cache.CheckAndSave(loader.devUUID, int(typeToProcess), data); err != nil {
+					log.Errorf("error in cache: %s", err)
+				}
+			}
+			if err = client.XAck(stream, loader.group, r.ID).Err(); err != nil {
+				return false, false, fmt.Errorf("XAck error: %s", err)
+			}
+			if !tocontinue {
+				return true, true, nil
+			}
+		}
+	}
+}
+
+//prevStreamID return ID of stream before id or empty string for the first one
+func prevStreamID(id string) string {
+	splitted := strings.Split(id, "-")
+	if len(splitted) != 2 {
+		return ""
+	}
+	ms, _ := strconv.ParseUint(splitted[0], 10, 64)
+	counter, _ := strconv.ParseUint(splitted[1], 10, 64)
+	switch {
+	case counter > 0:
+		return fmt.Sprintf("%d-%d", ms, counter-1)
+	case ms > 0:
+		return fmt.Sprintf("%d-%d", ms-1, uint64(math.MaxUint64))
+	default:
+		return ""
+	}
+}
+
+//processTail process Tail newest objects in range with IDs from start from oldest to newest
+func (loader *redisLoader) processTail(process ProcessFunction, typeToProcess infoOrLogs, stream string, start string) (processed, found bool, err error) {
+	var tail [][]byte
+	end := "+"
+	for end != "" && len(tail) < loader.rng.Tail {
+		rr, err := loader.client.XRevRangeN(stream, end, start, 10).Result()
+		if err != nil {
+			return false, false, fmt.Errorf("XRevRange error: %s", err)
+		}
+		if len(rr) == 0 {
+			break
+		}
+		for _, r := range rr {
+			data := []byte(r.Values["object"].(string))
+			if loader.rng.Contains(ObjectTime(data)) {
+				if tail = append(tail, data); len(tail) == loader.rng.Tail {
+					break
+				}
+			}
+		}
+		end = prevStreamID(rr[len(rr)-1].ID)
+	}
+	for i := len(tail) - 1; i >= 0; i-- {
+		tocontinue, err := process(tail[i])
+		if err != nil {
+			return false, false, fmt.Errorf("process: %s", err)
+		}
+		if loader.cache != nil {
+			if err = loader.cache.CheckAndSave(loader.devUUID, int(typeToProcess), tail[i]); err != nil {
+				log.Errorf("error in cache: %s", err)
+			}
+		}
+		if !tocontinue {
+			return true, true, nil
+		}
+	}
+	return true, false, nil
+}
+
 func (loader *redisLoader) repeatableConnection(process ProcessFunction, typeToProcess infoOrLogs, stream bool) error {
 	if _, _, err := loader.process(process, typeToProcess, stream); err == nil {
 		return nil
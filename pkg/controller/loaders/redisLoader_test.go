@@ -0,0 +1,89 @@
+package loaders
+
+import (
+	"errors"
+	"github.com/go-redis/redis/v7"
+	"reflect"
+	"testing"
+)
+
+//testGroupClient is stream with one consumer of group in memory
+type testGroupClient struct {
+	messages []redis.XMessage //all messages of stream
+	next     int              //index of the first message not delivered to group
+	pending  []redis.XMessage //delivered to consumer and not acknowledged
+	starts   []string         //IDs requested by XReadGroup
+	acked    []string
+}
+
+func (client *testGroupClient) XGroupCreateMkStream(stream, group, start string) *redis.StatusCmd {
+	return redis.NewStatusResult("", errors.New("BUSYGROUP Consumer Group name already exists"))
+}
+
+func (client *testGroupClient) XReadGroup(a *redis.XReadGroupArgs) *redis.XStreamSliceCmd {
+	start := a.Streams[1]
+	client.starts = append(client.starts, start)
+	var messages []redis.XMessage
+	if start == ">" {
+		for ; client.next < len(client.messages) && int64(len(messages)) < a.Count; client.next++ {
+			messages = append(messages, client.messages[client.next])
+			client.pending = append(client.pending, client.messages[client.next])
+		}
+	} else {
+		for _, m := range client.pending {
+			if m.ID > start {
+				messages = append(messages, m)
+			}
+		}
+	}
+	if len(messages) == 0 {
+		return redis.NewXStreamSliceCmdResult(nil, redis.Nil)
+	}
+	return redis.NewXStreamSliceCmdResult([]redis.XStream{{Stream: a.Streams[0], Messages: messages}}, nil)
+}
+
+func (client *testGroupClient) XAck(stream, group string, ids ...string) *redis.IntCmd {
+	for _, id := range ids {
+		for i, m := range client.pending {
+			if m.ID == id {
+				client.pending = append(client.pending[:i], client.pending[i+1:]...)
+				client.acked = append(client.acked, id)
+				break
+			}
+		}
+	}
+	return redis.NewIntResult(int64(len(ids)), nil)
+}
+
+//TestProcessGroup test that pending objects of consumer are processed before new ones and all of them are acknowledged
+func TestProcessGroup(t *testing.T) {
+	message := func(id, object string) redis.XMessage {
+		return redis.XMessage{ID: id, Values: map[string]interface{}{"object": object}}
+	}
+	client := &testGroupClient{
+		messages: []redis.XMessage{message("1-0", "pending"), message("2-0", "new"), {ID: "3-0"}, message("4-0", "last")},
+		next:     1,
+	}
+	client.pending = []redis.XMessage{client.messages[0]}
+	loader := &redisLoader{group: "group", consumer: "consumer"}
+	var processed []string
+	_, found, err := loader.processGroup(client, func(data []byte) (bool, error) {
+		processed = append(processed, string(data))
+		return string(data) != "last", nil
+	}, LogsType, "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("expected object to be found")
+	}
+	if !reflect.DeepEqual(processed, []string{"pending", "new", "last"}) {
+		t.Fatalf("wrong order of objects: %v", processed)
+	}
+	if !reflect.DeepEqual(client.starts, []string{"0", "1-0", ">"}) {
+		t.Fatalf("wrong reads of group: %v", client.starts)
+	}
+	if !reflect.DeepEqual(client.acked, []string{"1-0", "2-0", "3-0", "4-0"}) || len(client.pending) != 0 {
+		t.Fatalf("not all objects acknowledged: acked %v, pending %v", client.acked, client.pending)
+	}
+}
@@ -8,12 +8,16 @@ import (
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/lf-edge/eden/pkg/controller/cachers"
 	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eve/api/go/flowlog"
 	"github.com/lf-edge/eve/api/go/info"
 	"github.com/lf-edge/eve/api/go/logs"
+	"github.com/lf-edge/eve/api/go/metrics"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -33,15 +37,18 @@ type remoteLoader struct {
 	devUUID      uuid.UUID
 	urlLogs      getUrl
 	urlInfo      getUrl
+	urlMetrics   getUrl
+	urlFlowLog   getUrl
 	getClient    getClient
 	client       *http.Client
 	cache        cachers.Cacher
+	rng          Range
 }
 
 //RemoteLoader return loader from files
-func RemoteLoader(getClient getClient, urlLogs getUrl, urlInfo getUrl) *remoteLoader {
+func RemoteLoader(getClient getClient, urlLogs getUrl, urlInfo getUrl, urlMetrics getUrl, urlFlowLog getUrl) *remoteLoader {
 	log.Debugf("HTTP RemoteLoader init")
-	return &remoteLoader{urlLogs: urlLogs, urlInfo: urlInfo, getClient: getClient, firstLoad: true, lastTimesamp: nil, client: getClient()}
+	return &remoteLoader{urlLogs: urlLogs, urlInfo: urlInfo, urlMetrics: urlMetrics, urlFlowLog: urlFlowLog, getClient: getClient, firstLoad: true, lastTimesamp: nil, client: getClient()}
 }
 
 //SetRemoteCache add cache layer
@@ -51,7 +58,13 @@ func (loader *remoteLoader) SetRemoteCache(cache cachers.Cacher) {
 
 //Clone create copy
 func (loader *remoteLoader) Clone() Loader {
-	return &remoteLoader{urlLogs: loader.urlLogs, urlInfo: loader.urlInfo, getClient: loader.getClient, firstLoad: true, lastTimesamp: nil, devUUID: loader.devUUID, client: loader.getClient(), cache: loader.cache}
+	return &remoteLoader{urlLogs: loader.urlLogs, urlInfo: loader.urlInfo, urlMetrics: loader.urlMetrics, urlFlowLog: loader.urlFlowLog, getClient: loader.getClient, firstLoad: true, lastTimesamp: nil, devUUID: loader.devUUID, client: loader.getClient(), cache: loader.cache, rng: loader.rng}
+}
+
+//SetRange set range of objects for ProcessExisting, it is sent to controller as since, until and tail parameters
+//of request and checked for received objects
+func (loader *remoteLoader) SetRange(r Range) {
+	loader.rng = r
 }
 
 func (loader *remoteLoader) getUrl(typeToProcess infoOrLogs) string {
@@ -60,6 +73,10 @@ func (loader *remoteLoader) getUrl(typeToProcess infoOrLogs) string {
 		return loader.urlLogs(loader.devUUID)
 	case InfoType:
 		return loader.urlInfo(loader.devUUID)
+	case MetricsType:
+		return loader.urlMetrics(loader.devUUID)
+	case FlowLogType:
+		return loader.urlFlowLog(loader.devUUID)
 	default:
 		return ""
 	}
@@ -109,6 +126,28 @@ func (loader *remoteLoader) processNext(decoder *json.Decoder, process ProcessFu
 		if err := mler.Marshal(&buf, &emp); err != nil {
 			return false, false, err
 		}
+	case MetricsType:
+		var emp metrics.ZMetricMsg
+		if err := jsonpb.UnmarshalNext(decoder, &emp); err == io.EOF {
+			return false, false, nil
+		} else if err != nil {
+			return false, false, err
+		}
+		mler := jsonpb.Marshaler{}
+		if err := mler.Marshal(&buf, &emp); err != nil {
+			return false, false, err
+		}
+	case FlowLogType:
+		var emp flowlog.FlowMessage
+		if err := jsonpb.UnmarshalNext(decoder, &emp); err == io.EOF {
+			return false, false, nil
+		} else if err != nil {
+			return false, false, err
+		}
+		mler := jsonpb.Marshaler{}
+		if err := mler.Marshal(&buf, &emp); err != nil {
+			return false, false, err
+		}
 	}
 	if loader.cache != nil {
 		if err = loader.cache.CheckAndSave(loader.devUUID, int(typeToProcess), buf.Bytes()); err != nil {
@@ -119,6 +158,11 @@ func (loader *remoteLoader) processNext(decoder *json.Decoder, process ProcessFu
 		loader.curCount++
 		return false, true, nil
 	}
+	if !stream && !loader.rng.IsEmpty() && !loader.rng.Contains(ObjectTime(buf.Bytes())) {
+		loader.curCount++
+		loader.lastCount = loader.curCount
+		return false, true, nil
+	}
 	tocontinue, err = process(buf.Bytes())
 	if stream {
 		time.Sleep(1 * time.Second) //wait for load all data from buffer
@@ -130,6 +174,9 @@ func (loader *remoteLoader) processNext(decoder *json.Decoder, process ProcessFu
 
 func (loader *remoteLoader) process(process ProcessFunction, typeToProcess infoOrLogs, stream bool) (processed, found bool, err error) {
 	u := loader.getUrl(typeToProcess)
+	if !stream && !loader.rng.IsEmpty() {
+		u = loader.rangeURL(u)
+	}
 	log.Debugf("remote controller request %s", u)
 	req, err := http.NewRequest("GET", u, nil)
 	if stream {
@@ -151,6 +198,26 @@ func (loader *remoteLoader) process(process ProcessFunction, typeToProcess infoO
 	}
 }
 
+//rangeURL return u with parameters of range
+func (loader *remoteLoader) rangeURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	params := parsed.Query()
+	if !loader.rng.Since.IsZero() {
+		params.Set("since", loader.rng.Since.Format(time.RFC3339Nano))
+	}
+	if !loader.rng.Until.IsZero() {
+		params.Set("until", loader.rng.Until.Format(time.RFC3339Nano))
+	}
+	if loader.rng.Tail > 0 {
+		params.Set("tail", strconv.Itoa(loader.rng.Tail))
+	}
+	parsed.RawQuery = params.Encode()
+	return parsed.String()
+}
+
 func infoProcessInit(bytes []byte) (bool, error) {
 	return true, nil
 }
@@ -191,7 +258,7 @@ repeatLoop:
 				if _, _, err := loader.process(process, typeToProcess, stream); err == nil {
 					return nil
 				} else {
-					log.Debugf("error in controller request", err)
+					log.Debugf("error in controller request: %s", err)
 				}
 			}
 		}
@@ -203,8 +270,30 @@ repeatLoop:
 }
 
 //ProcessExisting for observe existing files
+//or Tail newest objects from oldest to newest if range has Tail
 func (loader *remoteLoader) ProcessExisting(process ProcessFunction, typeToProcess infoOrLogs) error {
-	return loader.repeatableConnection(process, typeToProcess, false)
+	if loader.rng.Tail == 0 {
+		return loader.repeatableConnection(process, typeToProcess, false)
+	}
+	var tail [][]byte
+	if err := loader.repeatableConnection(func(data []byte) (bool, error) {
+		if tail = append(tail, data); len(tail) > loader.rng.Tail {
+			tail = tail[1:]
+		}
+		return true, nil
+	}, typeToProcess, false); err != nil {
+		return err
+	}
+	for _, data := range tail {
+		doContinue, err := process(data)
+		if err != nil {
+			return err
+		}
+		if !doContinue {
+			return nil
+		}
+	}
+	return nil
 }
 
 //ProcessExisting for observe new files
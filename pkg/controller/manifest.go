@@ -0,0 +1,547 @@
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/lf-edge/eden/pkg/device"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/lf-edge/eve/api/go/evecommon"
+	uuid "github.com/satori/go.uuid"
+	"gopkg.in/yaml.v2"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//Manifest describes datastores, images, network instances and apps of device by names
+type Manifest struct {
+	Datastores       []*ManifestDatastore       `yaml:"datastores"`
+	Images           []*ManifestImage           `yaml:"images"`
+	NetworkInstances []*ManifestNetworkInstance `yaml:"networkInstances"`
+	Apps             []*ManifestApp             `yaml:"apps"`
+}
+
+//ManifestDatastore describes datastore
+type ManifestDatastore struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"` //http, https, s3, sftp, container or azure
+	FQDN     string `yaml:"fqdn"`
+	Path     string `yaml:"path"`
+	APIKey   string `yaml:"apiKey"`
+	Password string `yaml:"password"`
+	Region   string `yaml:"region"`
+}
+
+//ManifestImage describes image inside datastore
+type ManifestImage struct {
+	Name      string `yaml:"name"`
+	Datastore string `yaml:"datastore"`
+	Path      string `yaml:"path"`   //path inside datastore, name is used if empty
+	Format    string `yaml:"format"` //qcow2, raw, container, etc.
+	Sha256    string `yaml:"sha256"`
+	Size      int64  `yaml:"size"`
+	File      string `yaml:"file"` //local copy of image to calculate sha256 and size
+}
+
+//ManifestDhcpRange describes range of dhcp addresses
+type ManifestDhcpRange struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+//ManifestNetworkInstance describes network instance
+type ManifestNetworkInstance struct {
+	Name      string             `yaml:"name"`
+	Type      string             `yaml:"type"` //local, switch or cloud
+	Port      string             `yaml:"port"` //uplink for local and cloud and first adapter for switch if empty
+	Subnet    string             `yaml:"subnet"`
+	Gateway   string             `yaml:"gateway"`
+	DNS       []string           `yaml:"dns"`
+	DhcpRange *ManifestDhcpRange `yaml:"dhcpRange"`
+	VPN       *ManifestVPN       `yaml:"vpn"` //required for cloud
+}
+
+//ManifestVPN describes VPN connection of cloud network instance
+type ManifestVPN struct {
+	Gateway      string `yaml:"gateway"` //IP of VPN gateway
+	Subnet       string `yaml:"subnet"`  //subnet behind VPN gateway
+	PreSharedKey string `yaml:"preSharedKey"`
+}
+
+//vpnClient is client entry of opaque config of VPN
+type vpnClient struct {
+	IPAddr       string `json:"IpAddr"`
+	PreSharedKey string `json:"PreSharedKey"`
+	SubnetBlock  string `json:"SubnetBlock"`
+}
+
+//vpnConfig is opaque config of VPN for cloud network instance
+type vpnConfig struct {
+	VpnRole          string      `json:"VpnRole"`
+	VpnGatewayIPAddr string      `json:"VpnGatewayIpAddr"`
+	VpnSubnetBlock   string      `json:"VpnSubnetBlock"`
+	ClientConfigList []vpnClient `json:"ClientConfigList"`
+}
+
+//ManifestApp describes app instance
+type ManifestApp struct {
+	Name       string   `yaml:"name"`
+	Image      string   `yaml:"image"`
+	Memory     uint32   `yaml:"memory"` //in KiB
+	CPUs       uint32   `yaml:"cpus"`
+	Networks   []string `yaml:"networks"` //names of network instances in manifest or on device
+	UserData   string   `yaml:"userData"`
+	Ports      []string `yaml:"ports"` //published ports in format port:appPort[/udp]
+	VNCDisplay *uint32  `yaml:"vncDisplay"`
+	Activate   *bool    `yaml:"activate"`
+}
+
+var datastoreTypes = map[string]config.DsType{
+	"http":      config.DsType_DsHttp,
+	"https":     config.DsType_DsHttps,
+	"s3":        config.DsType_DsS3,
+	"sftp":      config.DsType_DsSFTP,
+	"container": config.DsType_DsContainerRegistry,
+	"azure":     config.DsType_DsAzureBlob,
+}
+
+var networkInstanceTypes = map[string]config.ZNetworkInstType{
+	"local":  config.ZNetworkInstType_ZnetInstLocal,
+	"switch": config.ZNetworkInstType_ZnetInstSwitch,
+	"cloud":  config.ZNetworkInstType_ZnetInstCloud,
+}
+
+//LoadManifest parses manifest from yaml, unknown fields are errors
+func LoadManifest(data []byte) (*Manifest, error) {
+	var manifest Manifest
+	if err := yaml.UnmarshalStrict(data, &manifest); err != nil {
+		return nil, fmt.Errorf("cannot parse manifest: %s", err)
+	}
+	return &manifest, nil
+}
+
+//ManifestID return uuid of object of kind with name, it is the same for every apply of manifest
+func ManifestID(kind, name string) string {
+	return uuid.NewV5(uuid.NamespaceURL, fmt.Sprintf("eden://%s/%s", kind, name)).String()
+}
+
+//nextVersion return version of object to replace old one: the same if objects are equal and incremented otherwise
+func nextVersion(oldUV, newUV *config.UUIDandVersion, equal func() bool) {
+	newUV.Version = oldUV.GetVersion()
+	if equal() {
+		return
+	}
+	version, _ := strconv.Atoi(oldUV.GetVersion())
+	newUV.Version = strconv.Itoa(version + 1)
+}
+
+func (cloud *CloudCtx) applyDatastore(el *ManifestDatastore) error {
+	dsType, ok := datastoreTypes[el.Type]
+	if !ok {
+		return fmt.Errorf("datastore %s: unknown type %q", el.Name, el.Type)
+	}
+	ds := &config.DatastoreConfig{
+		Id:       ManifestID("datastore", el.Name),
+		DType:    dsType,
+		Fqdn:     el.FQDN,
+		ApiKey:   el.APIKey,
+		Password: el.Password,
+		Dpath:    el.Path,
+		Region:   el.Region,
+	}
+	if old, err := cloud.GetDataStore(ds.Id); err == nil {
+		if proto.Equal(old, ds) {
+			return nil
+		}
+		if err = cloud.RemoveDataStore(ds.Id); err != nil {
+			return err
+		}
+	}
+	return cloud.AddDataStore(ds)
+}
+
+func (cloud *CloudCtx) applyImage(el *ManifestImage) error {
+	format, ok := config.Format_value[strings.ToUpper(el.Format)]
+	if !ok {
+		return fmt.Errorf("image %s: unknown format %q", el.Name, el.Format)
+	}
+	img := &config.Image{
+		Uuidandversion: &config.UUIDandVersion{Uuid: ManifestID("image", el.Name), Version: "1"},
+		Name:           el.Path,
+		Sha256:         el.Sha256,
+		Iformat:        config.Format(format),
+		DsId:           ManifestID("datastore", el.Datastore),
+		SizeBytes:      el.Size,
+		Siginfo:        &config.SignatureInfo{},
+	}
+	if img.Name == "" {
+		img.Name = el.Name
+	}
+	if el.File != "" {
+		fi, err := os.Stat(el.File)
+		if err != nil {
+			return fmt.Errorf("image %s: %s", el.Name, err)
+		}
+		img.SizeBytes = fi.Size()
+		if img.Iformat == config.Format_CONTAINER {
+			img.Sha256, err = utils.ComputeShaOCITar(el.File)
+		} else {
+			img.Sha256, err = utils.SHA256SUM(el.File)
+		}
+		if err != nil {
+			return fmt.Errorf("image %s: %s", el.Name, err)
+		}
+	}
+	if _, err := cloud.GetDataStore(img.DsId); err != nil {
+		return fmt.Errorf("image %s: unknown datastore %s", el.Name, el.Datastore)
+	}
+	if old, err := cloud.GetImage(img.Uuidandversion.Uuid); err == nil {
+		nextVersion(old.Uuidandversion, img.Uuidandversion, func() bool { return proto.Equal(old, img) })
+		if proto.Equal(old, img) {
+			return nil
+		}
+		if err = cloud.RemoveImage(img.Uuidandversion.Uuid); err != nil {
+			return err
+		}
+	}
+	return cloud.AddImage(img)
+}
+
+//SubnetDefaults return first host of IPv4 subnet as gateway and range from second to last host for dhcp
+func SubnetDefaults(subnet string) (gateway string, dhcpRange *ManifestDhcpRange, err error) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return "", nil, err
+	}
+	ip := ipNet.IP.To4()
+	if ip == nil {
+		return "", nil, fmt.Errorf("subnet %s is not IPv4", subnet)
+	}
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones < 2 {
+		return "", nil, fmt.Errorf("subnet %s is too small", subnet)
+	}
+	first := binary.BigEndian.Uint32(ip)
+	size := uint32(1) << uint(bits-ones)
+	host := func(n uint32) string {
+		res := make(net.IP, 4)
+		binary.BigEndian.PutUint32(res, first+n)
+		return res.String()
+	}
+	return host(1), &ManifestDhcpRange{Start: host(2), End: host(size - 2)}, nil
+}
+
+func (cloud *CloudCtx) applyNetworkInstance(dev *device.Ctx, el *ManifestNetworkInstance) error {
+	niType, ok := networkInstanceTypes[el.Type]
+	if !ok {
+		return fmt.Errorf("network instance %s: unknown type %q", el.Name, el.Type)
+	}
+	ni := &config.NetworkInstanceConfig{
+		Uuidandversion: &config.UUIDandVersion{Uuid: ManifestID("networkInstance", el.Name), Version: "1"},
+		Displayname:    el.Name,
+		InstType:       niType,
+		Activate:       true,
+		Port:           &config.Adapter{Name: el.Port},
+		Cfg:            &config.NetworkInstanceOpaqueConfig{},
+		IpType:         config.AddressType_First,
+	}
+	switch niType {
+	case config.ZNetworkInstType_ZnetInstSwitch:
+		if ni.Port.Name == "" {
+			ni.Port.Name = "uplink"
+			if adapters := dev.GetAdaptersForSwitch(); len(adapters) > 0 {
+				ni.Port.Name = adapters[0]
+			}
+		}
+	default:
+		if el.Subnet == "" {
+			return fmt.Errorf("network instance %s: subnet is required for %s type", el.Name, el.Type)
+		}
+		if ni.Port.Name == "" {
+			ni.Port.Name = "uplink"
+		}
+		ni.IpType = config.AddressType_IPV4
+		ni.Ip = &config.Ipspec{Subnet: el.Subnet, Gateway: el.Gateway, Dns: el.DNS}
+		if el.DhcpRange != nil {
+			ni.Ip.DhcpRange = &config.IpRange{Start: el.DhcpRange.Start, End: el.DhcpRange.End}
+		}
+		if niType == config.ZNetworkInstType_ZnetInstLocal && (ni.Ip.Gateway == "" || ni.Ip.DhcpRange == nil) {
+			gateway, dhcpRange, err := SubnetDefaults(el.Subnet)
+			if err != nil {
+				return fmt.Errorf("network instance %s: %s", el.Name, err)
+			}
+			if ni.Ip.Gateway == "" {
+				ni.Ip.Gateway = gateway
+			}
+			if len(ni.Ip.Dns) == 0 {
+				ni.Ip.Dns = []string{ni.Ip.Gateway}
+			}
+			if ni.Ip.DhcpRange == nil {
+				ni.Ip.DhcpRange = &config.IpRange{Start: dhcpRange.Start, End: dhcpRange.End}
+			}
+		}
+		if niType == config.ZNetworkInstType_ZnetInstCloud {
+			vpn := el.VPN
+			if vpn == nil || vpn.Gateway == "" || vpn.Subnet == "" || vpn.PreSharedKey == "" {
+				return fmt.Errorf("network instance %s: vpn with gateway, subnet and preSharedKey is required for cloud type", el.Name)
+			}
+			oconfig, err := json.Marshal(vpnConfig{
+				VpnRole:          "onPremClient",
+				VpnGatewayIPAddr: vpn.Gateway,
+				VpnSubnetBlock:   vpn.Subnet,
+				ClientConfigList: []vpnClient{{IPAddr: "%any", PreSharedKey: vpn.PreSharedKey, SubnetBlock: el.Subnet}},
+			})
+			if err != nil {
+				return err
+			}
+			ni.Port.Type = evecommon.PhyIoType_PhyIoNoop
+			ni.Ip.Dhcp = config.DHCPType_DHCPNone
+			ni.Cfg = &config.NetworkInstanceOpaqueConfig{
+				Oconfig: string(oconfig),
+				Type:    config.ZNetworkOpaqueConfigType_ZNetOConfigVPN,
+			}
+		}
+	}
+	id := ni.Uuidandversion.Uuid
+	if old, err := cloud.GetNetworkInstanceConfig(id); err == nil {
+		nextVersion(old.Uuidandversion, ni.Uuidandversion, func() bool { return proto.Equal(old, ni) })
+		if !proto.Equal(old, ni) {
+			if err = cloud.RemoveNetworkInstanceConfig(id); err != nil {
+				return err
+			}
+			if err = cloud.AddNetworkInstanceConfig(ni); err != nil {
+				return err
+			}
+		}
+	} else if err = cloud.AddNetworkInstanceConfig(ni); err != nil {
+		return err
+	}
+	if _, ok := utils.FindEleInSlice(dev.GetNetworkInstances(), id); !ok {
+		dev.SetNetworkInstanceConfig(append(dev.GetNetworkInstances(), id))
+	}
+	return nil
+}
+
+//findNetworkInstance return id of network instance with name from manifest or from device
+func (cloud *CloudCtx) findNetworkInstance(dev *device.Ctx, manifest *Manifest, name string) (string, error) {
+	for _, el := range manifest.NetworkInstances {
+		if el.Name == name {
+			return ManifestID("networkInstance", name), nil
+		}
+	}
+	for _, id := range dev.GetNetworkInstances() {
+		if ni, err := cloud.GetNetworkInstanceConfig(id); err == nil && ni.GetDisplayname() == name {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("unknown network instance %s", name)
+}
+
+//portMapACE return ACE with id which maps port of EVE into app port defined as port:appPort[/udp]
+func portMapACE(port string, id int32) (*config.ACE, error) {
+	protocol := "tcp"
+	if ind := strings.LastIndex(port, "/"); ind >= 0 {
+		protocol = port[ind+1:]
+		port = port[:ind]
+	}
+	ports := strings.Split(port, ":")
+	if len(ports) != 2 || (protocol != "tcp" && protocol != "udp") {
+		return nil, fmt.Errorf("wrong port %q, expected port:appPort[/udp]", port)
+	}
+	lport, err := strconv.ParseUint(ports[0], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("wrong port %q: %s", ports[0], err)
+	}
+	appPort, err := strconv.ParseUint(ports[1], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("wrong app port %q: %s", ports[1], err)
+	}
+	return &config.ACE{
+		Matches: []*config.ACEMatch{{
+			Type:  "protocol",
+			Value: protocol,
+		}, {
+			Type:  "lport",
+			Value: strconv.FormatUint(lport, 10),
+		}},
+		Actions: []*config.ACEAction{{
+			Portmap: true,
+			AppPort: uint32(appPort),
+		}},
+		Id:  id,
+		Dir: config.ACEDirection_BOTH,
+	}, nil
+}
+
+func (cloud *CloudCtx) applyApp(dev *device.Ctx, manifest *Manifest, el *ManifestApp) error {
+	img, err := cloud.GetImage(ManifestID("image", el.Image))
+	if err != nil {
+		return fmt.Errorf("app %s: unknown image %s", el.Name, el.Image)
+	}
+	app := &config.AppInstanceConfig{
+		Uuidandversion: &config.UUIDandVersion{Uuid: ManifestID("app", el.Name), Version: "1"},
+		Displayname:    el.Name,
+		Fixedresources: &config.VmConfig{
+			Memory:             el.Memory,
+			Maxmem:             el.Memory,
+			Vcpus:              el.CPUs,
+			Rootdev:            "/dev/xvda1",
+			Bootloader:         "/usr/bin/pygrub",
+			VirtualizationMode: config.VmMode_HVM,
+		},
+		Drives: []*config.Drive{{
+			Image:   img,
+			Drvtype: config.DriveType_HDD,
+			Target:  config.Target_Disk,
+		}},
+		Activate: el.Activate == nil || *el.Activate,
+		UserData: base64.StdEncoding.EncodeToString([]byte(el.UserData)),
+	}
+	if el.VNCDisplay != nil {
+		app.Fixedresources.EnableVnc = true
+		app.Fixedresources.VncDisplay = *el.VNCDisplay
+	}
+	if len(el.Ports) > 0 && len(el.Networks) == 0 {
+		return fmt.Errorf("app %s: ports require network", el.Name)
+	}
+	for i, name := range el.Networks {
+		id, err := cloud.findNetworkInstance(dev, manifest, name)
+		if err != nil {
+			return fmt.Errorf("app %s: %s", el.Name, err)
+		}
+		var acls []*config.ACE
+		if i == 0 {
+			//ports are published from the first network
+			for _, port := range el.Ports {
+				acl, err := portMapACE(port, int32(len(acls)+1))
+				if err != nil {
+					return fmt.Errorf("app %s: %s", el.Name, err)
+				}
+				acls = append(acls, acl)
+			}
+		}
+		acls = append(acls, &config.ACE{
+			Matches: []*config.ACEMatch{{Type: "host"}},
+			Id:      int32(len(acls) + 1),
+		})
+		app.Interfaces = append(app.Interfaces, &config.NetworkAdapter{
+			Name:      fmt.Sprintf("eth%d", i),
+			NetworkId: id,
+			Acls:      acls,
+		})
+	}
+	id := app.Uuidandversion.Uuid
+	if old, err := cloud.GetApplicationInstanceConfig(id); err == nil {
+		nextVersion(old.Uuidandversion, app.Uuidandversion, func() bool { return proto.Equal(old, app) })
+		if !proto.Equal(old, app) {
+			if err = cloud.RemoveApplicationInstanceConfig(id); err != nil {
+				return err
+			}
+			if err = cloud.AddApplicationInstanceConfig(app); err != nil {
+				return err
+			}
+		}
+	} else if err = cloud.AddApplicationInstanceConfig(app); err != nil {
+		return err
+	}
+	if _, ok := utils.FindEleInSlice(dev.GetApplicationInstances(), id); !ok {
+		dev.SetApplicationInstanceConfig(append(dev.GetApplicationInstances(), id))
+	}
+	return nil
+}
+
+//ManifestApply creates or updates objects of manifest for dev, objects not in manifest are not touched.
+//Versions of changed objects are incremented.
+func (cloud *CloudCtx) ManifestApply(dev *device.Ctx, manifest *Manifest) error {
+	for _, el := range manifest.Datastores {
+		if err := cloud.applyDatastore(el); err != nil {
+			return err
+		}
+	}
+	for _, el := range manifest.Images {
+		if err := cloud.applyImage(el); err != nil {
+			return err
+		}
+	}
+	for _, el := range manifest.NetworkInstances {
+		if err := cloud.applyNetworkInstance(dev, el); err != nil {
+			return err
+		}
+	}
+	for _, el := range manifest.Apps {
+		if err := cloud.applyApp(dev, manifest, el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//usedImages return ids of images and datastores used by drives of apps and baseOS of dev
+func (cloud *CloudCtx) usedImages(dev *device.Ctx) map[string]bool {
+	used := make(map[string]bool)
+	var drives []*config.Drive
+	for _, id := range dev.GetApplicationInstances() {
+		if app, err := cloud.GetApplicationInstanceConfig(id); err == nil {
+			drives = append(drives, app.GetDrives()...)
+		}
+	}
+	for _, id := range dev.GetBaseOSConfigs() {
+		if baseOS, err := cloud.GetBaseOSConfig(id); err == nil {
+			drives = append(drives, baseOS.GetDrives()...)
+		}
+	}
+	for _, drive := range drives {
+		used[drive.GetImage().GetUuidandversion().GetUuid()] = true
+		used[drive.GetImage().GetDsId()] = true
+	}
+	return used
+}
+
+//ManifestDelete removes apps and network instances of manifest from dev
+//and images and datastores of manifest not used by remaining apps and baseOS
+func (cloud *CloudCtx) ManifestDelete(dev *device.Ctx, manifest *Manifest) error {
+	remove := func(ids []string, id string) []string {
+		if ind, ok := utils.FindEleInSlice(ids, id); ok {
+			utils.DelEleInSlice(&ids, ind)
+		}
+		return ids
+	}
+	for _, el := range manifest.Apps {
+		id := ManifestID("app", el.Name)
+		dev.SetApplicationInstanceConfig(remove(dev.GetApplicationInstances(), id))
+		_ = cloud.RemoveApplicationInstanceConfig(id)
+	}
+	for _, el := range manifest.NetworkInstances {
+		id := ManifestID("networkInstance", el.Name)
+		for _, appID := range dev.GetApplicationInstances() {
+			app, err := cloud.GetApplicationInstanceConfig(appID)
+			if err != nil {
+				continue
+			}
+			for _, iface := range app.GetInterfaces() {
+				if iface.GetNetworkId() == id {
+					return fmt.Errorf("network instance %s is used by app %s", el.Name, app.GetDisplayname())
+				}
+			}
+		}
+		dev.SetNetworkInstanceConfig(remove(dev.GetNetworkInstances(), id))
+		_ = cloud.RemoveNetworkInstanceConfig(id)
+	}
+	used := cloud.usedImages(dev)
+	for _, el := range manifest.Images {
+		if id := ManifestID("image", el.Name); !used[id] {
+			_ = cloud.RemoveImage(id)
+		}
+	}
+	for _, el := range manifest.Datastores {
+		if id := ManifestID("datastore", el.Name); !used[id] {
+			_ = cloud.RemoveDataStore(id)
+		}
+	}
+	return nil
+}
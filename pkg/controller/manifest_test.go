@@ -0,0 +1,135 @@
+package controller
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"testing"
+)
+
+const testManifest = `
+datastores:
+  - name: eserver
+    type: http
+    fqdn: http://eserver:8888
+images:
+  - name: alpine
+    datastore: eserver
+    path: docker/alpine.tar
+    format: container
+networkInstances:
+  - name: local1
+    type: local
+    subnet: 10.1.0.0/24
+apps:
+  - name: nginx
+    image: alpine
+    memory: 1048576
+    cpus: 1
+    networks: [local1]
+    ports: ["8027:80"]
+`
+
+//TestManifest test apply and delete of manifest
+func TestManifest(t *testing.T) {
+	if _, err := LoadManifest([]byte("apps:\n  - name: nginx\n    cpu: 1\n")); err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+	manifest, err := LoadManifest([]byte(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloud := &CloudCtx{}
+	dev, err := cloud.AddDevice(uuid.NewV5(uuid.NamespaceURL, "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cloud.ManifestApply(dev, manifest); err != nil {
+		t.Fatalf("ManifestApply: %s", err)
+	}
+	appID := ManifestID("app", "nginx")
+	if len(dev.GetApplicationInstances()) != 1 || dev.GetApplicationInstances()[0] != appID {
+		t.Fatalf("expected app %s on device, got %v", appID, dev.GetApplicationInstances())
+	}
+	app, err := cloud.GetApplicationInstanceConfig(appID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.GetInterfaces()[0].GetNetworkId() != ManifestID("networkInstance", "local1") {
+		t.Fatalf("wrong network instance of app: %s", app.GetInterfaces()[0].GetNetworkId())
+	}
+	if acls := app.GetInterfaces()[0].GetAcls(); len(acls) != 2 || acls[0].GetActions()[0].GetAppPort() != 80 || acls[0].GetMatches()[1].GetValue() != "8027" {
+		t.Fatalf("wrong acls for published port: %v", acls)
+	}
+	if _, err = portMapACE("8027", 1); err == nil {
+		t.Fatal("expected error for port without app port")
+	}
+	//the same manifest must not change version, changed one must bump it
+	if err = cloud.ManifestApply(dev, manifest); err != nil {
+		t.Fatalf("ManifestApply: %s", err)
+	}
+	if app, _ = cloud.GetApplicationInstanceConfig(appID); app.GetUuidandversion().GetVersion() != "1" {
+		t.Fatalf("expected version 1 for unchanged app, got %s", app.GetUuidandversion().GetVersion())
+	}
+	manifest.Apps[0].CPUs = 2
+	if err = cloud.ManifestApply(dev, manifest); err != nil {
+		t.Fatalf("ManifestApply: %s", err)
+	}
+	if app, _ = cloud.GetApplicationInstanceConfig(appID); app.GetUuidandversion().GetVersion() != "2" {
+		t.Fatalf("expected version 2 for changed app, got %s", app.GetUuidandversion().GetVersion())
+	}
+	if err = cloud.ManifestDelete(dev, manifest); err != nil {
+		t.Fatalf("ManifestDelete: %s", err)
+	}
+	if len(dev.GetApplicationInstances()) != 0 || len(dev.GetNetworkInstances()) != 0 {
+		t.Fatalf("expected no apps and network instances, got %v and %v", dev.GetApplicationInstances(), dev.GetNetworkInstances())
+	}
+	if _, err = cloud.GetDataStore(ManifestID("datastore", "eserver")); err == nil {
+		t.Fatal("expected datastore to be deleted")
+	}
+}
+
+//TestSubnetDefaults test gateway and dhcp range calculated from subnet
+func TestSubnetDefaults(t *testing.T) {
+	gateway, dhcpRange, err := SubnetDefaults("10.1.2.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gateway != "10.1.2.1" || dhcpRange.Start != "10.1.2.2" || dhcpRange.End != "10.1.2.254" {
+		t.Fatalf("wrong defaults for /24: %s %v", gateway, dhcpRange)
+	}
+	if gateway, dhcpRange, err = SubnetDefaults("192.168.0.4/30"); err != nil {
+		t.Fatal(err)
+	}
+	if gateway != "192.168.0.5" || dhcpRange.Start != "192.168.0.6" || dhcpRange.End != "192.168.0.6" {
+		t.Fatalf("wrong defaults for /30: %s %v", gateway, dhcpRange)
+	}
+	for _, subnet := range []string{"10.1.2.1/31", "fd00::/64", "wrong"} {
+		if _, _, err = SubnetDefaults(subnet); err == nil {
+			t.Fatalf("expected error for %s", subnet)
+		}
+	}
+}
+
+//TestManifestCloud test VPN config of cloud network instance taken from manifest
+func TestManifestCloud(t *testing.T) {
+	cloud := &CloudCtx{}
+	dev, err := cloud.AddDevice(uuid.NewV5(uuid.NamespaceURL, "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ni := &ManifestNetworkInstance{Name: "cloud1", Type: "cloud", Subnet: "30.1.0.0/24"}
+	if err = cloud.ManifestApply(dev, &Manifest{NetworkInstances: []*ManifestNetworkInstance{ni}}); err == nil {
+		t.Fatal("expected error for cloud network instance without vpn")
+	}
+	ni.VPN = &ManifestVPN{Gateway: "192.168.0.1", Subnet: "20.1.0.0/24", PreSharedKey: "secret"}
+	if err = cloud.ManifestApply(dev, &Manifest{NetworkInstances: []*ManifestNetworkInstance{ni}}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := cloud.GetNetworkInstanceConfig(ManifestID("networkInstance", "cloud1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"VpnRole":"onPremClient","VpnGatewayIpAddr":"192.168.0.1","VpnSubnetBlock":"20.1.0.0/24","ClientConfigList":[{"IpAddr":"%any","PreSharedKey":"secret","SubnetBlock":"30.1.0.0/24"}]}`
+	if cfg.GetCfg().GetOconfig() != expected {
+		t.Fatalf("unexpected vpn config: %s", cfg.GetCfg().GetOconfig())
+	}
+}
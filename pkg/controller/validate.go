@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/lf-edge/eve/api/go/config"
+	"net"
+	"strconv"
+)
+
+//configValidator collects problems found in EdgeDevConfig
+type configValidator struct {
+	problems []string
+	uuids    map[string]string
+}
+
+func (v *configValidator) addProblem(format string, a ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, a...))
+}
+
+//checkUUID checks that id is defined and not used by another object
+func (v *configValidator) checkUUID(kind, id string) {
+	if id == "" {
+		v.addProblem("%s without uuid", kind)
+		return
+	}
+	if other, ok := v.uuids[id]; ok {
+		v.addProblem("duplicate uuid %s in %s and %s", id, other, kind)
+		return
+	}
+	v.uuids[id] = kind
+}
+
+//checkVersion checks that version of object is numeric
+func (v *configValidator) checkVersion(kind string, uv *config.UUIDandVersion) {
+	if uv == nil {
+		v.addProblem("%s without uuidandversion", kind)
+		return
+	}
+	if _, err := strconv.Atoi(uv.GetVersion()); err != nil {
+		v.addProblem("%s %s has non-numeric version %q", kind, uv.GetUuid(), uv.GetVersion())
+	}
+}
+
+//checkIP checks that dhcp range of ip spec is inside of its subnet
+func (v *configValidator) checkIP(kind, id string, ip *config.Ipspec) {
+	if ip == nil || ip.GetSubnet() == "" {
+		return
+	}
+	_, subnet, err := net.ParseCIDR(ip.GetSubnet())
+	if err != nil {
+		v.addProblem("%s %s has wrong subnet %q", kind, id, ip.GetSubnet())
+		return
+	}
+	if ip.GetGateway() != "" {
+		if gw := net.ParseIP(ip.GetGateway()); gw == nil || !subnet.Contains(gw) {
+			v.addProblem("%s %s has gateway %s outside of subnet %s", kind, id, ip.GetGateway(), subnet)
+		}
+	}
+	dhcpRange := ip.GetDhcpRange()
+	if dhcpRange.GetStart() == "" && dhcpRange.GetEnd() == "" {
+		return
+	}
+	start, end := net.ParseIP(dhcpRange.GetStart()), net.ParseIP(dhcpRange.GetEnd())
+	for _, el := range []struct {
+		name string
+		ip   net.IP
+		str  string
+	}{{"start", start, dhcpRange.GetStart()}, {"end", end, dhcpRange.GetEnd()}} {
+		if el.ip == nil || !subnet.Contains(el.ip) {
+			v.addProblem("%s %s has dhcp range %s %q outside of subnet %s", kind, id, el.name, el.str, subnet)
+		}
+	}
+	if start != nil && end != nil && bytes.Compare(start.To16(), end.To16()) > 0 {
+		v.addProblem("%s %s has dhcp range start %s after end %s", kind, id, start, end)
+	}
+}
+
+//checkDrives checks that images of drives reference known datastores
+func (v *configValidator) checkDrives(kind, id string, drives []*config.Drive, datastores map[string]bool) {
+	for _, drive := range drives {
+		image := drive.GetImage()
+		if image == nil {
+			v.addProblem("%s %s has drive without image", kind, id)
+			continue
+		}
+		if !datastores[image.GetDsId()] {
+			v.addProblem("%s %s has image %s with unknown datastore %q", kind, id, image.GetName(), image.GetDsId())
+		}
+		if image.GetUuidandversion() != nil {
+			v.checkVersion("image", image.GetUuidandversion())
+		}
+	}
+}
+
+//ConfigValidate checks referential integrity of devConfig and return found problems.
+//Adapters of switch network instances are checked only if adaptersForSwitch is not empty.
+func ConfigValidate(devConfig *config.EdgeDevConfig, adaptersForSwitch []string) []string {
+	v := &configValidator{uuids: make(map[string]string)}
+	if _, err := strconv.Atoi(devConfig.GetId().GetVersion()); err != nil {
+		v.addProblem("device has non-numeric config version %q", devConfig.GetId().GetVersion())
+	}
+	datastores := make(map[string]bool)
+	for _, el := range devConfig.GetDatastores() {
+		v.checkUUID("datastore", el.GetId())
+		datastores[el.GetId()] = true
+	}
+	networks := make(map[string]bool)
+	for _, el := range devConfig.GetNetworks() {
+		v.checkUUID("network", el.GetId())
+		v.checkIP("network", el.GetId(), el.GetIp())
+		networks[el.GetId()] = true
+	}
+	for _, el := range devConfig.GetSystemAdapterList() {
+		if el.GetNetworkUUID() != "" && !networks[el.GetNetworkUUID()] {
+			v.addProblem("system adapter %s references unknown network %s", el.GetName(), el.GetNetworkUUID())
+		}
+	}
+	switchAdapters := make(map[string]bool)
+	for _, el := range adaptersForSwitch {
+		switchAdapters[el] = true
+	}
+	networkInstances := make(map[string]bool)
+	for _, el := range devConfig.GetNetworkInstances() {
+		v.checkVersion("network instance", el.GetUuidandversion())
+		id := el.GetUuidandversion().GetUuid()
+		v.checkUUID("network instance", id)
+		v.checkIP("network instance", id, el.GetIp())
+		if el.GetInstType() == config.ZNetworkInstType_ZnetInstSwitch && len(adaptersForSwitch) > 0 {
+			if !switchAdapters[el.GetPort().GetName()] {
+				v.addProblem("switch network instance %s uses adapter %q not in adapters for switch %v", id, el.GetPort().GetName(), adaptersForSwitch)
+			}
+		}
+		networkInstances[id] = true
+	}
+	contentTrees := make(map[string]bool)
+	for _, el := range devConfig.GetContentInfo() {
+		v.checkUUID("content tree", el.GetUuid())
+		if !datastores[el.GetDsId()] {
+			v.addProblem("content tree %s references unknown datastore %q", el.GetUuid(), el.GetDsId())
+		}
+		contentTrees[el.GetUuid()] = true
+	}
+	for _, el := range devConfig.GetVolumes() {
+		v.checkUUID("volume", el.GetUuid())
+		if id := el.GetOrigin().GetDownloadContentTreeID(); id != "" && !contentTrees[id] {
+			v.addProblem("volume %s references unknown content tree %s", el.GetUuid(), id)
+		}
+	}
+	for _, el := range devConfig.GetBase() {
+		v.checkVersion("baseOS", el.GetUuidandversion())
+		id := el.GetUuidandversion().GetUuid()
+		v.checkUUID("baseOS", id)
+		v.checkDrives("baseOS", id, el.GetDrives(), datastores)
+	}
+	for _, el := range devConfig.GetApps() {
+		v.checkVersion("app", el.GetUuidandversion())
+		id := el.GetUuidandversion().GetUuid()
+		v.checkUUID("app", id)
+		v.checkDrives("app", id, el.GetDrives(), datastores)
+		for _, iface := range el.GetInterfaces() {
+			if !networkInstances[iface.GetNetworkId()] {
+				v.addProblem("app %s has interface %s with unknown network instance %q", id, iface.GetName(), iface.GetNetworkId())
+			}
+		}
+	}
+	return v.problems
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"github.com/lf-edge/eve/api/go/config"
+	"testing"
+)
+
+//TestConfigValidate test detection of broken references in config
+func TestConfigValidate(t *testing.T) {
+	deviceConfig := &config.EdgeDevConfig{
+		Id:         &config.UUIDandVersion{Uuid: "1b4a8cf4-6a2c-4e3b-9a3c-2f3a4e5b6c7d", Version: "4"},
+		Datastores: []*config.DatastoreConfig{{Id: "ds1", Fqdn: "docker://docker.io"}},
+		NetworkInstances: []*config.NetworkInstanceConfig{{
+			Uuidandversion: &config.UUIDandVersion{Uuid: "ni1", Version: "1"},
+			InstType:       config.ZNetworkInstType_ZnetInstLocal,
+			Ip: &config.Ipspec{
+				Subnet:    "10.1.0.0/24",
+				Gateway:   "10.1.0.1",
+				DhcpRange: &config.IpRange{Start: "10.1.0.2", End: "10.1.0.254"},
+			},
+		}, {
+			Uuidandversion: &config.UUIDandVersion{Uuid: "ni2", Version: "1"},
+			InstType:       config.ZNetworkInstType_ZnetInstSwitch,
+			Port:           &config.Adapter{Name: "eth1"},
+		}},
+		Apps: []*config.AppInstanceConfig{{
+			Uuidandversion: &config.UUIDandVersion{Uuid: "app1", Version: "1"},
+			Drives:         []*config.Drive{{Image: &config.Image{Name: "nginx", DsId: "ds1"}}},
+			Interfaces:     []*config.NetworkAdapter{{Name: "eth0", NetworkId: "ni1"}},
+		}},
+	}
+	if problems := ConfigValidate(deviceConfig, []string{"eth1"}); len(problems) != 0 {
+		t.Fatalf("expected no problems, got %v", problems)
+	}
+	deviceConfig.NetworkInstances[0].Ip.DhcpRange.End = "10.2.0.254"
+	deviceConfig.Apps[0].Uuidandversion.Version = "latest"
+	deviceConfig.Apps[0].Drives[0].Image.DsId = "ds2"
+	deviceConfig.Apps[0].Interfaces[0].NetworkId = "ni3"
+	deviceConfig.Base = []*config.BaseOSConfig{{Uuidandversion: &config.UUIDandVersion{Uuid: "app1", Version: "1"}}}
+	expected := []string{
+		`network instance ni1 has dhcp range end "10.2.0.254" outside of subnet 10.1.0.0/24`,
+		`switch network instance ni2 uses adapter "eth1" not in adapters for switch [eth0]`,
+		`app app1 has non-numeric version "latest"`,
+		"duplicate uuid app1 in baseOS and app",
+		`app app1 has image nginx with unknown datastore "ds2"`,
+		`app app1 has interface eth0 with unknown network instance "ni3"`,
+	}
+	problems := ConfigValidate(deviceConfig, []string{"eth0"})
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, problem := range problems {
+		if problem != expected[i] {
+			t.Errorf("problem %d: expected %q, got %q", i, expected[i], problem)
+		}
+	}
+}
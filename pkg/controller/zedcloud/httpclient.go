@@ -0,0 +1,90 @@
+package zedcloud
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/lf-edge/eden/pkg/utils"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// http client with correct config
+func (ctx *Ctx) getHTTPClient() *http.Client {
+	tlsConfig := &tls.Config{}
+	if ctx.serverCA != "" {
+		caCert, err := ioutil.ReadFile(ctx.serverCA)
+		if err != nil {
+			log.Fatalf("unable to read server CA file at %s: %v", ctx.serverCA, err)
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		tlsConfig.RootCAs = caCertPool
+	}
+	if ctx.insecureTLS {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	return &http.Client{
+		Timeout: time.Second * 10,
+		Transport: &http.Transport{
+			TLSClientConfig:       tlsConfig,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+//do sends request and returns body of response if status is 2xx
+func (ctx *Ctx) do(method string, path string, obj []byte) ([]byte, error) {
+	u, err := utils.ResolveURL(ctx.url, path)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing URL: %s", err)
+	}
+	req, err := http.NewRequest(method, u, bytes.NewBuffer(obj))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new http request: %s", err)
+	}
+	if obj != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := ctx.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send request: %s", err)
+	}
+	defer resp.Body.Close()
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read data from URL %s: %s", u, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s: %s %s", method, u, resp.Status, strings.TrimSpace(string(buf)))
+	}
+	return buf, nil
+}
+
+func (ctx *Ctx) getObj(path string) (out string, err error) {
+	buf, err := ctx.do("GET", path, nil)
+	return string(buf), err
+}
+
+func (ctx *Ctx) getList(path string) (out []string, err error) {
+	buf, err := ctx.do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(buf)), nil
+}
+
+func (ctx *Ctx) postObj(path string, obj []byte) (err error) {
+	_, err = ctx.do("POST", path, obj)
+	return err
+}
+
+func (ctx *Ctx) putObj(path string, obj []byte) (err error) {
+	_, err = ctx.do("PUT", path, obj)
+	return err
+}
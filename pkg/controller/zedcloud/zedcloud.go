@@ -0,0 +1,194 @@
+//Package zedcloud provides implementation of controller for zedcloud-compatible controllers,
+//which serve v2 device API with auth containers and admin API under /api/v2/admin.
+package zedcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lf-edge/adam/pkg/server"
+	"github.com/lf-edge/eden/pkg/controller/cachers"
+	"github.com/lf-edge/eden/pkg/controller/eflowlog"
+	"github.com/lf-edge/eden/pkg/controller/einfo"
+	"github.com/lf-edge/eden/pkg/controller/elog"
+	"github.com/lf-edge/eden/pkg/controller/emetric"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eden/pkg/zedapi"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"path"
+	"time"
+)
+
+//Ctx is controller for zedcloud-compatible controllers
+type Ctx struct {
+	dir         string
+	url         string
+	serverCA    string
+	insecureTLS bool
+}
+
+//getLoader return remote loader with v2 admin urls
+func (ctx *Ctx) getLoader() loaders.Loader {
+	return loaders.RemoteLoader(ctx.getHTTPClient, ctx.getLogsURL, ctx.getInfoURL, ctx.getMetricsURL, ctx.getFlowLogURL)
+}
+
+//InitWithVars use variables from viper for init controller
+func (ctx *Ctx) InitWithVars(vars *utils.ConfigVars) error {
+	if vars.ZedcloudURL == "" {
+		return fmt.Errorf("empty url of zedcloud controller")
+	}
+	ctx.dir = vars.ZedcloudDir
+	ctx.url = vars.ZedcloudURL
+	ctx.serverCA = vars.ZedcloudCA
+	ctx.insecureTLS = len(vars.ZedcloudCA) == 0
+	return nil
+}
+
+//GetDir return dir
+func (ctx *Ctx) GetDir() (dir string) {
+	return ctx.dir
+}
+
+func (ctx *Ctx) getDeviceURL(devUUID uuid.UUID, kind string) string {
+	resURL, err := utils.ResolveURL(ctx.url, path.Join(zedapi.AdminPrefix, "device", devUUID.String(), kind))
+	if err != nil {
+		log.Fatalf("ResolveURL: %s", err)
+	}
+	return resURL
+}
+
+//getLogsURL return logs url for devUUID
+func (ctx *Ctx) getLogsURL(devUUID uuid.UUID) string {
+	return ctx.getDeviceURL(devUUID, "logs")
+}
+
+//getInfoURL return info url for devUUID
+func (ctx *Ctx) getInfoURL(devUUID uuid.UUID) string {
+	return ctx.getDeviceURL(devUUID, "info")
+}
+
+//getMetricsURL return metrics url for devUUID
+func (ctx *Ctx) getMetricsURL(devUUID uuid.UUID) string {
+	return ctx.getDeviceURL(devUUID, "metrics")
+}
+
+//getFlowLogURL return flow log url for devUUID
+func (ctx *Ctx) getFlowLogURL(devUUID uuid.UUID) string {
+	return ctx.getDeviceURL(devUUID, "flowlog")
+}
+
+//Register onboarding cert with serials in controller
+func (ctx *Ctx) Register(eveCert string, eveSerial string) error {
+	b, err := ioutil.ReadFile(eveCert)
+	if err != nil {
+		return fmt.Errorf("error reading cert file %s: %s", eveCert, err)
+	}
+	body, err := json.Marshal(server.OnboardCert{
+		Cert:   b,
+		Serial: eveSerial,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding json: %s", err)
+	}
+	return ctx.postObj(path.Join(zedapi.AdminPrefix, "onboard"), body)
+}
+
+//OnBoardList return onboard list
+func (ctx *Ctx) OnBoardList() (out []string, err error) {
+	return ctx.getList(path.Join(zedapi.AdminPrefix, "onboard"))
+}
+
+//DeviceList return device list
+func (ctx *Ctx) DeviceList() (out []string, err error) {
+	return ctx.getList(path.Join(zedapi.AdminPrefix, "device"))
+}
+
+//DeviceGetSerial return serial used by device for onboarding
+func (ctx *Ctx) DeviceGetSerial(devUUID uuid.UUID) (serial string, err error) {
+	out, err := ctx.getObj(path.Join(zedapi.AdminPrefix, "device", devUUID.String()))
+	if err != nil {
+		return "", err
+	}
+	var deviceCert server.DeviceCert
+	if err = json.Unmarshal([]byte(out), &deviceCert); err != nil {
+		return "", fmt.Errorf("cannot parse device %s: %s", devUUID, err)
+	}
+	return deviceCert.Serial, nil
+}
+
+//ConfigSet set config for devID
+func (ctx *Ctx) ConfigSet(devUUID uuid.UUID, devConfig []byte) (err error) {
+	return ctx.putObj(path.Join(zedapi.AdminPrefix, "device", devUUID.String(), "config"), devConfig)
+}
+
+//ConfigGet get config for devID
+func (ctx *Ctx) ConfigGet(devUUID uuid.UUID) (out string, err error) {
+	return ctx.getObj(path.Join(zedapi.AdminPrefix, "device", devUUID.String(), "config"))
+}
+
+//LogChecker check logs by pattern from existence files with LogLast and use LogWatchWithTimeout with timeout for observe new files
+func (ctx *Ctx) LogChecker(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc, mode elog.LogCheckerMode, timeout time.Duration) (err error) {
+	return elog.LogChecker(ctx.getLoader(), devUUID, q, handler, mode, timeout)
+}
+
+//LogLastCallback check logs by pattern from existence files with callback
+func (ctx *Ctx) LogLastCallback(devUUID uuid.UUID, q map[string]string, handler elog.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return elog.LogLast(loader, q, handler)
+}
+
+//LogBundleLastCallback process log bundles from existing objects with range from query with callback
+func (ctx *Ctx) LogBundleLastCallback(devUUID uuid.UUID, q map[string]string, handler elog.BundleHandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return elog.LogBundleLast(loader, q, handler)
+}
+
+//CacheStats is not supported by zedcloud controller
+func (ctx *Ctx) CacheStats(devUUID uuid.UUID) (stats []cachers.Stats, err error) {
+	return nil, fmt.Errorf("caching is not supported by zedcloud controller")
+}
+
+//CachePrune is not supported by zedcloud controller
+func (ctx *Ctx) CachePrune(devUUID uuid.UUID, r cachers.Retention) (removed []cachers.Stats, err error) {
+	return nil, fmt.Errorf("caching is not supported by zedcloud controller")
+}
+
+//InfoChecker checks the information in the regular expression pattern 'query' and processes the info.ZInfoMsg found by the function 'handler' from existing files (mode=einfo.InfoExist), new files (mode=einfo.InfoNew) or any of them (mode=einfo.InfoAny) with timeout.
+func (ctx *Ctx) InfoChecker(devUUID uuid.UUID, q map[string]string, infoType einfo.ZInfoType, handler einfo.HandlerFunc, mode einfo.InfoCheckerMode, timeout time.Duration) (err error) {
+	return einfo.InfoChecker(ctx.getLoader(), devUUID, q, infoType, handler, mode, timeout)
+}
+
+//InfoLastCallback check info by pattern from existence files with callback
+func (ctx *Ctx) InfoLastCallback(devUUID uuid.UUID, q map[string]string, infoType einfo.ZInfoType, handler einfo.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return einfo.InfoLast(loader, q, einfo.ZInfoFind, handler, infoType)
+}
+
+//MetricChecker checks the metrics in the regular expression pattern 'query' and processes the metrics.ZMetricMsg found by the function 'handler' from existing files (mode=emetric.MetricExist), new files (mode=emetric.MetricNew) or any of them (mode=emetric.MetricAny) with timeout.
+func (ctx *Ctx) MetricChecker(devUUID uuid.UUID, q map[string]string, metricType emetric.ZMetricType, handler emetric.HandlerFunc, mode emetric.MetricCheckerMode, timeout time.Duration) (err error) {
+	return emetric.MetricChecker(ctx.getLoader(), devUUID, q, metricType, handler, mode, timeout)
+}
+
+//MetricLastCallback check metrics by pattern from existence files with callback
+func (ctx *Ctx) MetricLastCallback(devUUID uuid.UUID, q map[string]string, metricType emetric.ZMetricType, handler emetric.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return emetric.MetricLast(loader, q, emetric.ZMetricFind, handler, metricType)
+}
+
+//FlowLogChecker checks the flow logs in the regular expression pattern 'query' and processes the flowlog.FlowMessage found by the function 'handler' from existing files (mode=eflowlog.FlowLogExist), new files (mode=eflowlog.FlowLogNew) or any of them (mode=eflowlog.FlowLogAny) with timeout.
+func (ctx *Ctx) FlowLogChecker(devUUID uuid.UUID, q map[string]string, flowLogType eflowlog.FlowLogType, handler eflowlog.HandlerFunc, mode eflowlog.FlowLogCheckerMode, timeout time.Duration) (err error) {
+	return eflowlog.FlowLogChecker(ctx.getLoader(), devUUID, q, flowLogType, handler, mode, timeout)
+}
+
+//FlowLogLastCallback check flow logs by pattern from existence files with callback
+func (ctx *Ctx) FlowLogLastCallback(devUUID uuid.UUID, q map[string]string, flowLogType eflowlog.FlowLogType, handler eflowlog.HandlerFunc) (err error) {
+	var loader = ctx.getLoader()
+	loader.SetUUID(devUUID)
+	return eflowlog.FlowLogLast(loader, q, eflowlog.FlowLogFind, handler, flowLogType)
+}
@@ -15,6 +15,10 @@ const (
 	DefaultEVEDist          = "eve"              //directory for build EVE inside dist
 	DefaultCertsDist        = "certs"            //directory for certs inside dist
 	DefaultBinDist          = "bin"              //directory for binaries inside dist
+	DefaultSimDist          = "sim"              //directory for certs of simulated devices inside dist
+	DefaultZedcloudDist     = "zedcloud"         //directory for storage of zedcloud stand-in inside dist
+	DefaultEVEInstancesDist = "eves"             //directory for named EVE instances inside dist
+	DefaultModelsDir        = "models"           //directory with yaml files of device models
 	DefaultEdenHomeDir      = ".eden"            //directory inside HOME directory for configs
 	DefaultCurrentDirConfig = "config.yml"       //file for search config in current directory
 	DefaultContextFile      = "context.yml"      //file for saving current context inside DefaultEdenHomeDir
@@ -27,16 +31,27 @@ const (
 	DefaultContext = "default" //default context name
 
 	//domains, ips, ports
-	DefaultDomain      = "mydomain.adam"
-	DefaultIP          = "192.168.0.1"
-	DefaultEVEIP       = "192.168.1.2"
-	DefaultEserverPort = 8888
-	DefaultTelnetPort  = 7777
-	DefaultSSHPort     = 2222
-	DefaultEVEHost     = "127.0.0.1"
-	DefaultRedisHost   = "localhost"
-	DefaultRedisPort   = 6379
-	DefaultAdamPort    = 3333
+	DefaultDomain       = "mydomain.adam"
+	DefaultIP           = "192.168.0.1"
+	DefaultEVEIP        = "192.168.1.2"
+	DefaultEserverPort  = 8888
+	DefaultTelnetPort   = 7777
+	DefaultSSHPort      = 2222
+	DefaultEVEHost      = "127.0.0.1"
+	DefaultRedisHost    = "localhost"
+	DefaultRedisPort    = 6379
+	DefaultAdamPort     = 3333
+	DefaultZedcloudPort = 3335
+
+	DefaultEVEInstancePortStep = 100       //shift of forwarded ports between named EVE instances
+	DefaultEVEName             = "default" //name of EVE from config in list of EVE instances
+
+	DefaultAdamCertRefresh = 60 //how often, in seconds, embedded adam refresh certs from the filesystem
+
+	//simulated devices
+	DefaultSimCount        = 1     //number of simulated devices
+	DefaultSimSerialPrefix = "sim" //prefix for serials of simulated devices
+	DefaultSimInterval     = 10    //interval in seconds to poll config by simulated devices
 
 	//tags, versions, repos
 	DefaultEVETag            = "5ee6043906449f7fa3447c96fd38dc9a536c5693"        //DefaultEVETag tag for EVE image
@@ -73,7 +88,7 @@ const (
 	DefaultTestProg              = "eden.integration.test"
 	DefaultTestScript            = "eden.integration.tests.txt"
 	DefaultRootFSVersionPattern  = `^(\d+\.*){2,3}.*-(xen|kvm|acrn)-(amd64|arm64)$`
-	DefaultControllerModePattern = `^(?P<Type>(file|proto|adam|zedcloud)):\/\/(?P<URL>.*)$`
+	DefaultControllerModePattern = `^(?P<Type>(file|proto|adam|zedcloud|fake)):\/\/(?P<URL>.*)$`
 	DefaultRedisContainerName    = "eden_redis"
 	DefaultAdamContainerName     = "eden_adam"
 	DefaultDockerNetworkName     = "eden_network"
@@ -82,6 +97,9 @@ const (
 	DefaultX509Company           = "Itmo"
 	DefaultLogsRedisPrefix       = "LOGS_EVE_"
 	DefaultInfoRedisPrefix       = "INFO_EVE_"
+	DefaultMetricsRedisPrefix    = "METRICS_EVE_"
+	DefaultFlowLogRedisPrefix    = "FLOW_MESSAGE_EVE_"
+	DefaultDeviceEnv             = "EDEN_DEVICE" //selector of device passed into tests
 )
 
 var (
@@ -102,6 +120,13 @@ var (
 		"adam.v1":           "api-v1",
 		"adam.redis.adam":   "adam-redis-url",
 		"adam.remote.redis": "adam-redis",
+		"adam.embedded":     "adam-embedded",
+		"adam.pid":          "adam-pid",
+		"adam.log":          "adam-log",
+
+		"adam.caching.max-age":     "max-age",
+		"adam.caching.max-entries": "max-entries",
+		"adam.caching.max-size":    "max-size",
 
 		"eve.arch":         "eve-arch",
 		"eve.os":           "eve-os",
@@ -123,6 +148,7 @@ var (
 		"eve.dtb-part":     "dtb-part",
 		"eve.config-part":  "config-part",
 		"eve.base-version": "os-version",
+		"eve.instances":    "eve-instances",
 
 		"eden.images.dist":   "image-dist",
 		"eden.images.docker": "docker-yml",
@@ -133,6 +159,17 @@ var (
 		"eden.eserver.pid":   "eserver-pid",
 		"eden.eserver.log":   "eserver-log",
 		"eden.certs-dist":    "certs-dist",
+		"sim.dist":           "sim-dist",
+		"sim.count":          "sim-count",
+		"sim.serial-prefix":  "sim-serial-prefix",
+		"sim.interval":       "sim-interval",
+		"sim.pid":            "sim-pid",
+		"sim.log":            "sim-log",
+		"zedcloud.dist":      "zedcloud-dist",
+		"zedcloud.port":      "zedcloud-port",
+		"zedcloud.url":       "zedcloud-url",
+		"zedcloud.pid":       "zedcloud-pid",
+		"zedcloud.log":       "zedcloud-log",
 		"eden.bin-dist":      "bin-dist",
 		"eden.ssh-key":       "ssh-key",
 		"eden.test-bin":      "eden.integration.test",
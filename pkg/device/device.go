@@ -7,6 +7,8 @@ import (
 //Ctx is base struct for device
 type Ctx struct {
 	id                         uuid.UUID
+	serial                     string
+	name                       string
 	configVersion              int
 	baseOSConfigs              []string
 	networkInstances           []string
@@ -36,6 +38,18 @@ func CreateWithBaseConfig(id uuid.UUID) *Ctx {
 //GetID return id of device
 func (cfg *Ctx) GetID() uuid.UUID { return cfg.id }
 
+//GetSerial return serial of device used for onboarding
+func (cfg *Ctx) GetSerial() string { return cfg.serial }
+
+//SetSerial set serial of device
+func (cfg *Ctx) SetSerial(serial string) { cfg.serial = serial }
+
+//GetName return name of device
+func (cfg *Ctx) GetName() string { return cfg.name }
+
+//SetName set name of device
+func (cfg *Ctx) SetName(name string) { cfg.name = name }
+
 //GetConfigVersion return configVersion of device
 func (cfg *Ctx) GetConfigVersion() int { return cfg.configVersion }
 
@@ -0,0 +1,392 @@
+//Package sim provides simulated EVE devices, which onboard into controller
+//with the same API as EVE, poll config and report synthetic info, logs and metrics.
+package sim
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/utils"
+	"github.com/lf-edge/eden/pkg/zedapi"
+	"github.com/lf-edge/eve/api/go/auth"
+	"github.com/lf-edge/eve/api/go/certs"
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/lf-edge/eve/api/go/logs"
+	"github.com/lf-edge/eve/api/go/register"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const mimeProto = "application/x-proto-binary"
+
+//Device is simulated EVE
+type Device struct {
+	Serial      string        //serial of device, must be registered in controller with onboarding cert
+	URL         string        //url of controller, for example https://127.0.0.1:3333
+	CA          string        //root certificate of controller, skip verification if empty
+	OnboardCert string        //onboarding certificate from eden certs
+	OnboardKey  string        //key of onboarding certificate
+	Dir         string        //directory to save device certificate and key
+	Interval    time.Duration //interval to poll config and send metrics
+	APIv2       bool          //use v2 API with auth containers of zedcloud-compatible controllers
+
+	deviceCert     *tls.Certificate
+	controllerCert *x509.Certificate //cert to verify auth containers from controller in v2 API
+	configHash     string
+	state          *state
+	msgID          uint64
+}
+
+//apiPath returns path of device API endpoint
+func (dev *Device) apiPath(name string) string {
+	if dev.APIv2 {
+		return path.Join(zedapi.DevicePrefix, name)
+	}
+	return path.Join("/api/v1/edgedevice", name)
+}
+
+func (dev *Device) getHTTPClient(cert *tls.Certificate) (*http.Client, error) {
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{*cert}}
+	if dev.CA != "" {
+		caCert, err := ioutil.ReadFile(dev.CA)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file at %s: %s", dev.CA, err)
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		tlsConfig.RootCAs = caCertPool
+	} else {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	return &http.Client{
+		Timeout: time.Second * 10,
+		Transport: &http.Transport{
+			TLSClientConfig:       tlsConfig,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+		},
+	}, nil
+}
+
+func (dev *Device) request(cert *tls.Certificate, method string, path string, msg proto.Message) (status int, body []byte, err error) {
+	client, err := dev.getHTTPClient(cert)
+	if err != nil {
+		return 0, nil, err
+	}
+	u, err := utils.ResolveURL(dev.URL, path)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error constructing URL: %s", err)
+	}
+	var data []byte
+	if msg != nil {
+		if data, err = proto.Marshal(msg); err != nil {
+			return 0, nil, fmt.Errorf("cannot marshal %T: %s", msg, err)
+		}
+		if dev.APIv2 {
+			ac, err := zedapi.Seal(data, cert)
+			if err != nil {
+				return 0, nil, err
+			}
+			if data, err = proto.Marshal(ac); err != nil {
+				return 0, nil, fmt.Errorf("cannot marshal auth container: %s", err)
+			}
+		}
+	}
+	req, err := http.NewRequest(method, u, bytes.NewBuffer(data))
+	if err != nil {
+		return 0, nil, fmt.Errorf("unable to create new http request: %s", err)
+	}
+	req.Header.Set("Content-Type", mimeProto)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("unable to send request: %s", err)
+	}
+	defer resp.Body.Close()
+	body, err = ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, body, err
+}
+
+func (dev *Device) post(path string, msg proto.Message) error {
+	status, body, err := dev.request(dev.deviceCert, "POST", path, msg)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusCreated && status != http.StatusOK {
+		return fmt.Errorf("POST %s: %d %s", path, status, body)
+	}
+	return nil
+}
+
+//genDeviceCert generates self-signed device certificate as EVE do on first boot
+func genDeviceCert(serial string) (certPEM []byte, keyPEM []byte, err error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-10 * time.Second),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		Subject: pkix.Name{
+			Country:      []string{defaults.DefaultX509Country},
+			Organization: []string{defaults.DefaultX509Company},
+			CommonName:   serial,
+		},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), nil
+}
+
+//loadOrCreateDeviceCert returns true if device cert was created
+func (dev *Device) loadOrCreateDeviceCert() (created bool, err error) {
+	dir := filepath.Join(dev.Dir, dev.Serial)
+	certFile := filepath.Join(dir, "device.cert.pem")
+	keyFile := filepath.Join(dir, "device.key.pem")
+	if _, err = os.Stat(certFile); os.IsNotExist(err) {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return false, err
+		}
+		certPEM, keyPEM, err := genDeviceCert(dev.Serial)
+		if err != nil {
+			return false, fmt.Errorf("cannot generate device cert: %s", err)
+		}
+		if err = ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+			return false, err
+		}
+		if err = ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+			return false, err
+		}
+		created = true
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return false, fmt.Errorf("cannot load device cert: %s", err)
+	}
+	dev.deviceCert = &cert
+	return created, nil
+}
+
+func (dev *Device) register() error {
+	onboardCert, err := tls.LoadX509KeyPair(dev.OnboardCert, dev.OnboardKey)
+	if err != nil {
+		return fmt.Errorf("cannot load onboarding cert: %s", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: dev.deviceCert.Certificate[0]})
+	msg := &register.ZRegisterMsg{
+		PemCert: []byte(base64.StdEncoding.EncodeToString(certPEM)),
+		Serial:  dev.Serial,
+	}
+	status, body, err := dev.request(&onboardCert, "POST", dev.apiPath("register"), msg)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusCreated:
+		return nil
+	case http.StatusConflict:
+		return fmt.Errorf("serial %s already used for onboarding, remove device from controller or %s", dev.Serial, filepath.Join(dev.Dir, dev.Serial))
+	default:
+		return fmt.Errorf("register: %d %s", status, body)
+	}
+}
+
+//onboard registers device in controller if it is not registered yet
+func (dev *Device) onboard() error {
+	created, err := dev.loadOrCreateDeviceCert()
+	if err != nil {
+		return err
+	}
+	if !created {
+		status, _, err := dev.request(dev.deviceCert, "GET", dev.apiPath("ping"), nil)
+		if err != nil {
+			return err
+		}
+		if status == http.StatusOK {
+			return nil
+		}
+	}
+	log.Infof("sim %s: register", dev.Serial)
+	return dev.register()
+}
+
+//getControllerCert obtains signing cert of controller in v2 API
+func (dev *Device) getControllerCert() error {
+	status, body, err := dev.request(dev.deviceCert, "GET", dev.apiPath("certs"), nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("GET certs: %d %s", status, body)
+	}
+	var controllerCerts certs.ZControllerCert
+	if err = proto.Unmarshal(body, &controllerCerts); err != nil {
+		return fmt.Errorf("cannot unmarshal controller certs: %s", err)
+	}
+	for _, el := range controllerCerts.Certs {
+		if el.Type != certs.ZCertType_CERT_TYPE_CONTROLLER_SIGNING {
+			continue
+		}
+		block, _ := pem.Decode(el.Cert)
+		if block == nil {
+			return fmt.Errorf("no PEM data in controller cert")
+		}
+		dev.controllerCert, err = x509.ParseCertificate(block.Bytes)
+		return err
+	}
+	return fmt.Errorf("no signing cert of controller")
+}
+
+//getConfigV2 returns nil if config not modified since last request
+func (dev *Device) getConfigV2() (*config.EdgeDevConfig, error) {
+	if dev.controllerCert == nil {
+		if err := dev.getControllerCert(); err != nil {
+			return nil, err
+		}
+	}
+	status, body, err := dev.request(dev.deviceCert, "POST", dev.apiPath("config"), &config.ConfigRequest{ConfigHash: dev.configHash})
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case http.StatusNotModified:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("POST config: %d %s", status, body)
+	}
+	var ac auth.AuthContainer
+	if err = proto.Unmarshal(body, &ac); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal auth container: %s", err)
+	}
+	payload, err := zedapi.Open(&ac, dev.controllerCert)
+	if err != nil {
+		return nil, err
+	}
+	var resp config.ConfigResponse
+	if err = proto.Unmarshal(payload, &resp); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal config response: %s", err)
+	}
+	dev.configHash = resp.ConfigHash
+	return resp.Config, nil
+}
+
+func (dev *Device) getConfig() (*config.EdgeDevConfig, error) {
+	if dev.APIv2 {
+		return dev.getConfigV2()
+	}
+	status, body, err := dev.request(dev.deviceCert, "GET", dev.apiPath("config"), nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("GET config: %d %s", status, body)
+	}
+	var devConfig config.EdgeDevConfig
+	if err = proto.Unmarshal(body, &devConfig); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal config: %s", err)
+	}
+	return &devConfig, nil
+}
+
+//Step onboards device if needed, polls config and sends info, logs and metrics
+func (dev *Device) Step() error {
+	if dev.state == nil {
+		if err := dev.onboard(); err != nil {
+			return err
+		}
+		dev.state = newState(dev.Serial)
+	}
+	devConfig, err := dev.getConfig()
+	if err != nil {
+		return err
+	}
+	if devConfig == nil {
+		return dev.post(dev.apiPath("metrics"), dev.state.metrics())
+	}
+	if dev.state.devID == "" {
+		if devConfig.Id == nil {
+			return fmt.Errorf("no device id in config")
+		}
+		dev.state.devID = devConfig.Id.Uuid
+		log.Infof("sim %s: onboarded with uuid %s", dev.Serial, dev.state.devID)
+	}
+	infos, entries := dev.state.applyConfig(devConfig)
+	for _, im := range infos {
+		if err = dev.post(dev.apiPath("info"), im); err != nil {
+			return err
+		}
+	}
+	if len(entries) > 0 {
+		for _, el := range entries {
+			dev.msgID++
+			el.Msgid = dev.msgID
+		}
+		lb := &logs.LogBundle{
+			DevID:      dev.state.devID,
+			Image:      "IMGA",
+			Log:        entries,
+			Timestamp:  ptypes.TimestampNow(),
+			EveVersion: dev.state.baseOS,
+		}
+		if err = dev.post(dev.apiPath("logs"), lb); err != nil {
+			return err
+		}
+	}
+	return dev.post(dev.apiPath("metrics"), dev.state.metrics())
+}
+
+//Run calls Step every Interval until stop is closed
+func (dev *Device) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(dev.Interval)
+	defer ticker.Stop()
+	for {
+		if err := dev.Step(); err != nil {
+			log.Errorf("sim %s: %s", dev.Serial, err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//RunDevices runs devices in parallel until stop is closed
+func RunDevices(devices []*Device, stop <-chan struct{}) {
+	var wg sync.WaitGroup
+	for _, dev := range devices {
+		wg.Add(1)
+		go func(dev *Device) {
+			defer wg.Done()
+			dev.Run(stop)
+		}(dev)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,250 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/lf-edge/eve/api/go/info"
+	"github.com/lf-edge/eve/api/go/logs"
+	"github.com/lf-edge/eve/api/go/metrics"
+	"sort"
+	"time"
+)
+
+type appState struct {
+	name    string
+	version string
+	state   info.ZSwState
+}
+
+type niState struct {
+	name      string
+	version   string
+	instType  config.ZNetworkInstType
+	activated bool
+}
+
+//state keeps objects reported by simulated EVE to compare them with received config
+type state struct {
+	devID            string
+	serial           string
+	bootTime         time.Time
+	configVersion    string
+	baseOS           string
+	apps             map[string]*appState
+	networkInstances map[string]*niState
+}
+
+func newState(serial string) *state {
+	return &state{
+		serial:           serial,
+		bootTime:         time.Now(),
+		apps:             map[string]*appState{},
+		networkInstances: map[string]*niState{},
+	}
+}
+
+//newLogEntry returns LogEntry with json content as EVE agents produce
+func newLogEntry(source string, level string, msg string) *logs.LogEntry {
+	now := time.Now()
+	content, _ := json.Marshal(map[string]interface{}{
+		"source": source,
+		"level":  level,
+		"msg":    msg,
+		"time":   now.Format(time.RFC3339Nano),
+		"pid":    1,
+	})
+	ts, _ := ptypes.TimestampProto(now)
+	return &logs.LogEntry{
+		Severity:  level,
+		Source:    source,
+		Content:   string(content),
+		Timestamp: ts,
+	}
+}
+
+func (st *state) newInfo(ztype info.ZInfoTypes) *info.ZInfoMsg {
+	return &info.ZInfoMsg{
+		Ztype:       ztype,
+		DevId:       st.devID,
+		AtTimeStamp: ptypes.TimestampNow(),
+	}
+}
+
+func (st *state) deviceInfo() *info.ZInfoMsg {
+	im := st.newInfo(info.ZInfoTypes_ZiDevice)
+	bootTime, _ := ptypes.TimestampProto(st.bootTime)
+	var appInstances []*info.ZInfoAppInstance
+	for _, id := range st.appIDs() {
+		appInstances = append(appInstances, &info.ZInfoAppInstance{Uuid: id, Name: st.apps[id].name})
+	}
+	im.InfoContent = &info.ZInfoMsg_Dinfo{Dinfo: &info.ZInfoDevice{
+		MachineArch: "x86_64",
+		CpuArch:     "x86_64",
+		Platform:    "sim",
+		Ncpu:        4,
+		Memory:      4096,
+		Storage:     65536,
+		HostName:    st.serial,
+		BootTime:    bootTime,
+		SwList: []*info.ZInfoDevSW{{
+			Activated:      true,
+			PartitionLabel: "IMGA",
+			Status:         info.ZSwState_RUNNING,
+			ShortVersion:   st.baseOS,
+			UserStatus:     info.BaseOsStatus_UPDATED,
+		}},
+		LastRebootReason: "simulated boot",
+		AppInstances:     appInstances,
+	}}
+	return im
+}
+
+func (st *state) appInfo(id string, app *appState) *info.ZInfoMsg {
+	im := st.newInfo(info.ZInfoTypes_ZiApp)
+	bootTime, _ := ptypes.TimestampProto(st.bootTime)
+	im.InfoContent = &info.ZInfoMsg_Ainfo{Ainfo: &info.ZInfoApp{
+		AppID:      id,
+		AppVersion: app.version,
+		AppName:    app.name,
+		BootTime:   bootTime,
+		State:      app.state,
+	}}
+	return im
+}
+
+func (st *state) networkInstanceInfo(id string, ni *niState) *info.ZInfoMsg {
+	im := st.newInfo(info.ZInfoTypes_ZiNetworkInstance)
+	im.InfoContent = &info.ZInfoMsg_Niinfo{Niinfo: &info.ZInfoNetworkInstance{
+		NetworkID:      id,
+		NetworkVersion: ni.version,
+		InstType:       uint32(ni.instType),
+		Displayname:    ni.name,
+		Activated:      ni.activated,
+		UpTimeStamp:    ptypes.TimestampNow(),
+	}}
+	return im
+}
+
+//applyConfig compares received config with current state
+//and returns info messages and log entries for changed objects as EVE do
+func (st *state) applyConfig(devConfig *config.EdgeDevConfig) (infos []*info.ZInfoMsg, entries []*logs.LogEntry) {
+	if devConfig.Id != nil && devConfig.Id.Version == st.configVersion {
+		return nil, nil
+	}
+	if devConfig.Id != nil {
+		st.configVersion = devConfig.Id.Version
+	}
+	entries = append(entries, newLogEntry("zedagent", "info", fmt.Sprintf("received config version %s", st.configVersion)))
+
+	baseOS := st.baseOS
+	for _, el := range devConfig.Base {
+		if el.Activate {
+			baseOS = el.BaseOSVersion
+		}
+	}
+	if baseOS != st.baseOS {
+		entries = append(entries, newLogEntry("baseosmgr", "info", fmt.Sprintf("base os %s activated", baseOS)))
+		st.baseOS = baseOS
+	}
+
+	nis := map[string]bool{}
+	for _, el := range devConfig.NetworkInstances {
+		if el.Uuidandversion == nil {
+			continue
+		}
+		id := el.Uuidandversion.Uuid
+		nis[id] = true
+		ni := &niState{name: el.Displayname, version: el.Uuidandversion.Version, instType: el.InstType, activated: el.Activate}
+		if old, ok := st.networkInstances[id]; ok && *old == *ni {
+			continue
+		}
+		st.networkInstances[id] = ni
+		infos = append(infos, st.networkInstanceInfo(id, ni))
+		entries = append(entries, newLogEntry("zedrouter", "info", fmt.Sprintf("network instance %s (%s) activated: %t", ni.name, id, ni.activated)))
+	}
+	for _, id := range st.networkInstanceIDs() {
+		if !nis[id] {
+			entries = append(entries, newLogEntry("zedrouter", "info", fmt.Sprintf("network instance %s (%s) removed", st.networkInstances[id].name, id)))
+			delete(st.networkInstances, id)
+		}
+	}
+
+	apps := map[string]bool{}
+	for _, el := range devConfig.Apps {
+		if el.Uuidandversion == nil {
+			continue
+		}
+		id := el.Uuidandversion.Uuid
+		apps[id] = true
+		app := &appState{name: el.Displayname, version: el.Uuidandversion.Version, state: info.ZSwState_HALTED}
+		if el.Activate {
+			app.state = info.ZSwState_RUNNING
+		}
+		if old, ok := st.apps[id]; ok && *old == *app {
+			continue
+		}
+		st.apps[id] = app
+		infos = append(infos, st.appInfo(id, app))
+		entries = append(entries, newLogEntry("zedmanager", "info", fmt.Sprintf("app %s (%s) state %s", app.name, id, app.state)))
+	}
+	for _, id := range st.appIDs() {
+		if !apps[id] {
+			entries = append(entries, newLogEntry("zedmanager", "info", fmt.Sprintf("app %s (%s) removed", st.apps[id].name, id)))
+			delete(st.apps, id)
+		}
+	}
+
+	infos = append([]*info.ZInfoMsg{st.deviceInfo()}, infos...)
+	return infos, entries
+}
+
+//metrics returns synthetic metrics for device and running apps
+func (st *state) metrics() *metrics.ZMetricMsg {
+	msg := &metrics.ZMetricMsg{
+		DevID:       st.devID,
+		AtTimeStamp: ptypes.TimestampNow(),
+	}
+	var usedMem uint32 = 512
+	for _, id := range st.appIDs() {
+		app := st.apps[id]
+		if app.state != info.ZSwState_RUNNING {
+			continue
+		}
+		usedMem += 256
+		msg.Am = append(msg.Am, &metrics.AppMetric{
+			AppID:      id,
+			AppVersion: app.version,
+			AppName:    app.name,
+			Memory:     &metrics.MemoryMetric{UsedMem: 256, AvailMem: 768, UsedPercentage: 25, AvailPercentage: 75},
+		})
+	}
+	msg.MetricContent = &metrics.ZMetricMsg_Dm{Dm: &metrics.DeviceMetric{
+		Memory: &metrics.MemoryMetric{
+			UsedMem:         usedMem,
+			AvailMem:        4096 - usedMem,
+			UsedPercentage:  float64(usedMem) / 4096 * 100,
+			AvailPercentage: float64(4096-usedMem) / 4096 * 100,
+		},
+	}}
+	return msg
+}
+
+//appIDs returns sorted ids of apps to have stable order of messages
+func (st *state) appIDs() (ids []string) {
+	for id := range st.apps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+//networkInstanceIDs returns sorted ids of network instances to have stable order of messages
+func (st *state) networkInstanceIDs() (ids []string) {
+	for id := range st.networkInstances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,54 @@
+package sim
+
+import (
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/lf-edge/eve/api/go/info"
+	"testing"
+)
+
+//TestApplyConfig test reaction of simulated device on changes in config
+func TestApplyConfig(t *testing.T) {
+	st := newState("sim-0")
+	st.devID = "00000000-0000-0000-0000-000000000001"
+	devConfig := &config.EdgeDevConfig{
+		Id: &config.UUIDandVersion{Uuid: st.devID, Version: "1"},
+		Apps: []*config.AppInstanceConfig{{
+			Uuidandversion: &config.UUIDandVersion{Uuid: "app-uuid", Version: "1"},
+			Displayname:    "app",
+			Activate:       true,
+		}},
+		NetworkInstances: []*config.NetworkInstanceConfig{{
+			Uuidandversion: &config.UUIDandVersion{Uuid: "ni-uuid", Version: "1"},
+			Displayname:    "ni",
+			Activate:       true,
+		}},
+	}
+	infos, entries := st.applyConfig(devConfig)
+	if len(infos) != 3 {
+		t.Fatalf("expected 3 info messages, got %d", len(infos))
+	}
+	if len(entries) == 0 {
+		t.Fatal("expected log entries")
+	}
+	ainfo := infos[2].GetAinfo()
+	if ainfo == nil || ainfo.AppName != "app" || ainfo.State != info.ZSwState_RUNNING {
+		t.Fatalf("unexpected app info: %v", infos[2])
+	}
+	if infos, _ = st.applyConfig(devConfig); len(infos) != 0 {
+		t.Fatalf("expected no info for the same config version, got %d", len(infos))
+	}
+
+	devConfig.Id.Version = "2"
+	devConfig.Apps[0].Activate = false
+	infos, _ = st.applyConfig(devConfig)
+	if len(infos) != 2 || infos[1].GetAinfo().State != info.ZSwState_HALTED {
+		t.Fatalf("expected device info and halted app info, got %v", infos)
+	}
+
+	devConfig.Id.Version = "3"
+	devConfig.Apps = nil
+	st.applyConfig(devConfig)
+	if len(st.metrics().Am) != 0 || len(st.apps) != 0 {
+		t.Fatal("app is not removed")
+	}
+}
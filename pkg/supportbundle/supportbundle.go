@@ -0,0 +1,169 @@
+//Package supportbundle provides writing of support bundle: tar.gz archive with logs, info, configs and status
+//of harness and EVE collected for bug reports. Secrets inside of text files are redacted.
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+//Layout of support bundle
+const (
+	ManifestFile = "bundle.json" //manifest of bundle
+	LogsDir      = "logs"        //log bundles of device, one json per file
+	InfoDir      = "info"        //latest info of device per type, one json per file
+)
+
+//Manifest describes content of support bundle
+type Manifest struct {
+	Created time.Time `json:"created"`
+	Device  string    `json:"device,omitempty"`
+	Files   []string  `json:"files"`
+	Errors  []string  `json:"errors,omitempty"`
+}
+
+const redacted = "<redacted>"
+
+var (
+	secretName     = `(?i:[a-z_\-]*(?:password|passwd|secret|token|apikey|api_key|privatekey|private_key|private-key)[a-z_\-]*)`
+	privateKeyRe   = regexp.MustCompile(`-----BEGIN ([A-Z ]*)PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+	jsonSecretRe   = regexp.MustCompile(`("` + secretName + `"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	yamlSecretRe   = regexp.MustCompile(`(?m)^(\s*(?:-\s+)?` + secretName + `\s*[:=]\s*)\S.*$`)
+	urlPasswordRe  = regexp.MustCompile(`([a-z][a-z0-9+.\-]*://[^:/@\s"']*:)[^@/\s"']+@`)
+	bearerSecretRe = regexp.MustCompile(`(?i)(authorization:\s*(?:bearer|basic)\s+)\S+`)
+)
+
+//Redact return data with private keys, passwords, tokens and other secrets replaced.
+//Binary data is returned as is.
+func Redact(data []byte) []byte {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return data
+	}
+	data = privateKeyRe.ReplaceAll(data, []byte("-----BEGIN ${1}PRIVATE KEY-----"+redacted+"-----END ${1}PRIVATE KEY-----"))
+	data = jsonSecretRe.ReplaceAll(data, []byte(`${1}"`+redacted+`"`))
+	data = yamlSecretRe.ReplaceAll(data, []byte("${1}"+redacted))
+	data = urlPasswordRe.ReplaceAll(data, []byte("${1}"+redacted+"@"))
+	return bearerSecretRe.ReplaceAll(data, []byte("${1}"+redacted))
+}
+
+//Writer writes support bundle
+type Writer struct {
+	gz       *gzip.Writer
+	tw       *tar.Writer
+	manifest Manifest
+	names    map[string]bool
+}
+
+//NewWriter return Writer of support bundle into w for device
+func NewWriter(w io.Writer, device string) *Writer {
+	gz := gzip.NewWriter(w)
+	return &Writer{
+		gz:       gz,
+		tw:       tar.NewWriter(gz),
+		manifest: Manifest{Created: time.Now().UTC(), Device: device},
+		names:    make(map[string]bool),
+	}
+}
+
+func (w *Writer) write(name string, data []byte, mode int64, modTime time.Time) error {
+	name = path.Clean(filepath.ToSlash(name))
+	if w.names[name] {
+		return fmt.Errorf("duplicate file %s in bundle", name)
+	}
+	if err := w.tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: modTime}); err != nil {
+		return err
+	}
+	if _, err := w.tw.Write(data); err != nil {
+		return err
+	}
+	w.names[name] = true
+	w.manifest.Files = append(w.manifest.Files, name)
+	return nil
+}
+
+//Add add file with name and redacted data into bundle
+func (w *Writer) Add(name string, data []byte) error {
+	return w.write(name, Redact(data), 0644, time.Now())
+}
+
+//AddPath add redacted file or directory src into bundle with name
+func (w *Writer) AddPath(name string, src string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return w.write(path.Join(name, filepath.ToSlash(rel)), Redact(data), int64(info.Mode().Perm()), info.ModTime())
+	})
+}
+
+//AddTar add redacted regular files from tar stream r into bundle under prefix
+func (w *Writer) AddTar(prefix string, r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if err = w.write(path.Join(prefix, hdr.Name), Redact(data), hdr.Mode, hdr.ModTime); err != nil {
+			return err
+		}
+	}
+}
+
+//Errorf record problem of collection into manifest of bundle
+func (w *Writer) Errorf(format string, args ...interface{}) {
+	w.manifest.Errors = append(w.manifest.Errors, fmt.Sprintf(format, args...))
+}
+
+//Errors return problems of collection recorded with Errorf
+func (w *Writer) Errors() []string {
+	return w.manifest.Errors
+}
+
+//Close write manifest and close bundle
+func (w *Writer) Close() error {
+	sort.Strings(w.manifest.Files)
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = w.write(ManifestFile, data, 0644, time.Now()); err != nil {
+		return err
+	}
+	if err = w.tw.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
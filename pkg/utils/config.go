@@ -13,6 +13,7 @@ import (
 	"runtime"
 	"strings"
 	"text/template"
+	"time"
 )
 
 //ConfigVars struct with parameters from config file
@@ -25,9 +26,17 @@ type ConfigVars struct {
 	AdamCaching       bool
 	AdamCachingRedis  bool
 	AdamCachingPrefix string
+	AdamCachingAge    time.Duration //max age of cached objects
+	AdamCachingLen    int           //max number of cached objects
+	AdamCachingSize   int64         //max size of cached objects
 	AdamRemoteRedis   bool
+	AdamEmbedded      bool
 	AdamRedisUrlEden  string
 	AdamRedisUrlAdam  string
+	ZedcloudEnabled   bool
+	ZedcloudURL       string
+	ZedcloudCA        string
+	ZedcloudDir       string
 	EveBaseTag        string
 	EveBaseVersion    string
 	EveHV             string
@@ -72,9 +81,17 @@ func InitVars() (*ConfigVars, error) {
 			DevModel:          viper.GetString("eve.devmodel"),
 			AdamRemote:        viper.GetBool("adam.remote.enabled"),
 			AdamRemoteRedis:   viper.GetBool("adam.remote.redis"),
+			AdamEmbedded:      viper.GetBool("adam.embedded"),
 			AdamCaching:       viper.GetBool("adam.caching.enabled"),
 			AdamCachingPrefix: viper.GetString("adam.caching.prefix"),
 			AdamCachingRedis:  viper.GetBool("adam.caching.redis"),
+			AdamCachingAge:    viper.GetDuration("adam.caching.max-age"),
+			AdamCachingLen:    viper.GetInt("adam.caching.max-entries"),
+			AdamCachingSize:   int64(viper.GetSizeInBytes("adam.caching.max-size")),
+			ZedcloudEnabled:   viper.GetBool("zedcloud.enabled"),
+			ZedcloudURL:       viper.GetString("zedcloud.url"),
+			ZedcloudCA:        ResolveAbsPath(viper.GetString("zedcloud.ca")),
+			ZedcloudDir:       ResolveAbsPath(viper.GetString("zedcloud.dist")),
 			EdenBinDir:        viper.GetString("eden.bin-dist"),
 			EdenProg:          viper.GetString("eden.eden-bin"),
 			TestProg:          viper.GetString("eden.test-bin"),
@@ -127,6 +144,15 @@ adam:
     #use v1 api
     v1: true
 
+    #run adam inside eden process instead of docker container
+    embedded: false
+
+    #embedded adam pid file
+    pid: adam.pid
+
+    #embedded adam log file
+    log: adam.log
+
     caching:
         enabled: false
 
@@ -136,8 +162,14 @@ adam:
         #prefix for directory/redis stream
         prefix: cache
 
+        #retention of cache for every device and type of objects (0 for unlimited):
+        #max age of objects (e.g. 72h), max number of objects and max size of objects (e.g. 1GB)
+        max-age: 0
+        max-entries: 0
+        max-size: 0
+
 eve:
-    #devmodel
+    #devmodel (name of model from eden.models or built-in one, or path to yaml file of model)
     devmodel: Qemu
 
     #EVE arch (amd64/arm64)
@@ -208,6 +240,9 @@ eve:
     #config part of EVE
     config-part: {{ .DefaultAdamDist }}/run/config
 
+    #directory for named EVE instances (eden eve start --name)
+    instances: {{ .DefaultEVEInstancesDist }}
+
 eden:
     #root directory of eden
     root: {{ .Root }}
@@ -221,6 +256,9 @@ eden:
         #yml to build vm image
         vm: {{ .ImageDir }}/vm/alpine/alpine.yml
 
+    #directory with yaml files of device models (eve.devmodel)
+    models: {{ .ModelsDir }}
+
     #download eve instead of build
     download: true
 
@@ -268,6 +306,47 @@ redis:
 
     #directory to use for redis persistence
     dist: {{ .DefaultRedisDist }}
+
+sim:
+    #directory to save certs of simulated devices
+    dist: {{ .DefaultSimDist }}
+
+    #number of simulated devices
+    count: {{ .DefaultSimCount }}
+
+    #prefix for serials of simulated devices
+    serial-prefix: {{ .DefaultSimSerialPrefix }}
+
+    #interval in seconds to poll config
+    interval: {{ .DefaultSimInterval }}
+
+    #simulated devices pid file
+    pid: sim.pid
+
+    #simulated devices log file
+    log: sim.log
+
+zedcloud:
+    #use zedcloud-compatible controller with v2 api instead of adam
+    enabled: false
+
+    #url of controller
+    url: https://{{ .IP }}:{{ .DefaultZedcloudPort }}
+
+    #certificate for communication with controller
+    ca: {{ .DefaultAdamDist }}/run/config/root-certificate.pem
+
+    #location of storage of local stand-in
+    dist: {{ .DefaultZedcloudDist }}
+
+    #port of local stand-in
+    port: {{ .DefaultZedcloudPort }}
+
+    #local stand-in pid file
+    pid: zedcloud.pid
+
+    #local stand-in log file
+    log: zedcloud.log
 `
 
 //DefaultEdenDir returns path to default directory
@@ -411,6 +490,7 @@ func generateConfigFileFromTemplate(filePath string, templateString string) erro
 			DefaultAdamPort      int
 			DefaultImageDist     string
 			ImageDir             string
+			ModelsDir            string
 			Root                 string
 			IP                   string
 			EVEIP                string
@@ -437,6 +517,16 @@ func generateConfigFileFromTemplate(filePath string, templateString string) erro
 			DefaultSSHKey        string
 			DefaultEveRepo       string
 
+			DefaultEVEInstancesDist string
+
+			DefaultSimDist         string
+			DefaultSimCount        int
+			DefaultSimSerialPrefix string
+			DefaultSimInterval     int
+
+			DefaultZedcloudDist string
+			DefaultZedcloudPort int
+
 			DefaultRedisContainerName string
 		}{
 			DefaultAdamDist:      defaults.DefaultAdamDist,
@@ -444,6 +534,7 @@ func generateConfigFileFromTemplate(filePath string, templateString string) erro
 			DefaultAdamTag:       defaults.DefaultAdamTag,
 			DefaultImageDist:     defaults.DefaultImageDist,
 			ImageDir:             filepath.Join(currentPath, defaults.DefaultImageDist),
+			ModelsDir:            filepath.Join(currentPath, defaults.DefaultModelsDir),
 			Root:                 filepath.Join(currentPath, defaults.DefaultDist),
 			IP:                   ip,
 			EVEIP:                eveIP,
@@ -470,6 +561,16 @@ func generateConfigFileFromTemplate(filePath string, templateString string) erro
 			DefaultSSHKey:        defaults.DefaultSSHKey,
 			DefaultEveRepo:       defaults.DefaultEveRepo,
 
+			DefaultEVEInstancesDist: defaults.DefaultEVEInstancesDist,
+
+			DefaultSimDist:         defaults.DefaultSimDist,
+			DefaultSimCount:        defaults.DefaultSimCount,
+			DefaultSimSerialPrefix: defaults.DefaultSimSerialPrefix,
+			DefaultSimInterval:     defaults.DefaultSimInterval,
+
+			DefaultZedcloudDist: defaults.DefaultZedcloudDist,
+			DefaultZedcloudPort: defaults.DefaultZedcloudPort,
+
 			DefaultRedisContainerName: defaults.DefaultRedisContainerName,
 		})
 	if err != nil {
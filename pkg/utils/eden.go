@@ -152,6 +152,67 @@ func StatusAdam() (status string, err error) {
 	return state, nil
 }
 
+//StartAdamEmbedded function run adam server inside eden process in background
+//with file storage inside adamPath/run/adam
+func StartAdamEmbedded(commandPath string, adamPort int, adamPath string, logFile string, pidFile string) (err error) {
+	status, err := StatusCommandWithPid(pidFile)
+	if err != nil {
+		return fmt.Errorf("error in get status of embedded adam: %s", err)
+	}
+	if strings.HasPrefix(status, "running") {
+		log.Infof("Embedded adam already %s", status)
+		return nil
+	}
+	if _, err = os.Stat(pidFile); err == nil {
+		if err = os.Remove(pidFile); err != nil {
+			return fmt.Errorf("cannot delete stale pid file %s: %s", pidFile, err)
+		}
+	}
+	commandArgsString := fmt.Sprintf("adam serve --adam-port=%d --adam-dist=%s -v %s", adamPort, adamPath, log.GetLevel())
+	log.Infof("StartAdamEmbedded run: %s %s", commandPath, commandArgsString)
+	return RunCommandNohup(commandPath, logFile, pidFile, strings.Fields(commandArgsString)...)
+}
+
+//StopAdamEmbedded function stop embedded adam
+func StopAdamEmbedded(pidFile string) (err error) {
+	return StopCommandWithPid(pidFile)
+}
+
+//StatusAdamEmbedded function get status of embedded adam
+func StatusAdamEmbedded(pidFile string) (status string, err error) {
+	return StatusCommandWithPid(pidFile)
+}
+
+//StartZedcloud function run local stand-in of zedcloud-compatible controller in background
+func StartZedcloud(commandPath string, port int, zedcloudDist string, certsDir string, logFile string, pidFile string) (err error) {
+	status, err := StatusCommandWithPid(pidFile)
+	if err != nil {
+		return fmt.Errorf("error in get status of zedcloud stand-in: %s", err)
+	}
+	if strings.HasPrefix(status, "running") {
+		log.Infof("Zedcloud stand-in already %s", status)
+		return nil
+	}
+	if _, err = os.Stat(pidFile); err == nil {
+		if err = os.Remove(pidFile); err != nil {
+			return fmt.Errorf("cannot delete stale pid file %s: %s", pidFile, err)
+		}
+	}
+	commandArgsString := fmt.Sprintf("zedcloud serve --zedcloud-port=%d --zedcloud-dist=%s --certs-dist=%s -v %s", port, zedcloudDist, certsDir, log.GetLevel())
+	log.Infof("StartZedcloud run: %s %s", commandPath, commandArgsString)
+	return RunCommandNohup(commandPath, logFile, pidFile, strings.Fields(commandArgsString)...)
+}
+
+//StopZedcloud function stop local stand-in of zedcloud-compatible controller
+func StopZedcloud(pidFile string) (err error) {
+	return StopCommandWithPid(pidFile)
+}
+
+//StatusZedcloud function get status of local stand-in of zedcloud-compatible controller
+func StatusZedcloud(pidFile string) (status string, err error) {
+	return StatusCommandWithPid(pidFile)
+}
+
 //StartEServer function run eserver to serve images
 func StartEServer(commandPath string, serverPort int, imageDist string, logFile string, pidFile string) (err error) {
 	commandArgsString := fmt.Sprintf("server -p %d -d %s -v %s", serverPort, imageDist, log.GetLevel())
@@ -169,6 +230,24 @@ func StatusEServer(pidFile string) (status string, err error) {
 	return StatusCommandWithPid(pidFile)
 }
 
+//StartSim function run simulated EVE devices in background
+func StartSim(commandPath string, count int, serialPrefix string, interval int, simDist string, logFile string, pidFile string) (err error) {
+	commandArgsString := fmt.Sprintf("sim run --sim-count=%d --sim-serial-prefix=%s --sim-interval=%d --sim-dist=%s -v %s",
+		count, serialPrefix, interval, simDist, log.GetLevel())
+	log.Infof("StartSim run: %s %s", commandPath, commandArgsString)
+	return RunCommandNohup(commandPath, logFile, pidFile, strings.Fields(commandArgsString)...)
+}
+
+//StopSim function stop simulated EVE devices
+func StopSim(pidFile string) (err error) {
+	return StopCommandWithPid(pidFile)
+}
+
+//StatusSim function get status of simulated EVE devices
+func StatusSim(pidFile string) (status string, err error) {
+	return StatusCommandWithPid(pidFile)
+}
+
 //StartEVEQemu function run EVE in qemu
 func StartEVEQemu(commandPath string, qemuARCH string, qemuOS string, eveImageFile string, qemuSMBIOSSerial string, qemuAccel bool, qemuConfigFilestring, logFile string, pidFile string) (err error) {
 	commandArgsString := fmt.Sprintf("eve start --qemu-config=%s --eve-serial=%s --eve-accel=%t --eve-arch=%s --eve-os=%s --eve-log=%s --eve-pid=%s --image-file=%s -v %s",
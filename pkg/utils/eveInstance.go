@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/lf-edge/eden/pkg/defaults"
+	uuid "github.com/satori/go.uuid"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const eveInstanceFile = "instance.yml"
+
+//EVEInstance is named EVE, which runs side by side with EVE from config
+//it has own image, config partition with onboarding cert, qemu config, pid and log files and forwarded ports
+type EVEInstance struct {
+	Name       string            `yaml:"name"`
+	UUID       string            `yaml:"uuid"`
+	Serial     string            `yaml:"serial"`
+	TelnetPort int               `yaml:"telnet-port"`
+	SSHPort    int               `yaml:"ssh-port"`
+	HostFWD    map[string]string `yaml:"hostfwd"`
+	dir        string
+}
+
+//ImageFile return path to image of instance
+func (inst *EVEInstance) ImageFile() string { return filepath.Join(inst.dir, "live.qcow2") }
+
+//ConfigPart return path to config partition of instance
+func (inst *EVEInstance) ConfigPart() string { return filepath.Join(inst.dir, "config") }
+
+//CertFile return path to onboarding cert of instance
+func (inst *EVEInstance) CertFile() string {
+	return filepath.Join(inst.ConfigPart(), "onboard.cert.pem")
+}
+
+//QemuConfig return path to qemu config of instance
+func (inst *EVEInstance) QemuConfig() string { return filepath.Join(inst.dir, "qemu.conf") }
+
+//PidFile return path to pid file of instance
+func (inst *EVEInstance) PidFile() string { return filepath.Join(inst.dir, "eve.pid") }
+
+//LogFile return path to log file of instance
+func (inst *EVEInstance) LogFile() string { return filepath.Join(inst.dir, "eve.log") }
+
+//ports return all ports of instance forwarded to host
+func (inst *EVEInstance) ports() (ports []int) {
+	ports = append(ports, inst.TelnetPort)
+	for ext := range inst.HostFWD {
+		if port, err := strconv.Atoi(ext); err == nil {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+func (inst *EVEInstance) save() error {
+	data, err := yaml.Marshal(inst)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(inst.dir, eveInstanceFile), data, 0644)
+}
+
+//EVEInstanceLoad load instance with name from instancesDir
+func EVEInstanceLoad(instancesDir string, name string) (*EVEInstance, error) {
+	dir := filepath.Join(instancesDir, name)
+	data, err := ioutil.ReadFile(filepath.Join(dir, eveInstanceFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no EVE instance %s in %s", name, instancesDir)
+		}
+		return nil, err
+	}
+	inst := &EVEInstance{dir: dir}
+	if err = yaml.Unmarshal(data, inst); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %s", filepath.Join(dir, eveInstanceFile), err)
+	}
+	return inst, nil
+}
+
+//EVEInstanceList return instances from instancesDir sorted by name
+func EVEInstanceList(instancesDir string) (instances []*EVEInstance, err error) {
+	files, err := ioutil.ReadDir(instancesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		inst, err := EVEInstanceLoad(instancesDir, file.Name())
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, inst)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Name < instances[j].Name })
+	return instances, nil
+}
+
+//EVEInstanceCreate creates instance with name inside instancesDir
+//it copies imageFile and configPart, generates onboarding cert signed by root CA from certsDir
+//and shifts telnetPort and hostFWD ports of EVE from config to not conflict with other instances and used ports
+func EVEInstanceCreate(instancesDir string, name string, serial string, imageFile string, configPart string, certsDir string, telnetPort int, hostFWD map[string]string) (*EVEInstance, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("wrong name of EVE instance: %q", name)
+	}
+	inst := &EVEInstance{Name: name, Serial: serial, dir: filepath.Join(instancesDir, name)}
+	if _, err := os.Stat(inst.dir); !os.IsNotExist(err) {
+		return nil, fmt.Errorf("EVE instance %s already exists", name)
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	inst.UUID = id.String()
+	if err = inst.allocatePorts(instancesDir, telnetPort, hostFWD); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(inst.ConfigPart(), 0755); err != nil {
+		return nil, err
+	}
+	if err = inst.populate(imageFile, configPart, certsDir); err != nil {
+		_ = os.RemoveAll(inst.dir)
+		return nil, err
+	}
+	return inst, nil
+}
+
+//populate fills directory of instance with image, config partition and description
+func (inst *EVEInstance) populate(imageFile string, configPart string, certsDir string) error {
+	if err := CopyFile(imageFile, inst.ImageFile()); err != nil {
+		return fmt.Errorf("cannot copy image: %s", err)
+	}
+	if err := copyConfigPart(configPart, inst.ConfigPart()); err != nil {
+		return fmt.Errorf("cannot copy config partition: %s", err)
+	}
+	rootCert, rootKey, err := ReadCertAndKey(filepath.Join(certsDir, "root-certificate.pem"), filepath.Join(certsDir, "root-certificate.key"))
+	if err != nil {
+		return fmt.Errorf("cannot read root certificate: %s", err)
+	}
+	serial, err := GenSerial()
+	if err != nil {
+		return fmt.Errorf("cannot generate serial of certificate: %s", err)
+	}
+	onboardCert, onboardKey := GenServerCert(rootCert, rootKey, serial, nil, nil, inst.UUID)
+	if err = WriteToFiles(onboardCert, onboardKey, inst.CertFile(), filepath.Join(inst.ConfigPart(), "onboard.key.pem")); err != nil {
+		return err
+	}
+	return inst.save()
+}
+
+//WriteQemuConfig writes qemu config of instance based on settings of EVE from config
+//with config partition and forwarded ports of instance and own copy of writable firmware variables
+func (inst *EVEInstance) WriteQemuConfig(settings QemuSettings) error {
+	settings.ConfigDrive = inst.ConfigPart()
+	settings.HostFWD = inst.HostFWD
+	if len(settings.Firmware) == 2 {
+		vars := filepath.Join(inst.dir, filepath.Base(settings.Firmware[1]))
+		if err := CopyFileNotExists(settings.Firmware[1], vars); err != nil {
+			return fmt.Errorf("cannot copy firmware: %s", err)
+		}
+		settings.Firmware = []string{settings.Firmware[0], vars}
+	}
+	conf, err := settings.GenerateQemuConfig()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(inst.QemuConfig(), conf, 0644)
+}
+
+//copyConfigPart copy files from config partition except ones EVE generates for itself
+func copyConfigPart(src string, dst string) error {
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), "device.") {
+			continue
+		}
+		if err = CopyFile(filepath.Join(src, file.Name()), filepath.Join(dst, file.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//allocatePorts sets ports of instance shifted from ports of EVE from config
+func (inst *EVEInstance) allocatePorts(instancesDir string, telnetPort int, hostFWD map[string]string) error {
+	base := &EVEInstance{TelnetPort: telnetPort, HostFWD: hostFWD}
+	used := map[int]bool{}
+	for _, port := range base.ports() {
+		used[port] = true
+	}
+	instances, err := EVEInstanceList(instancesDir)
+	if err != nil {
+		return err
+	}
+	for _, el := range instances {
+		for _, port := range el.ports() {
+			used[port] = true
+		}
+	}
+	offset, err := portsOffset(base.ports(), used, portFree)
+	if err != nil {
+		return err
+	}
+	inst.TelnetPort = telnetPort + offset
+	inst.HostFWD = make(map[string]string)
+	for ext, internal := range hostFWD {
+		port, err := strconv.Atoi(ext)
+		if err != nil {
+			return fmt.Errorf("wrong port %s in hostfwd: %s", ext, err)
+		}
+		inst.HostFWD[strconv.Itoa(port+offset)] = internal
+		if internal == "22" {
+			inst.SSHPort = port + offset
+		}
+	}
+	return nil
+}
+
+//portsOffset return the smallest shift by defaults.DefaultEVEInstancePortStep,
+//which moves all ports out of used ones and keeps them free on host
+func portsOffset(ports []int, used map[int]bool, free func(port int) bool) (int, error) {
+	for offset := defaults.DefaultEVEInstancePortStep; ; offset += defaults.DefaultEVEInstancePortStep {
+		suitable := true
+		for _, port := range ports {
+			shifted := port + offset
+			if shifted > 65535 {
+				return 0, fmt.Errorf("no free ports to forward for %v", ports)
+			}
+			if used[shifted] || !free(shifted) {
+				suitable = false
+				break
+			}
+		}
+		if suitable {
+			return offset, nil
+		}
+	}
+}
+
+//portFree checks if port can be listened on host
+func portFree(port int) bool {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	_ = ln.Close()
+	return true
+}
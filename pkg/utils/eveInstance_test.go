@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestPortsOffset(t *testing.T) {
+	ports := []int{7777, 2222, 5911, 5912}
+	used := map[int]bool{7777: true, 2222: true, 5911: true, 5912: true, 2322: true}
+	busy := map[int]bool{6111: true}
+	free := func(port int) bool { return !busy[port] }
+	offset, err := portsOffset(ports, used, free)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//100 conflicts with instance using 2322, 200 conflicts with 6111 busy on host
+	if offset != 300 {
+		t.Errorf("expected offset 300, got %d", offset)
+	}
+	if _, err = portsOffset([]int{65500}, used, free); err == nil {
+		t.Error("offset out of port range must fail")
+	}
+}
+
+func TestGenSerial(t *testing.T) {
+	first, err := GenSerial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GenSerial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Cmp(second) == 0 {
+		t.Errorf("serials of certificates of instances must differ: %s", first)
+	}
+}
@@ -24,12 +24,17 @@ type StateObject struct {
 	EveUUID    string
 	DeviceUUID string
 	QEMUConfig string
+	StateKey   string //key of state file of device, EveUUID is used if empty
 }
 
 //GenerateStateFile generates state in file
 func GenerateStateFile(dirToSave string, state StateObject) error {
 	state.DeviceUUID = filepath.Join(dirToSave, fmt.Sprintf("devUUID-%s.json", state.DeviceUUID))
-	filePath := filepath.Join(dirToSave, fmt.Sprintf("state-%s.yml", state.EveUUID))
+	stateKey := state.StateKey
+	if stateKey == "" {
+		stateKey = state.EveUUID
+	}
+	filePath := filepath.Join(dirToSave, fmt.Sprintf("state-%s.yml", stateKey))
 	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
 		log.Fatal(err)
 	}
@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/lf-edge/eden/pkg/defaults"
+	"io/ioutil"
 	"math/big"
 	"net"
 	"os"
@@ -46,6 +47,11 @@ func genCert(template, parent *x509.Certificate, publicKey *rsa.PublicKey, priva
 	return cert
 }
 
+//GenSerial return random 128-bit serial number for certificate to not reuse serials under the same CA
+func GenSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
 //GenCARoot gen root CA
 func GenCARoot() (*x509.Certificate, *rsa.PrivateKey) {
 	var rootTemplate = x509.Certificate{
@@ -196,3 +202,36 @@ func WriteToFiles(crt *x509.Certificate, key interface{}, certFile string, keyFi
 	}
 	return nil
 }
+
+//ReadCertAndKey read cert and rsa key saved by WriteToFiles
+func ReadCertAndKey(certFile string, keyFile string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	certData, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	certBlock, _ := pem.Decode(certData)
+	if certBlock == nil {
+		return nil, nil, fmt.Errorf("no pem block in %s", certFile)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyData, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyBlock, _ := pem.Decode(keyData)
+	if keyBlock == nil {
+		return nil, nil, fmt.Errorf("no pem block in %s", keyFile)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("not rsa key in %s", keyFile)
+	}
+	return cert, rsaKey, nil
+}
@@ -0,0 +1,122 @@
+//Package zedapi provides helpers for zedcloud-style v2 device API with auth containers
+//and local stand-in controller, which implements this API for testing with eden.
+package zedapi
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/lf-edge/eve/api/go/auth"
+	"github.com/lf-edge/eve/api/go/evecommon"
+	"math/big"
+)
+
+//CertHash returns sha256 of PEM encoded certificate as EVE calculates it for SenderCertHash
+func CertHash(cert *x509.Certificate) []byte {
+	h := sha256.Sum256(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	return h[:]
+}
+
+//sign returns signature of hash of payload
+//for ECDSA it is r|s with padding of each part to the size of curve as EVE do
+func sign(key crypto.PrivateKey, hash []byte) ([]byte, error) {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, hash)
+		if err != nil {
+			return nil, err
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig := make([]byte, 2*size)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[size-len(rb):size], rb)
+		copy(sig[2*size-len(sb):], sb)
+		return sig, nil
+	case *rsa.PrivateKey:
+		return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hash)
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+//verify checks signature of hash with public key of cert
+func verify(cert *x509.Certificate, hash []byte, sig []byte) error {
+	switch k := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return fmt.Errorf("wrong signature length %d", len(sig))
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, hash, r, s) {
+			return fmt.Errorf("signature verification failed")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, hash, sig)
+	default:
+		return fmt.Errorf("unsupported public key type %T", cert.PublicKey)
+	}
+}
+
+//Seal wraps payload into AuthContainer signed with cert
+func Seal(payload []byte, cert *tls.Certificate) (*auth.AuthContainer, error) {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return nil, fmt.Errorf("no certificate to sign with")
+	}
+	x509Cert, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse certificate: %s", err)
+	}
+	hash := sha256.Sum256(payload)
+	sig, err := sign(cert.PrivateKey, hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign payload: %s", err)
+	}
+	return &auth.AuthContainer{
+		AuthPayload:    &auth.AuthBody{Payload: payload},
+		Algo:           evecommon.HashAlgorithm_HASH_ALGORITHM_SHA256_32BYTES,
+		SenderCertHash: CertHash(x509Cert),
+		SignatureHash:  sig,
+		SenderCert:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: x509Cert.Raw}),
+	}, nil
+}
+
+//Open checks that AuthContainer is signed with senderCert and returns payload from it
+func Open(ac *auth.AuthContainer, senderCert *x509.Certificate) ([]byte, error) {
+	if senderCert == nil {
+		return nil, fmt.Errorf("no sender certificate to verify auth container")
+	}
+	if ac.AuthPayload == nil {
+		return nil, fmt.Errorf("no payload in auth container")
+	}
+	payload := ac.AuthPayload.Payload
+	var hashLen int
+	switch ac.Algo {
+	case evecommon.HashAlgorithm_HASH_ALGORITHM_SHA256_16BYTES:
+		hashLen = 16
+	case evecommon.HashAlgorithm_HASH_ALGORITHM_SHA256_32BYTES:
+		hashLen = 32
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %s", ac.Algo)
+	}
+	if len(ac.SenderCertHash) != hashLen {
+		return nil, fmt.Errorf("wrong length of sender cert hash: %d", len(ac.SenderCertHash))
+	}
+	if !bytes.Equal(CertHash(senderCert)[:hashLen], ac.SenderCertHash) {
+		return nil, fmt.Errorf("sender cert hash mismatch")
+	}
+	hash := sha256.Sum256(payload)
+	if err := verify(senderCert, hash[:], ac.SignatureHash); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,74 @@
+package zedapi
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func genCert(t *testing.T, priv crypto.Signer) *tls.Certificate {
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		Subject:      pkix.Name{CommonName: "test"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, priv.Public(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: priv}
+}
+
+func TestSealOpen(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := genCert(t, ecKey)
+	for name, cert := range map[string]*tls.Certificate{"ecdsa": genCert(t, ecKey), "rsa": genCert(t, rsaKey)} {
+		t.Run(name, func(t *testing.T) {
+			x509Cert, err := x509.ParseCertificate(cert.Certificate[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			ac, err := Seal([]byte("payload"), cert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload, err := Open(ac, x509Cert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(payload) != "payload" {
+				t.Errorf("unexpected payload %q", payload)
+			}
+			ac.AuthPayload.Payload = []byte("modified")
+			if _, err = Open(ac, x509Cert); err == nil {
+				t.Error("modified payload must fail verification")
+			}
+			otherCert, err := x509.ParseCertificate(other.Certificate[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = Open(ac, otherCert); err == nil {
+				t.Error("container must not be opened with other cert")
+			}
+			if _, err = Open(ac, nil); err == nil {
+				t.Error("container must not be opened without cert")
+			}
+		})
+	}
+}
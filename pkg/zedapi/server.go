@@ -0,0 +1,743 @@
+package zedapi
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/lf-edge/adam/pkg/driver"
+	"github.com/lf-edge/adam/pkg/server"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eve/api/go/auth"
+	"github.com/lf-edge/eve/api/go/certs"
+	"github.com/lf-edge/eve/api/go/config"
+	"github.com/lf-edge/eve/api/go/evecommon"
+	"github.com/lf-edge/eve/api/go/flowlog"
+	"github.com/lf-edge/eve/api/go/info"
+	"github.com/lf-edge/eve/api/go/logs"
+	"github.com/lf-edge/eve/api/go/metrics"
+	"github.com/lf-edge/eve/api/go/register"
+	uuid "github.com/satori/go.uuid"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mimeProto = "application/x-proto-binary"
+	mimeJSON  = "application/json"
+
+	//DevicePrefix is prefix of v2 device API
+	DevicePrefix = "/api/v2/edgedevice"
+	//AdminPrefix is prefix of admin API used by eden to manage controller
+	AdminPrefix = "/api/v2/admin"
+)
+
+//Server is local stand-in of zedcloud-compatible controller
+//it keeps devices, configs, logs, info, metrics and flow logs in DeviceManager
+type Server struct {
+	Port          string
+	Address       string
+	CertPath      string //server certificate, also used to sign responses
+	KeyPath       string
+	DeviceManager driver.DeviceManager
+
+	signingCert *tls.Certificate
+	logs        hub
+	info        hub
+	metrics     hub
+	flowLog     hub
+}
+
+//subscriber receives messages of one device
+type subscriber struct {
+	devID string
+	ch    chan proto.Message
+}
+
+//hub sends messages to all subscribers of device
+type hub struct {
+	mu   sync.Mutex
+	subs map[*subscriber]bool
+}
+
+func (h *hub) subscribe(devID string) *subscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs == nil {
+		h.subs = map[*subscriber]bool{}
+	}
+	s := &subscriber{devID: devID, ch: make(chan proto.Message, 100)}
+	h.subs[s] = true
+	return s
+}
+
+func (h *hub) unsubscribe(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, s)
+}
+
+//publish do not block on slow subscribers
+func (h *hub) publish(devID string, msg proto.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		if s.devID != devID {
+			continue
+		}
+		select {
+		case s.ch <- msg:
+		default:
+		}
+	}
+}
+
+//Handler returns http.Handler with device and admin API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.probe)
+	mux.HandleFunc(DevicePrefix+"/certs", s.method("GET", s.certs))
+	mux.HandleFunc(DevicePrefix+"/register", s.method("POST", s.register))
+	mux.HandleFunc(DevicePrefix+"/ping", s.method("GET", s.ping))
+	mux.HandleFunc(DevicePrefix+"/config", s.method("POST", s.config))
+	mux.HandleFunc(DevicePrefix+"/info", s.method("POST", s.infoPost))
+	mux.HandleFunc(DevicePrefix+"/metrics", s.method("POST", s.metricsPost))
+	mux.HandleFunc(DevicePrefix+"/logs", s.method("POST", s.logsPost))
+	mux.HandleFunc(DevicePrefix+"/flowlog", s.method("POST", s.flowLogPost))
+	mux.HandleFunc(AdminPrefix+"/onboard", s.onboard)
+	mux.HandleFunc(AdminPrefix+"/device", s.method("GET", s.deviceList))
+	mux.HandleFunc(AdminPrefix+"/device/", s.device)
+	return mux
+}
+
+//Start loads certificates and serves API, blocks until error
+func (s *Server) Start() error {
+	if s.DeviceManager == nil {
+		return fmt.Errorf("empty device manager")
+	}
+	cert, err := tls.LoadX509KeyPair(s.CertPath, s.KeyPath)
+	if err != nil {
+		return fmt.Errorf("cannot load server cert: %s", err)
+	}
+	s.signingCert = &cert
+	srv := &http.Server{
+		Handler: s.Handler(),
+		Addr:    fmt.Sprintf("%s:%s", s.Address, s.Port),
+		TLSConfig: &tls.Config{
+			ClientAuth:   tls.RequestClientCert,
+			Certificates: []tls.Certificate{cert},
+		},
+	}
+	log.Infof("Starting zedcloud stand-in on %s:%s with storage %s", s.Address, s.Port, s.DeviceManager.Database())
+	return srv.ListenAndServeTLS("", "")
+}
+
+func (s *Server) method(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *Server) probe(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+//clientCert returns cert from mTLS
+func clientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
+
+//openRequest reads AuthContainer from body, verifies it with cert and unmarshal payload into msg
+func openRequest(r *http.Request, cert *x509.Certificate, msg proto.Message) error {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("error reading request body: %s", err)
+	}
+	var ac auth.AuthContainer
+	if err = proto.Unmarshal(b, &ac); err != nil {
+		return fmt.Errorf("cannot parse auth container: %s", err)
+	}
+	payload, err := Open(&ac, cert)
+	if err != nil {
+		return fmt.Errorf("auth container check failed: %s", err)
+	}
+	if err = proto.Unmarshal(payload, msg); err != nil {
+		return fmt.Errorf("cannot parse %T: %s", msg, err)
+	}
+	return nil
+}
+
+//writeSealed sends msg inside AuthContainer signed by server
+func (s *Server) writeSealed(w http.ResponseWriter, msg proto.Message) {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ac, err := Seal(payload, s.signingCert)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out, err := proto.Marshal(ac)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mimeProto)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(out)
+}
+
+//checkDevice returns uuid of device for mTLS cert and device cert saved on registration
+//to verify auth containers of device
+func (s *Server) checkDevice(w http.ResponseWriter, r *http.Request) (*x509.Certificate, *uuid.UUID) {
+	cert := clientCert(r)
+	if cert == nil {
+		http.Error(w, "client TLS authentication required", http.StatusUnauthorized)
+		return nil, nil
+	}
+	u, err := s.DeviceManager.DeviceCheckCert(cert)
+	if err != nil || u == nil {
+		log.Debugf("unknown device cert %s: %v", cert.Subject, err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return nil, nil
+	}
+	deviceCert, _, _, err := s.DeviceManager.DeviceGet(u)
+	if err != nil {
+		log.Debugf("cannot get device %s: %s", u, err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return nil, nil
+	}
+	if deviceCert == nil {
+		//some device managers do not keep device cert, mTLS cert is the registered one as DeviceCheckCert found it
+		deviceCert = cert
+	}
+	return deviceCert, u
+}
+
+//checkSender rejects messages with device id other than device authenticated with mTLS
+func checkSender(w http.ResponseWriter, u *uuid.UUID, devID string) bool {
+	if devID != u.String() {
+		log.Debugf("device %s sends message of device %s", u, devID)
+		http.Error(w, "device id of message does not match authenticated device", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (s *Server) certs(w http.ResponseWriter, r *http.Request) {
+	x509Cert, err := x509.ParseCertificate(s.signingCert.Certificate[0])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out, err := proto.Marshal(&certs.ZControllerCert{Certs: []*certs.ZCert{{
+		HashAlgo: evecommon.HashAlgorithm_HASH_ALGORITHM_SHA256_32BYTES,
+		CertHash: CertHash(x509Cert),
+		Type:     certs.ZCertType_CERT_TYPE_CONTROLLER_SIGNING,
+		Cert:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: x509Cert.Raw}),
+	}}})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mimeProto)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(out)
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	onboardCert := clientCert(r)
+	if onboardCert == nil {
+		http.Error(w, "client TLS authentication required", http.StatusUnauthorized)
+		return
+	}
+	var msg register.ZRegisterMsg
+	if err := openRequest(r, onboardCert, &msg); err != nil {
+		log.Errorf("register: %s", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.DeviceManager.OnboardCheck(onboardCert, msg.Serial); err != nil {
+		switch err.(type) {
+		case *driver.InvalidCertError, *driver.InvalidSerialError:
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case *driver.UsedSerialError:
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(string(msg.PemCert))
+	if err != nil {
+		http.Error(w, "error base64-decoding device certificate", http.StatusBadRequest)
+		return
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		http.Error(w, "no PEM data in device certificate", http.StatusBadRequest)
+		return
+	}
+	deviceCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	u, err := s.DeviceManager.DeviceRegister(deviceCert, onboardCert, msg.Serial)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Infof("device %s registered with serial %s", u, msg.Serial)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) ping(w http.ResponseWriter, r *http.Request) {
+	if _, u := s.checkDevice(w, r); u == nil {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) config(w http.ResponseWriter, r *http.Request) {
+	cert, u := s.checkDevice(w, r)
+	if u == nil {
+		return
+	}
+	var req config.ConfigRequest
+	if err := openRequest(r, cert, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, err := s.DeviceManager.GetConfigResponse(*u)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.ConfigHash != "" && req.ConfigHash == resp.ConfigHash {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.writeSealed(w, resp)
+}
+
+func (s *Server) infoPost(w http.ResponseWriter, r *http.Request) {
+	cert, u := s.checkDevice(w, r)
+	if u == nil {
+		return
+	}
+	var msg info.ZInfoMsg
+	if err := openRequest(r, cert, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !checkSender(w, u, msg.DevId) {
+		return
+	}
+	if err := s.DeviceManager.WriteInfo(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.info.publish(msg.DevId, &msg)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) metricsPost(w http.ResponseWriter, r *http.Request) {
+	cert, u := s.checkDevice(w, r)
+	if u == nil {
+		return
+	}
+	var msg metrics.ZMetricMsg
+	if err := openRequest(r, cert, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !checkSender(w, u, msg.DevID) {
+		return
+	}
+	if err := s.DeviceManager.WriteMetrics(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.metrics.publish(msg.DevID, &msg)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) logsPost(w http.ResponseWriter, r *http.Request) {
+	cert, u := s.checkDevice(w, r)
+	if u == nil {
+		return
+	}
+	var msg logs.LogBundle
+	if err := openRequest(r, cert, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !checkSender(w, u, msg.DevID) {
+		return
+	}
+	if err := s.DeviceManager.WriteLogs(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.logs.publish(msg.DevID, &msg)
+	w.WriteHeader(http.StatusCreated)
+}
+
+//flowLogPost saves flow message into file storage, DeviceManager has no method for it
+func (s *Server) flowLogPost(w http.ResponseWriter, r *http.Request) {
+	cert, u := s.checkDevice(w, r)
+	if u == nil {
+		return
+	}
+	var msg flowlog.FlowMessage
+	if err := openRequest(r, cert, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !checkSender(w, u, msg.DevId) {
+		return
+	}
+	dir, err := s.storageDir(*u, "flowlog")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	buf := new(bytes.Buffer)
+	mler := jsonpb.Marshaler{}
+	if err = mler.Marshal(buf, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%d:%09d", now.Unix(), now.Nanosecond())), buf.Bytes(), 0644); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.flowLog.publish(msg.DevId, &msg)
+	w.WriteHeader(http.StatusCreated)
+}
+
+//onboard lists onboarding certs on GET and adds cert with serials on POST
+//with the same json as adam uses
+func (s *Server) onboard(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		cns, err := s.DeviceManager.OnboardList()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(strings.Join(cns, "\n")))
+	case "POST":
+		var t server.OnboardCert
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		block, _ := pem.Decode(t.Cert)
+		if block == nil {
+			http.Error(w, "no PEM data in onboarding certificate", http.StatusBadRequest)
+			return
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = s.DeviceManager.OnboardRegister(cert, strings.Split(t.Serial, ",")); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) deviceList(w http.ResponseWriter, r *http.Request) {
+	uids, err := s.DeviceManager.DeviceList()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var ids []string
+	for _, u := range uids {
+		if u != nil {
+			ids = append(ids, u.String())
+		}
+	}
+	_, _ = w.Write([]byte(strings.Join(ids, "\n")))
+}
+
+//device serves /device/{uuid} and /device/{uuid}/(config|logs|info|metrics|flowlog)
+func (s *Server) device(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, AdminPrefix+"/device/"), "/")
+	if len(parts) == 1 {
+		parts = append(parts, "")
+	}
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	u, err := uuid.FromString(parts[0])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch {
+	case parts[1] == "" && r.Method == "GET":
+		s.deviceGet(w, r, u)
+	case parts[1] == "config" && r.Method == "GET":
+		s.deviceConfigGet(w, r, u)
+	case parts[1] == "config" && r.Method == "PUT":
+		s.deviceConfigSet(w, r, u)
+	case parts[1] == "logs" && r.Method == "GET":
+		s.deviceDataGet(w, r, u, &s.logs, s.DeviceManager.GetLogsReader)
+	case parts[1] == "info" && r.Method == "GET":
+		s.deviceDataGet(w, r, u, &s.info, s.DeviceManager.GetInfoReader)
+	case parts[1] == "metrics" && r.Method == "GET":
+		s.deviceDataGet(w, r, u, &s.metrics, s.storageReader("metrics"))
+	case parts[1] == "flowlog" && r.Method == "GET":
+		s.deviceDataGet(w, r, u, &s.flowLog, s.storageReader("flowlog"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+//deviceGet returns certs and serial of device with the same json as adam uses
+func (s *Server) deviceGet(w http.ResponseWriter, r *http.Request, u uuid.UUID) {
+	deviceCert, onboardCert, serial, err := s.DeviceManager.DeviceGet(&u)
+	if _, isNotFound := err.(*driver.NotFoundError); isNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	dc := server.DeviceCert{Serial: serial}
+	if deviceCert != nil {
+		dc.Cert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: deviceCert.Raw})
+	}
+	if onboardCert != nil {
+		dc.Onboard = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: onboardCert.Raw})
+	}
+	body, err := json.Marshal(dc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mimeJSON)
+	_, _ = w.Write(body)
+}
+
+func (s *Server) deviceConfigGet(w http.ResponseWriter, r *http.Request, u uuid.UUID) {
+	devConfig, err := s.DeviceManager.GetConfig(u)
+	if _, isNotFound := err.(*driver.NotFoundError); isNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, err := json.Marshal(devConfig)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mimeJSON)
+	_, _ = w.Write(body)
+}
+
+func (s *Server) deviceConfigSet(w http.ResponseWriter, r *http.Request, u uuid.UUID) {
+	var devConfig config.EdgeDevConfig
+	if err := json.NewDecoder(r.Body).Decode(&devConfig); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err := s.DeviceManager.SetConfig(u, &devConfig)
+	if _, isNotFound := err.(*driver.NotFoundError); isNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+//storageDir returns directory with messages of kind for device inside file storage
+func (s *Server) storageDir(u uuid.UUID, kind string) (string, error) {
+	mgr, ok := s.DeviceManager.(*driver.DeviceManagerFile)
+	if !ok {
+		return "", fmt.Errorf("%s are available only with file storage", kind)
+	}
+	return filepath.Join(driver.GetDevicePath(mgr.Database(), u), kind), nil
+}
+
+//storageReader returns reader of saved messages of kind
+//DeviceManager has no readers for metrics and flow logs, so we read directory of file storage
+func (s *Server) storageReader(kind string) func(u uuid.UUID) (io.Reader, error) {
+	return func(u uuid.UUID) (io.Reader, error) {
+		dir, err := s.storageDir(u, kind)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = os.Stat(dir); err != nil {
+			return nil, &driver.NotFoundError{}
+		}
+		return &driver.DirReader{Path: dir, LineFeed: true}, nil
+	}
+}
+
+//deviceDataGet returns saved messages or streams new ones of device if X-Stream header set
+//saved messages are limited with since, until and tail parameters
+func (s *Server) deviceDataGet(w http.ResponseWriter, r *http.Request, u uuid.UUID, h *hub, readerFunc func(u uuid.UUID) (io.Reader, error)) {
+	if r.Header.Get(server.StreamHeader) != server.StreamValue {
+		rng, err := rangeFromParams(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reader, err := readerFunc(u)
+		if _, isNotFound := err.(*driver.NotFoundError); isNotFound {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", mimeJSON)
+		if rng.IsEmpty() {
+			_, _ = io.Copy(w, reader)
+			return
+		}
+		if err := writeRange(w, reader, rng); err != nil {
+			log.Errorf("deviceDataGet: %s", err)
+		}
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	sub := h.subscribe(u.String())
+	defer h.unsubscribe(sub)
+	w.Header().Set("Content-Type", mimeJSON)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	mler := jsonpb.Marshaler{}
+	for {
+		select {
+		case m := <-sub.ch:
+			buf := new(bytes.Buffer)
+			if err := mler.Marshal(buf, m); err != nil {
+				log.Errorf("cannot marshal %T: %s", m, err)
+				continue
+			}
+			buf.WriteByte('\n')
+			if _, err := w.Write(buf.Bytes()); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+//rangeFromParams return range of saved messages from since, until (RFC3339Nano) and tail parameters
+func rangeFromParams(params url.Values) (loaders.Range, error) {
+	var rng loaders.Range
+	var err error
+	if since := params.Get("since"); since != "" {
+		if rng.Since, err = time.Parse(time.RFC3339Nano, since); err != nil {
+			return rng, fmt.Errorf("cannot parse since: %s", err)
+		}
+	}
+	if until := params.Get("until"); until != "" {
+		if rng.Until, err = time.Parse(time.RFC3339Nano, until); err != nil {
+			return rng, fmt.Errorf("cannot parse until: %s", err)
+		}
+	}
+	if tail := params.Get("tail"); tail != "" {
+		if rng.Tail, err = strconv.Atoi(tail); err != nil || rng.Tail < 0 {
+			return rng, fmt.Errorf("cannot parse tail: %s", tail)
+		}
+	}
+	return rng, nil
+}
+
+//writeRange writes messages from reader with timestamps inside of rng, only rng.Tail newest of them from oldest to newest if set
+func writeRange(w io.Writer, reader io.Reader, rng loaders.Range) error {
+	var tail []json.RawMessage
+	dec := json.NewDecoder(reader)
+	for {
+		var msg json.RawMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if !rng.Contains(loaders.ObjectTime(msg)) {
+			continue
+		}
+		if rng.Tail == 0 {
+			if _, err := w.Write(append(msg, '\n')); err != nil {
+				return err
+			}
+			continue
+		}
+		tail = append(tail, msg)
+	}
+	//reader of adam does not sort files, so sort by timestamp before taking the newest ones
+	sort.SliceStable(tail, func(i, j int) bool {
+		return loaders.ObjectTime(tail[i]).Before(loaders.ObjectTime(tail[j]))
+	})
+	if len(tail) > rng.Tail {
+		tail = tail[len(tail)-rng.Tail:]
+	}
+	for _, msg := range tail {
+		if _, err := w.Write(append(msg, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package zedapi
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/lf-edge/adam/pkg/driver"
+	"github.com/lf-edge/eden/pkg/controller/loaders"
+	"github.com/lf-edge/eve/api/go/logs"
+	uuid "github.com/satori/go.uuid"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+//TestDeviceDataRange test that saved logs are limited by since, until and tail on server for remote loader
+func TestDeviceDataRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zedapi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dm := &driver.DeviceManagerFile{}
+	if _, err := dm.Init(dir); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(genCert(t, key).Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	devUUID, err := dm.DeviceRegister(cert, cert, "serial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2020, 5, 13, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		ts, err := ptypes.TimestampProto(start.Add(time.Duration(i) * time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lb := &logs.LogBundle{DevID: devUUID.String(), Timestamp: ts, Image: fmt.Sprintf("log%d", i)}
+		if err := dm.WriteLogs(lb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts := httptest.NewServer((&Server{DeviceManager: dm}).Handler())
+	defer ts.Close()
+	logsURL := func(devUUID uuid.UUID) string {
+		return fmt.Sprintf("%s%s/device/%s/logs", ts.URL, AdminPrefix, devUUID)
+	}
+	tests := map[string]struct {
+		rng      loaders.Range
+		expected []string
+	}{
+		"all":   {loaders.Range{}, []string{"log0", "log1", "log2", "log3", "log4"}},
+		"since": {loaders.Range{Since: start.Add(3 * time.Minute)}, []string{"log3", "log4"}},
+		"until": {loaders.Range{Until: start.Add(time.Minute)}, []string{"log0", "log1"}},
+		"tail":  {loaders.Range{Until: start.Add(3 * time.Minute), Tail: 2}, []string{"log2", "log3"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			loader := loaders.RemoteLoader(ts.Client, logsURL, logsURL, logsURL, logsURL)
+			loader.SetUUID(*devUUID)
+			loader.SetRange(tt.rng)
+			var received []string
+			err := loader.ProcessExisting(func(data []byte) (bool, error) {
+				var lb struct{ Image string }
+				if err := json.Unmarshal(data, &lb); err != nil {
+					return false, err
+				}
+				received = append(received, lb.Image)
+				return true, nil
+			}, loaders.LogsType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			//files of device are not sorted by reader
+			sort.Strings(received)
+			if !reflect.DeepEqual(received, tt.expected) {
+				t.Errorf("loader received %v, expected %v", received, tt.expected)
+			}
+			//check that server sends only objects inside of range
+			query := make(map[string]string)
+			tt.rng.ToQuery(query)
+			req, err := http.NewRequest("GET", logsURL(*devUUID), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			params := req.URL.Query()
+			for k, v := range query {
+				params.Set(k[1:], v)
+			}
+			req.URL.RawQuery = params.Encode()
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			lines := 0
+			for scanner := bufio.NewScanner(resp.Body); scanner.Scan(); {
+				lines++
+			}
+			if lines != len(tt.expected) {
+				t.Errorf("server sent %d objects, expected %d", lines, len(tt.expected))
+			}
+		})
+	}
+}
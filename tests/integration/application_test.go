@@ -147,9 +147,9 @@ func TestApplication(t *testing.T) {
 			if err != nil {
 				t.Fatal("Fail in prepare app from local file: ", err)
 			}
-			deviceCtx, err := ctx.GetDeviceFirst()
+			deviceCtx, err := getDevice(ctx)
 			if err != nil {
-				t.Fatal("Fail in get first device: ", err)
+				t.Fatal("Fail in get device: ", err)
 			}
 			err = ctx.ApplyDevModel(deviceCtx, deviceModel)
 			if err != nil {
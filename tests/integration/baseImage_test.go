@@ -45,9 +45,9 @@ func TestBaseImage(t *testing.T) {
 			if err != nil {
 				t.Fatal("Fail in prepare base image from local file: ", err)
 			}
-			deviceCtx, err := ctx.GetDeviceFirst()
+			deviceCtx, err := getDevice(ctx)
 			if err != nil {
-				t.Fatal("Fail in get first device: ", err)
+				t.Fatal("Fail in get device: ", err)
 			}
 			deviceCtx.SetBaseOSConfig([]string{tt.baseID})
 			devUUID := deviceCtx.GetID()
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/lf-edge/eden/pkg/controller"
 	"github.com/lf-edge/eden/pkg/defaults"
+	"github.com/lf-edge/eden/pkg/device"
 	"github.com/lf-edge/eden/pkg/utils"
 	"github.com/lf-edge/eve/api/go/config"
 	"github.com/lf-edge/eve/api/go/evecommon"
@@ -35,6 +36,11 @@ type appInstLocal struct {
 	accessPortInternal string
 }
 
+//getDevice return device selected by eden test --device or first device
+func getDevice(ctx controller.Cloud) (*device.Ctx, error) {
+	return ctx.GetDevice(os.Getenv(defaults.DefaultDeviceEnv))
+}
+
 var eServerURL = fmt.Sprintf("http://%s:%d", defaults.DefaultDomain, defaults.DefaultEserverPort)
 
 var (
@@ -5,7 +5,6 @@ import (
 	"github.com/lf-edge/eden/pkg/controller/einfo"
 	"github.com/lf-edge/eden/pkg/controller/elog"
 	"testing"
-	"time"
 )
 
 //TestAdamOnBoard test onboarding into controller
@@ -14,41 +13,14 @@ func TestAdamOnBoard(t *testing.T) {
 	if err != nil {
 		t.Fatalf("CloudPrepare: %s", err)
 	}
-	vars := ctx.GetVars()
-	devUUID, err := ctx.GetDeviceFirst()
-	if devUUID == nil {
-		t.Logf("Try to add onboarding")
-		err = ctx.Register(vars.EveCert, vars.EveSerial)
-		if err != nil {
-			t.Fatal(err)
-		}
-		res, err := ctx.OnBoardList()
-		if err != nil {
-			t.Fatal(err)
-		}
-		if len(res) == 0 {
-			t.Fatal("No onboard in list")
-		}
-		t.Log(res)
-
-		maxRepeat := 20
-		delayTime := 20 * time.Second
-
-		for i := 0; i < maxRepeat; i++ {
-			cmdOut, err := ctx.DeviceList()
-			if err != nil {
-				t.Fatal(err)
-			}
-			if len(cmdOut) > 0 {
-				t.Logf("Done onboarding in adam!")
-				t.Logf("Device uuid: %s", cmdOut)
-				return
-			}
-			t.Logf("Attempt to list devices (%d) of (%d)", i, maxRepeat)
-			time.Sleep(delayTime)
-		}
-		t.Fatal("Onboarding timeout")
+	if err = ctx.OnBoard(); err != nil {
+		t.Fatal(err)
+	}
+	dev, err := getDevice(ctx)
+	if err != nil {
+		t.Fatal(err)
 	}
+	t.Logf("Device uuid: %s", dev.GetID())
 }
 
 //TestControllerSetConfig test config set via controller
@@ -57,9 +29,9 @@ func TestControllerSetConfig(t *testing.T) {
 	if err != nil {
 		t.Fatalf("CloudPrepare: %s", err)
 	}
-	deviceCtx, err := ctx.GetDeviceFirst()
+	deviceCtx, err := getDevice(ctx)
 	if err != nil {
-		t.Fatal("Fail in get first device: ", err)
+		t.Fatal("Fail in get device: ", err)
 	}
 	err = ctx.ConfigSync(deviceCtx)
 	if err != nil {
@@ -73,9 +45,9 @@ func TestControllerGetConfig(t *testing.T) {
 	if err != nil {
 		t.Fatalf("CloudPrepare: %s", err)
 	}
-	devUUID, err := ctx.GetDeviceFirst()
+	devUUID, err := getDevice(ctx)
 	if err != nil {
-		t.Fatal("Fail in get first device: ", err)
+		t.Fatal("Fail in get device: ", err)
 	}
 	config, err := ctx.ConfigGet(devUUID.GetID())
 	if err != nil {
@@ -90,9 +62,9 @@ func TestControllerLogs(t *testing.T) {
 	if err != nil {
 		t.Fatalf("CloudPrepare: %s", err)
 	}
-	devUUID, err := ctx.GetDeviceFirst()
+	devUUID, err := getDevice(ctx)
 	if err != nil {
-		t.Fatal("Fail in get first device: ", err)
+		t.Fatal("Fail in get device: ", err)
 	}
 	t.Log(devUUID.GetID())
 	err = ctx.LogChecker(devUUID.GetID(), map[string]string{"devId": devUUID.GetID().String()}, elog.HandleFirst, elog.LogAny, 600)
@@ -107,9 +79,9 @@ func TestControllerInfo(t *testing.T) {
 	if err != nil {
 		t.Fatalf("CloudPrepare: %s", err)
 	}
-	devUUID, err := ctx.GetDeviceFirst()
+	devUUID, err := getDevice(ctx)
 	if err != nil {
-		t.Fatal("Fail in get first device: ", err)
+		t.Fatal("Fail in get device: ", err)
 	}
 	t.Log(devUUID.GetID())
 	err = ctx.InfoChecker(devUUID.GetID(), map[string]string{"devId": devUUID.GetID().String()}, einfo.ZInfoDinfo, einfo.HandleFirst, einfo.InfoAny, 300)
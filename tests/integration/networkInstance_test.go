@@ -16,9 +16,9 @@ func TestNetworkInstance(t *testing.T) {
 		t.Fatalf("CloudPrepare: %s", err)
 	}
 
-	deviceCtx, err := ctx.GetDeviceFirst()
+	deviceCtx, err := getDevice(ctx)
 	if err != nil {
-		t.Fatal("Fail in get first device: ", err)
+		t.Fatal("Fail in get device: ", err)
 	}
 	devModel, err := ctx.GetDevModelByName(deviceCtx.GetDevModel())
 	if err != nil {